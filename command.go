@@ -0,0 +1,230 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CommandError is returned when a zfs or zpool command fails. It exposes the
+// full context of the failed command.
+//
+// It satisfies errors.Is for ErrZFS or ErrZpool depending on Command, and
+// any sentinel errors, like ErrNotFound and ErrBusy, indicated by the command's
+// stderr output. It unwraps to Err, allowing errors.As to be used to get the
+// underlying error returned by the runner.Runner.
+type CommandError struct {
+	// Command is the command which was executed, either "zfs" or "zpool".
+	Command string
+
+	// Args are the arguments passed to Command.
+	Args []string
+
+	// ExitCode is the exit code of the command. It is -1 if the exit code
+	// could not be determined, for example if the command could not be
+	// started, or was killed.
+	ExitCode int
+
+	// Stderr is the raw stderr output of the command.
+	Stderr []byte
+
+	// CleanStderr is the stderr output tidied up by removing usage
+	// information, empty lines, and joining lines with ": ".
+	CleanStderr string
+
+	// Duration is how long the command took to run.
+	Duration time.Duration
+
+	// Err is the error returned by the runner.Runner.
+	Err error
+
+	// errs are the sentinel errors the command error is considered to be.
+	errs []error
+}
+
+// Error returns a message in the format of:
+//
+//  <command>[; <sentinel>...]; <err>: <clean stderr>
+//
+// For example:
+//
+//  zfs; not found; exit status 1: cannot open 'tank/x': dataset does not exist
+func (e *CommandError) Error() string {
+	msgs := make([]string, 0, len(e.errs)+1)
+	for _, err := range e.errs {
+		msgs = append(msgs, err.Error())
+	}
+	msgs = append(msgs, fmt.Sprintf("%s: %s", e.Err, e.CleanStderr))
+
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether the CommandError is considered to be target.
+func (e *CommandError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Unwrap returns the underlying error returned by the runner.Runner.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// CommandLine returns the command and its arguments as a single string,
+// quoting any arguments containing whitespace or quotes. It is intended for
+// logging purposes, and is not guaranteed to be safe for shell execution.
+func (e *CommandError) CommandLine() string {
+	return commandLine(e.Command, e.Args)
+}
+
+func commandLine(command string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, command)
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		parts = append(parts, arg)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// exitCoder is implemented by *exec.ExitError.
+type exitCoder interface {
+	ExitCode() int
+}
+
+func exitCode(err error) int {
+	var ec exitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+
+	return -1
+}
+
+// run executes command via the Manager's runner, returning the parsed tabular
+// stdout output, or a *CommandError if the command fails. The kind error,
+// either ErrZFS or ErrZpool, is the first sentinel error of the returned
+// *CommandError.
+//
+// Failed commands are retried as per the RetryPolicy in effect for ctx.
+//
+// If the Manager has a Plan, mutating commands are recorded in it instead of
+// being run, and no records are returned.
+func (m *Manager) run(
+	ctx context.Context,
+	stdin io.Reader,
+	kind error,
+	command string,
+	args ...string,
+) ([][]string, error) {
+	if m.Plan != nil && mutating(command, args) {
+		return nil, m.plan(ctx, stdin, kind, command, args)
+	}
+
+	return m.retry(ctx, stdin, func() ([][]string, error) {
+		return m.runOnce(ctx, stdin, kind, command, args...)
+	})
+}
+
+// output executes a read-only command via the Manager's runner like run, but
+// returns the raw stdout output, for callers which parse it themselves.
+func (m *Manager) output(
+	ctx context.Context,
+	kind error,
+	command string,
+	args ...string,
+) ([]byte, error) {
+	var stdout []byte
+	_, err := m.retry(ctx, nil, func() ([][]string, error) {
+		var err error
+		stdout, err = m.exec(ctx, nil, kind, command, args...)
+
+		return nil, err
+	})
+
+	return stdout, err
+}
+
+// runOnce executes command once, as described by run.
+func (m *Manager) runOnce(
+	ctx context.Context,
+	stdin io.Reader,
+	kind error,
+	command string,
+	args ...string,
+) ([][]string, error) {
+	stdout, err := m.exec(ctx, stdin, kind, command, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTabular(stdout), nil
+}
+
+// exec executes command once, returning its raw stdout output, or a
+// *CommandError if the command fails.
+func (m *Manager) exec(
+	ctx context.Context,
+	stdin io.Reader,
+	kind error,
+	command string,
+	args ...string,
+) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	err := m.execTo(ctx, stdin, &stdout, kind, command, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// execTo executes command once like exec, but writes stdout output to stdout
+// as it is produced, rather than buffering it.
+func (m *Manager) execTo(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	kind error,
+	command string,
+	args ...string,
+) error {
+	var stderr bytes.Buffer
+
+	m.beforeCommand(ctx, command, args)
+	start := time.Now()
+	err := m.Runner.RunContext(ctx, stdin, stdout, &stderr, command, args...)
+	duration := time.Since(start)
+	if err != nil {
+		cleanStderr := cleanUpStderr(stderr.Bytes())
+		cmdErr := &CommandError{
+			Command:     command,
+			Args:        args,
+			ExitCode:    exitCode(err),
+			Stderr:      stderr.Bytes(),
+			CleanStderr: string(cleanStderr),
+			Duration:    duration,
+			Err:         err,
+			errs:        append([]error{kind}, stderrErrors(cleanStderr)...),
+		}
+		m.afterCommand(ctx, command, args, duration, cmdErr)
+
+		return cmdErr
+	}
+	m.afterCommand(ctx, command, args, duration, nil)
+
+	return nil
+}
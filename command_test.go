@@ -0,0 +1,202 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testExitError struct {
+	code int
+}
+
+func (e *testExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e *testExitError) ExitCode() int {
+	return e.code
+}
+
+func TestCommandError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *CommandError
+		want string
+	}{
+		{
+			name: "zfs",
+			err: &CommandError{
+				Command:     "zfs",
+				Err:         errors.New("exit status 1"),
+				CleanStderr: "something went wrong",
+				errs:        []error{ErrZFS},
+			},
+			want: "zfs; exit status 1: something went wrong",
+		},
+		{
+			name: "zpool not found",
+			err: &CommandError{
+				Command:     "zpool",
+				Err:         errors.New("exit status 1"),
+				CleanStderr: "cannot open 'nope': no such pool",
+				errs:        []error{ErrZpool, ErrNotFound},
+			},
+			want: "zpool; not found; exit status 1: " +
+				"cannot open 'nope': no such pool",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.err.Error())
+		})
+	}
+}
+
+func TestCommandError_CommandLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		want    string
+	}{
+		{
+			name:    "no args",
+			command: "zfs",
+			want:    "zfs",
+		},
+		{
+			name:    "plain args",
+			command: "zfs",
+			args: []string{
+				"get", "-Hp", "-o", "name,property,value,source", "all",
+				"tank/data",
+			},
+			want: "zfs get -Hp -o name,property,value,source all tank/data",
+		},
+		{
+			name:    "args needing quotes",
+			command: "zpool",
+			args: []string{
+				"set", "comment=hello world", "", `say "hi"`, "tank",
+			},
+			want: `zpool set "comment=hello world" "" "say \"hi\"" tank`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &CommandError{Command: tt.command, Args: tt.args}
+
+			assert.Equal(t, tt.want, e.CommandLine())
+		})
+	}
+}
+
+func TestManager_run_CommandError(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name           string
+		command        string
+		args           []string
+		stderr         string
+		commandErr     error
+		wantExitCode   int
+		wantStderr     string
+		wantErr        string
+		wantErrTargets []error
+		wantNotTargets []error
+	}{
+		{
+			name:    "zfs with exit code",
+			command: "zfs",
+			args:    []string{"destroy", "tank/data"},
+			stderr: "cannot destroy 'tank/data': dataset is busy\n" +
+				"usage:\n\tdestroy [-fnpRrv] <filesystem|volume>\n",
+			commandErr:   &testExitError{code: 1},
+			wantExitCode: 1,
+			wantStderr:   "cannot destroy 'tank/data': dataset is busy",
+			wantErr: "zfs; busy; exit status 1: " +
+				"cannot destroy 'tank/data': dataset is busy",
+			wantErrTargets: []error{Err, ErrZFS, ErrBusy},
+			wantNotTargets: []error{ErrZpool, ErrNotFound},
+		},
+		{
+			name:         "zpool without exit code",
+			command:      "zpool",
+			args:         []string{"get", "-Hp", "-o", "value", "size", "x"},
+			stderr:       "cannot open 'x': no such pool\n",
+			commandErr:   context.Canceled,
+			wantExitCode: -1,
+			wantStderr:   "cannot open 'x': no such pool",
+			wantErr: "zpool; not found; context canceled: " +
+				"cannot open 'x': no such pool",
+			wantErrTargets: []error{
+				Err, ErrZpool, ErrNotFound, context.Canceled,
+			},
+			wantNotTargets: []error{ErrZFS, ErrBusy},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				gomock.Nil(),
+				gomock.AssignableToTypeOf(ioWriter),
+				gomock.AssignableToTypeOf(ioWriter),
+				tt.command,
+				tt.args,
+			).DoAndReturn(func(
+				_ context.Context,
+				_ io.Reader,
+				_ io.Writer,
+				stderr io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				_, _ = stderr.Write([]byte(tt.stderr))
+
+				return tt.commandErr
+			})
+
+			m := &Manager{Runner: r}
+
+			var err error
+			if tt.command == "zpool" {
+				_, err = m.zpool(ctx, tt.args...)
+			} else {
+				_, err = m.zfs(ctx, tt.args...)
+			}
+
+			assert.EqualError(t, err, tt.wantErr)
+			for _, target := range tt.wantErrTargets {
+				assert.ErrorIs(t, err, target)
+			}
+			for _, target := range tt.wantNotTargets {
+				assert.NotErrorIs(t, err, target)
+			}
+
+			var cmdErr *CommandError
+			require.True(t, errors.As(err, &cmdErr))
+			assert.Equal(t, tt.command, cmdErr.Command)
+			assert.Equal(t, tt.args, cmdErr.Args)
+			assert.Equal(t, tt.wantExitCode, cmdErr.ExitCode)
+			assert.Equal(t, []byte(tt.stderr), cmdErr.Stderr)
+			assert.Equal(t, tt.wantStderr, cmdErr.CleanStderr)
+			assert.Equal(t, tt.commandErr, cmdErr.Err)
+			assert.GreaterOrEqual(t, int64(cmdErr.Duration), int64(0))
+		})
+	}
+}
@@ -80,6 +80,14 @@ func (p *Dataset) Exec() (bool, bool) {
 	return p.Bool(zfsprops.Exec)
 }
 
+// Mounted returns the value of the "mounted" property as a bool.
+//
+// The second return value indicates if the property is present in the Dataset
+// instance.
+func (p *Dataset) Mounted() (bool, bool) {
+	return p.Bool(zfsprops.Mounted)
+}
+
 // ReadOnly returns the value of the "readonly" property as a bool.
 //
 // The second return value indicates if the property is present in the Dataset
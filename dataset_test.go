@@ -762,6 +762,13 @@ func TestDataset_Bool(t *testing.T) {
 				return d.Exec()
 			},
 		},
+		{
+			name:     "Mounted",
+			property: "mounted",
+			lookup: func(d *Dataset) (bool, bool) {
+				return d.Mounted()
+			},
+		},
 		{
 			name:     "SetUID",
 			property: "setuid",
@@ -826,6 +833,18 @@ func TestDataset_Bool(t *testing.T) {
 			want:   false,
 			wantOk: true,
 		},
+		{
+			name:   "yes",
+			value:  "yes",
+			want:   true,
+			wantOk: true,
+		},
+		{
+			name:   "no",
+			value:  "no",
+			want:   false,
+			wantOk: true,
+		},
 	}
 	for _, prop := range props {
 		t.Run(prop.name, func(t *testing.T) {
@@ -0,0 +1,152 @@
+package zfs
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The following errors are returned when stderr output from a failed zfs or
+// zpool command indicates a common failure case. They are returned in addition
+// to ErrZFS or ErrZpool, and can be checked with errors.Is.
+var (
+	ErrExists             = fmt.Errorf("%walready exists", Err)
+	ErrBusy               = fmt.Errorf("%wbusy", Err)
+	ErrPermissionDenied   = fmt.Errorf("%wpermission denied", Err)
+	ErrHasChildren        = fmt.Errorf("%whas children", Err)
+	ErrHasClones          = fmt.Errorf("%whas clones", Err)
+	ErrHasHolds           = fmt.Errorf("%whas holds", Err)
+	ErrNoSpace            = fmt.Errorf("%wno space", Err)
+	ErrKeyNotLoaded       = fmt.Errorf("%wkey not loaded", Err)
+	ErrPoolIOFailure      = fmt.Errorf("%wpool I/O failure", Err)
+	ErrUnsupportedFeature = fmt.Errorf("%wunsupported feature", Err)
+)
+
+// stderrClass maps a sentinel error to lower-cased texts which, when found in
+// stderr output, indicate that the error applies.
+type stderrClass struct {
+	err   error
+	texts [][]byte
+}
+
+// stderrClasses is the list of known stderr classifications. Order matters, as
+// it determines the order in which errors are combined.
+var stderrClasses = []stderrClass{
+	{
+		err: ErrNotFound,
+		texts: [][]byte{
+			[]byte("dataset does not exist"),
+			[]byte("parent does not exist"),
+			[]byte("no such pool"),
+		},
+	},
+	{
+		err: ErrExists,
+		texts: [][]byte{
+			[]byte("dataset already exists"),
+			[]byte("pool already exists"),
+			[]byte("bookmark exists"),
+			[]byte("destination already exists"),
+		},
+	},
+	{
+		err: ErrBusy,
+		texts: [][]byte{
+			[]byte("dataset is busy"),
+			[]byte("pool is busy"),
+			[]byte("pool or dataset is busy"),
+			[]byte("device or resource busy"),
+		},
+	},
+	{
+		err: ErrPermissionDenied,
+		texts: [][]byte{
+			[]byte("permission denied"),
+			[]byte("operation not permitted"),
+			[]byte("must be run as root"),
+		},
+	},
+	{
+		err: ErrHasChildren,
+		texts: [][]byte{
+			[]byte("filesystem has children"),
+			[]byte("volume has children"),
+			[]byte("snapshot has children"),
+		},
+	},
+	{
+		err: ErrHasClones,
+		texts: [][]byte{
+			[]byte("has dependent clones"),
+		},
+	},
+	{
+		err: ErrHasHolds,
+		texts: [][]byte{
+			[]byte("being held"),
+		},
+	},
+	{
+		err: ErrNoSpace,
+		texts: [][]byte{
+			[]byte("out of space"),
+			[]byte("no space left on device"),
+		},
+	},
+	{
+		err: ErrKeyNotLoaded,
+		texts: [][]byte{
+			[]byte("encryption key not loaded"),
+			[]byte("key must be loaded"),
+			[]byte("keys must be loaded"),
+		},
+	},
+	{
+		err: ErrInvalidProperty,
+		texts: [][]byte{
+			[]byte("bad numeric value"),
+			[]byte("invalid value"),
+			[]byte("' must be one of "),
+			[]byte("' must be a number"),
+			[]byte("' must be power of 2"),
+		},
+	},
+	{
+		err: ErrPoolIOFailure,
+		texts: [][]byte{
+			[]byte("i/o is currently suspended"),
+			[]byte("i/o error"),
+			[]byte("input/output error"),
+		},
+	},
+	{
+		err: ErrUnsupportedFeature,
+		texts: [][]byte{
+			[]byte("unsupported feature"),
+			[]byte("unsupported version or feature"),
+			[]byte("not supported by this system"),
+			[]byte("must be upgraded"),
+			[]byte("feature is not enabled"),
+			[]byte("feature not enabled"),
+			[]byte("operation not supported"),
+		},
+	},
+}
+
+// stderrErrors returns the list of sentinel errors which the given stderr
+// output indicates, in the order defined by stderrClasses.
+func stderrErrors(stderr []byte) []error {
+	lower := bytes.ToLower(stderr)
+
+	errs := []error{}
+	for _, class := range stderrClasses {
+		for _, text := range class.texts {
+			if bytes.Contains(lower, text) {
+				errs = append(errs, class.err)
+
+				break
+			}
+		}
+	}
+
+	return errs
+}
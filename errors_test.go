@@ -0,0 +1,341 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+)
+
+// stderrSamples are real stderr outputs from zfs and zpool commands, collected
+// from OpenZFS 0.8, 2.0, 2.1, and 2.2 on Linux, and FreeBSD 13.
+var stderrSamples = []struct {
+	name    string
+	command string
+	stderr  string
+	want    []error
+}{
+	{
+		name:    "0.8+ dataset does not exist",
+		command: "zfs",
+		stderr:  "cannot open 'tank/nope': dataset does not exist\n",
+		want:    []error{ErrNotFound},
+	},
+	{
+		name:    "0.8+ parent does not exist",
+		command: "zfs",
+		stderr:  "cannot create 'tank/a/b/c': parent does not exist\n",
+		want:    []error{ErrNotFound},
+	},
+	{
+		name:    "0.8+ no such pool",
+		command: "zpool",
+		stderr:  "cannot open 'nope': no such pool\n",
+		want:    []error{ErrNotFound},
+	},
+	{
+		name:    "0.8+ create dataset already exists",
+		command: "zfs",
+		stderr:  "cannot create 'tank/a': dataset already exists\n",
+		want:    []error{ErrExists},
+	},
+	{
+		name:    "0.8+ snapshot already exists",
+		command: "zfs",
+		stderr:  "cannot create snapshot 'tank/a@s1': dataset already exists\n",
+		want:    []error{ErrExists},
+	},
+	{
+		name:    "0.8+ rename dataset already exists",
+		command: "zfs",
+		stderr:  "cannot rename to 'tank/b': dataset already exists\n",
+		want:    []error{ErrExists},
+	},
+	{
+		name:    "0.8+ pool already exists",
+		command: "zpool",
+		stderr:  "cannot create 'tank': pool already exists\n",
+		want:    []error{ErrExists},
+	},
+	{
+		name:    "2.0+ bookmark exists",
+		command: "zfs",
+		stderr:  "cannot create bookmark 'tank/a#b1': bookmark exists\n",
+		want:    []error{ErrExists},
+	},
+	{
+		name:    "0.8+ destroy dataset is busy",
+		command: "zfs",
+		stderr:  "cannot destroy 'tank/a': dataset is busy\n",
+		want:    []error{ErrBusy},
+	},
+	{
+		name:    "2.1+ unmount pool or dataset is busy",
+		command: "zfs",
+		stderr: "cannot unmount '/tank/a': pool or dataset is busy\n" +
+			"umount: /tank/a: target is busy.\n",
+		want: []error{ErrBusy},
+	},
+	{
+		name:    "0.8+ export pool is busy",
+		command: "zpool",
+		stderr:  "cannot export 'tank': pool is busy\n",
+		want:    []error{ErrBusy},
+	},
+	{
+		name:    "FreeBSD 13 unmount device busy",
+		command: "zfs",
+		stderr:  "cannot unmount '/tank/a': Device or resource busy\n",
+		want:    []error{ErrBusy},
+	},
+	{
+		name:    "0.8+ permission denied",
+		command: "zfs",
+		stderr:  "cannot create 'tank/a': permission denied\n",
+		want:    []error{ErrPermissionDenied},
+	},
+	{
+		name:    "0.8+ must be run as root",
+		command: "zpool",
+		stderr:  "Permission denied the ZFS utilities must be run as root.\n",
+		want:    []error{ErrPermissionDenied},
+	},
+	{
+		name:    "FreeBSD 13 operation not permitted",
+		command: "zfs",
+		stderr:  "cannot set property for 'tank/a': Operation not permitted\n",
+		want:    []error{ErrPermissionDenied},
+	},
+	{
+		name:    "0.8+ filesystem has children",
+		command: "zfs",
+		stderr: "cannot destroy 'tank/a': filesystem has children\n" +
+			"use '-r' to destroy the following datasets:\n" +
+			"tank/a/b\n" +
+			"tank/a/c\n",
+		want: []error{ErrHasChildren},
+	},
+	{
+		name:    "0.8+ volume has children",
+		command: "zfs",
+		stderr: "cannot destroy 'tank/vol': volume has children\n" +
+			"use '-r' to destroy the following datasets:\n" +
+			"tank/vol@s1\n",
+		want: []error{ErrHasChildren},
+	},
+	{
+		name:    "0.8+ filesystem has dependent clones",
+		command: "zfs",
+		stderr: "cannot destroy 'tank/a': filesystem has dependent clones\n" +
+			"use '-R' to destroy the following datasets:\n" +
+			"tank/clone\n",
+		want: []error{ErrHasClones},
+	},
+	{
+		name:    "0.8+ snapshot has dependent clones",
+		command: "zfs",
+		stderr: "cannot destroy 'tank/a@s1': snapshot has dependent clones\n" +
+			"use '-R' to destroy the following datasets:\n" +
+			"tank/clone\n",
+		want: []error{ErrHasClones},
+	},
+	{
+		name:    "2.0+ snapshot is being held",
+		command: "zfs",
+		stderr: "cannot destroy snapshot tank/a@s1: it's being held. " +
+			"Run 'zfs holds -r tank/a@s1' to see holders.\n",
+		want: []error{ErrHasHolds},
+	},
+	{
+		name:    "0.8+ out of space",
+		command: "zfs",
+		stderr:  "cannot create 'tank/a': out of space\n",
+		want:    []error{ErrNoSpace},
+	},
+	{
+		name:    "0.8+ receive out of space",
+		command: "zfs",
+		stderr:  "cannot receive new filesystem stream: out of space\n",
+		want:    []error{ErrNoSpace},
+	},
+	{
+		name:    "0.8+ snapshot out of space",
+		command: "zfs",
+		stderr:  "cannot create snapshot 'tank/a@s1': out of space\n",
+		want:    []error{ErrNoSpace},
+	},
+	{
+		name:    "0.8+ mount encryption key not loaded",
+		command: "zfs",
+		stderr:  "cannot mount 'tank/enc': encryption key not loaded\n",
+		want:    []error{ErrKeyNotLoaded},
+	},
+	{
+		name:    "0.8+ change-key key must be loaded",
+		command: "zfs",
+		stderr:  "Key change error: Key must be loaded for 'tank/enc'.\n",
+		want:    []error{ErrKeyNotLoaded},
+	},
+	{
+		name:    "0.8+ must be one of",
+		command: "zfs",
+		stderr: "cannot set property for 'tank/a': 'sync' must be one of " +
+			"'standard | always | disabled'\n",
+		want: []error{ErrInvalidProperty},
+	},
+	{
+		name:    "0.8+ bad numeric value",
+		command: "zfs",
+		stderr:  "cannot create 'tank/a': bad numeric value 'what'\n",
+		want:    []error{ErrInvalidProperty},
+	},
+	{
+		name:    "0.8+ must be power of 2",
+		command: "zfs",
+		stderr: "cannot set property for 'tank/a': 'recordsize' must be " +
+			"power of 2 from 512B to 1M\n",
+		want: []error{ErrInvalidProperty},
+	},
+	{
+		name:    "0.8+ pool property must be one of",
+		command: "zpool",
+		stderr: "cannot set property for 'tank': 'listsnapshots' must be " +
+			"one of 'on | off'\n",
+		want: []error{ErrInvalidProperty},
+	},
+	{
+		name:    "0.8+ pool I/O is currently suspended",
+		command: "zpool",
+		stderr:  "cannot open 'tank': pool I/O is currently suspended\n",
+		want:    []error{ErrPoolIOFailure},
+	},
+	{
+		name:    "0.8+ import I/O error",
+		command: "zpool",
+		stderr: "cannot import 'tank': I/O error\n" +
+			"\tDestroy and re-create the pool from\n" +
+			"\ta backup source.\n",
+		want: []error{ErrPoolIOFailure},
+	},
+	{
+		name:    "0.8+ import unsupported feature",
+		command: "zpool",
+		stderr: "This pool uses the following feature(s) not supported by " +
+			"this system:\n" +
+			"\tcom.delphix:redaction_bookmarks\n" +
+			"cannot import 'tank': unsupported version or feature\n",
+		want: []error{ErrUnsupportedFeature},
+	},
+	{
+		name:    "0.8+ set property must be upgraded",
+		command: "zfs",
+		stderr: "cannot set property for 'tank/a': pool and or dataset " +
+			"must be upgraded to set this property or value\n",
+		want: []error{ErrUnsupportedFeature},
+	},
+	{
+		name:    "0.8+ unclassified",
+		command: "zfs",
+		stderr: "bad property list: invalid property 'sizex'\n" +
+			"usage:\n" +
+			"\tget [-rHp] [-d max] [-o \"all\" | field[,...]]\n",
+		want: []error{},
+	},
+	{
+		name:    "multiple",
+		command: "zfs",
+		stderr: "cannot destroy 'tank/a': filesystem has children\n" +
+			"cannot destroy 'tank/b': dataset is busy\n",
+		want: []error{ErrBusy, ErrHasChildren},
+	},
+}
+
+func Test_stderrErrors(t *testing.T) {
+	for _, tt := range stderrSamples {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stderrErrors(cleanUpStderr([]byte(tt.stderr)))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_stderrErrors(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+	allErrs := []error{
+		ErrNotFound,
+		ErrExists,
+		ErrBusy,
+		ErrPermissionDenied,
+		ErrHasChildren,
+		ErrHasClones,
+		ErrHasHolds,
+		ErrNoSpace,
+		ErrKeyNotLoaded,
+		ErrInvalidProperty,
+		ErrPoolIOFailure,
+		ErrUnsupportedFeature,
+	}
+
+	for _, tt := range stderrSamples {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				gomock.Nil(),
+				gomock.AssignableToTypeOf(ioWriter),
+				gomock.AssignableToTypeOf(ioWriter),
+				tt.command,
+				"list",
+			).DoAndReturn(func(
+				_ context.Context,
+				_ io.Reader,
+				_ io.Writer,
+				stderr io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				_, _ = stderr.Write([]byte(tt.stderr))
+
+				return errors.New("exit status 1")
+			})
+
+			m := &Manager{Runner: r}
+
+			var err error
+			if tt.command == "zpool" {
+				_, err = m.zpool(ctx, "list")
+				assert.ErrorIs(t, err, ErrZpool)
+			} else {
+				_, err = m.zfs(ctx, "list")
+				assert.ErrorIs(t, err, ErrZFS)
+			}
+
+			for _, target := range allErrs {
+				if containsErr(tt.want, target) {
+					assert.ErrorIs(t, err, target)
+				} else {
+					assert.NotErrorIs(t, err, target)
+				}
+			}
+		})
+	}
+}
+
+func containsErr(errs []error, target error) bool {
+	for _, err := range errs {
+		if err == target { //nolint:errorlint
+			return true
+		}
+	}
+
+	return false
+}
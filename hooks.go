@@ -0,0 +1,209 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Hook is notified around every zfs and zpool command run by a Manager. If
+// a command is retried, the hook is notified of each attempt.
+//
+// Hooks are called synchronously, and should return quickly. The ctx given is
+// the context passed to the Manager method, allowing request scoped values,
+// like request IDs, to be included in logs and audit trails.
+type Hook interface {
+	// BeforeCommand is called before command is run with args.
+	BeforeCommand(ctx context.Context, command string, args []string)
+
+	// AfterCommand is called after command has been run with args. The err
+	// argument is nil if the command succeeded, otherwise typically a
+	// *CommandError.
+	AfterCommand(
+		ctx context.Context,
+		command string,
+		args []string,
+		duration time.Duration,
+		err error,
+	)
+}
+
+// beforeCommand calls BeforeCommand on all hooks of the Manager.
+func (m *Manager) beforeCommand(
+	ctx context.Context,
+	command string,
+	args []string,
+) {
+	for _, h := range m.Hooks {
+		h.BeforeCommand(ctx, command, args)
+	}
+}
+
+// afterCommand calls AfterCommand on all hooks of the Manager.
+func (m *Manager) afterCommand(
+	ctx context.Context,
+	command string,
+	args []string,
+	duration time.Duration,
+	err error,
+) {
+	for _, h := range m.Hooks {
+		h.AfterCommand(ctx, command, args, duration, err)
+	}
+}
+
+// Logger is a structured logger, with methods matching those of *slog.Logger
+// from the log/slog package, which satisfies this interface.
+type Logger interface {
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// LogHook is a Hook which logs every command after it has run. Successful
+// commands are logged with InfoContext, and failed commands with
+// ErrorContext.
+//
+// Each log entry has the message "zfs command", and the following key-value
+// pairs:
+//
+//  - "command": the full command line, as returned by
+//    CommandError.CommandLine.
+//  - "duration": a time.Duration of how long the command took to run.
+//  - "exit_code": the exit code of the command. Only present for failed
+//    commands.
+//  - "error": the error. Only present for failed commands.
+type LogHook struct {
+	Logger Logger
+}
+
+var _ Hook = &LogHook{}
+
+// NewLogHook returns a new LogHook which logs to logger.
+func NewLogHook(logger Logger) *LogHook {
+	return &LogHook{Logger: logger}
+}
+
+// BeforeCommand does nothing, as commands are logged after they have run.
+func (h *LogHook) BeforeCommand(context.Context, string, []string) {}
+
+// AfterCommand logs the command.
+func (h *LogHook) AfterCommand(
+	ctx context.Context,
+	command string,
+	args []string,
+	duration time.Duration,
+	err error,
+) {
+	attrs := []interface{}{
+		"command", commandLine(command, args),
+		"duration", duration,
+	}
+	if err == nil {
+		h.Logger.InfoContext(ctx, "zfs command", attrs...)
+
+		return
+	}
+
+	code := exitCode(err)
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		code = cmdErr.ExitCode
+	}
+
+	attrs = append(attrs, "exit_code", code, "error", err)
+	h.Logger.ErrorContext(ctx, "zfs command", attrs...)
+}
+
+// CommandStats are statistics for a zfs or zpool subcommand, as collected by
+// CommandCounters.
+type CommandStats struct {
+	// Calls is the number of times the subcommand was run.
+	Calls uint64
+
+	// Failures is the number of times the subcommand failed.
+	Failures uint64
+
+	// Duration is the total time spent running the subcommand.
+	Duration time.Duration
+}
+
+// CommandCounters is a Hook which counts calls, failures, and time spent per
+// subcommand, for example "zfs list" or "zpool import". It is safe for
+// concurrent use, and its zero value is ready for use.
+type CommandCounters struct {
+	mu    sync.Mutex
+	stats map[string]CommandStats
+}
+
+var _ Hook = &CommandCounters{}
+
+// BeforeCommand does nothing, as commands are counted after they have run.
+func (c *CommandCounters) BeforeCommand(context.Context, string, []string) {}
+
+// AfterCommand updates the statistics of the subcommand.
+func (c *CommandCounters) AfterCommand(
+	_ context.Context,
+	command string,
+	args []string,
+	duration time.Duration,
+	err error,
+) {
+	name := command
+	if len(args) > 0 {
+		name += " " + args[0]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stats == nil {
+		c.stats = map[string]CommandStats{}
+	}
+
+	s := c.stats[name]
+	s.Calls++
+	if err != nil {
+		s.Failures++
+	}
+	s.Duration += duration
+	c.stats[name] = s
+}
+
+// Stats returns a snapshot of statistics, keyed by subcommand, for example
+// "zfs list".
+func (c *CommandCounters) Stats() map[string]CommandStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make(map[string]CommandStats, len(c.stats))
+	for k, v := range c.stats {
+		stats[k] = v
+	}
+
+	return stats
+}
+
+// Subcommands returns the names of all subcommands which have been counted,
+// sorted alphabetically.
+func (c *CommandCounters) Subcommands() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.stats))
+	for k := range c.stats {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Reset clears all statistics.
+func (c *CommandCounters) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats = nil
+}
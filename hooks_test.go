@@ -0,0 +1,290 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+// hookCall is a record of a call to recordingHook.
+type hookCall struct {
+	name    string
+	reqID   interface{}
+	command string
+	args    []string
+	err     error
+}
+
+// recordingHook is a Hook which records all calls made to it.
+type recordingHook struct {
+	name  string
+	calls *[]hookCall
+}
+
+func (h *recordingHook) BeforeCommand(
+	ctx context.Context,
+	command string,
+	args []string,
+) {
+	*h.calls = append(*h.calls, hookCall{
+		name:    h.name + ".before",
+		reqID:   ctx.Value(ctxKey{}),
+		command: command,
+		args:    args,
+	})
+}
+
+func (h *recordingHook) AfterCommand(
+	ctx context.Context,
+	command string,
+	args []string,
+	_ time.Duration,
+	err error,
+) {
+	*h.calls = append(*h.calls, hookCall{
+		name:    h.name + ".after",
+		reqID:   ctx.Value(ctxKey{}),
+		command: command,
+		args:    args,
+		err:     err,
+	})
+}
+
+// expectRuns sets up r to expect command to be run with args once for each of
+// stderrs, writing the stderr output and failing for non-empty values.
+func expectRuns(
+	r *mock_runner.MockRunner,
+	command string,
+	args []string,
+	stderrs ...string,
+) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+	calls := 0
+	r.EXPECT().RunContext(
+		gomock.Any(),
+		gomock.Nil(),
+		gomock.AssignableToTypeOf(ioWriter),
+		gomock.AssignableToTypeOf(ioWriter),
+		command,
+		args,
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		_ io.Writer,
+		stderr io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		s := stderrs[calls]
+		calls++
+		if s == "" {
+			return nil
+		}
+		_, _ = stderr.Write([]byte(s))
+
+		return errors.New("exit status 1")
+	}).Times(len(stderrs))
+}
+
+func TestManager_Hooks(t *testing.T) {
+	busy := "cannot destroy 'tank/data': dataset is busy\n"
+	args := []string{"destroy", "tank/data"}
+
+	tests := []struct {
+		name      string
+		policy    *RetryPolicy
+		stderrs   []string
+		wantCalls []string
+		wantErrs  []bool
+	}{
+		{
+			name:    "success",
+			stderrs: []string{""},
+			wantCalls: []string{
+				"a.before", "b.before", "a.after", "b.after",
+			},
+			wantErrs: []bool{false, false, false, false},
+		},
+		{
+			name:    "failure",
+			stderrs: []string{busy},
+			wantCalls: []string{
+				"a.before", "b.before", "a.after", "b.after",
+			},
+			wantErrs: []bool{false, false, true, true},
+		},
+		{
+			name: "retried",
+			policy: &RetryPolicy{
+				MaxAttempts:    2,
+				InitialBackoff: time.Millisecond,
+			},
+			stderrs: []string{busy, ""},
+			wantCalls: []string{
+				"a.before", "b.before", "a.after", "b.after",
+				"a.before", "b.before", "a.after", "b.after",
+			},
+			wantErrs: []bool{
+				false, false, true, true,
+				false, false, false, false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			expectRuns(r, "zfs", args, tt.stderrs...)
+
+			calls := []hookCall{}
+			m := &Manager{
+				Runner:      r,
+				RetryPolicy: tt.policy,
+				Hooks: []Hook{
+					&recordingHook{name: "a", calls: &calls},
+					&recordingHook{name: "b", calls: &calls},
+				},
+			}
+
+			err := m.DestroyDataset(ctx, "tank/data")
+
+			names := make([]string, 0, len(calls))
+			errs := make([]bool, 0, len(calls))
+			for _, c := range calls {
+				names = append(names, c.name)
+				errs = append(errs, c.err != nil)
+				assert.Equal(t, "req-1", c.reqID)
+				assert.Equal(t, "zfs", c.command)
+				assert.Equal(t, args, c.args)
+			}
+			assert.Equal(t, tt.wantCalls, names)
+			assert.Equal(t, tt.wantErrs, errs)
+
+			last := calls[len(calls)-1]
+			if last.err != nil {
+				var cmdErr *CommandError
+				require.True(t, errors.As(last.err, &cmdErr))
+				assert.Equal(t, err, last.err)
+			}
+		})
+	}
+}
+
+// logEntry is a record of a call to testLogger.
+type logEntry struct {
+	level string
+	msg   string
+	args  []interface{}
+}
+
+// testLogger is a Logger which records all log entries.
+type testLogger struct {
+	entries []logEntry
+}
+
+func (l *testLogger) InfoContext(
+	_ context.Context,
+	msg string,
+	args ...interface{},
+) {
+	l.entries = append(l.entries, logEntry{"info", msg, args})
+}
+
+func (l *testLogger) ErrorContext(
+	_ context.Context,
+	msg string,
+	args ...interface{},
+) {
+	l.entries = append(l.entries, logEntry{"error", msg, args})
+}
+
+func TestLogHook(t *testing.T) {
+	logger := &testLogger{}
+	h := NewLogHook(logger)
+	ctx := context.Background()
+	cmdErr := &CommandError{
+		Command:  "zfs",
+		Args:     []string{"destroy", "tank/data"},
+		ExitCode: 1,
+		Err:      errors.New("exit status 1"),
+		errs:     []error{ErrZFS, ErrBusy},
+	}
+
+	h.BeforeCommand(ctx, "zpool", []string{"list"})
+	h.AfterCommand(ctx, "zpool", []string{"list"}, time.Second, nil)
+	h.AfterCommand(ctx, "zfs", []string{"set", "user:note=a b", "tank"},
+		2*time.Second, nil,
+	)
+	h.AfterCommand(ctx, "zfs", []string{"destroy", "tank/data"},
+		3*time.Second, cmdErr,
+	)
+
+	assert.Equal(t, []logEntry{
+		{
+			level: "info",
+			msg:   "zfs command",
+			args: []interface{}{
+				"command", "zpool list",
+				"duration", time.Second,
+			},
+		},
+		{
+			level: "info",
+			msg:   "zfs command",
+			args: []interface{}{
+				"command", `zfs set "user:note=a b" tank`,
+				"duration", 2 * time.Second,
+			},
+		},
+		{
+			level: "error",
+			msg:   "zfs command",
+			args: []interface{}{
+				"command", "zfs destroy tank/data",
+				"duration", 3 * time.Second,
+				"exit_code", 1,
+				"error", cmdErr,
+			},
+		},
+	}, logger.entries)
+}
+
+func TestCommandCounters(t *testing.T) {
+	c := &CommandCounters{}
+	ctx := context.Background()
+	err := fmt.Errorf("failed")
+
+	assert.Empty(t, c.Stats())
+	assert.Empty(t, c.Subcommands())
+
+	c.BeforeCommand(ctx, "zfs", []string{"list"})
+	c.AfterCommand(ctx, "zfs", []string{"list"}, time.Second, nil)
+	c.AfterCommand(ctx, "zfs", []string{"list", "-H"}, time.Second, err)
+	c.AfterCommand(ctx, "zpool", []string{"import"}, time.Minute, nil)
+	c.AfterCommand(ctx, "zfs", nil, time.Millisecond, err)
+
+	assert.Equal(t, map[string]CommandStats{
+		"zfs list":     {Calls: 2, Failures: 1, Duration: 2 * time.Second},
+		"zpool import": {Calls: 1, Duration: time.Minute},
+		"zfs":          {Calls: 1, Failures: 1, Duration: time.Millisecond},
+	}, c.Stats())
+	assert.Equal(t,
+		[]string{"zfs", "zfs list", "zpool import"},
+		c.Subcommands(),
+	)
+
+	c.Reset()
+	assert.Empty(t, c.Stats())
+}
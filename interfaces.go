@@ -0,0 +1,164 @@
+package zfs
+
+import "context"
+
+//go:generate mockgen -source=$GOFILE -destination=zfsmock/${GOFILE} -package=zfsmock
+
+// DatasetManager is implemented by *Manager, and covers all dataset
+// operations, excluding mounts and encryption keys.
+type DatasetManager interface {
+	GetDatasetProperty(
+		ctx context.Context,
+		name string,
+		property string,
+	) (string, error)
+	SetDatasetProperty(
+		ctx context.Context,
+		name string,
+		property string,
+		value string,
+	) error
+	SetDatasetProperties(
+		ctx context.Context,
+		name string,
+		properties map[string]string,
+	) error
+	InheritDatasetProperty(
+		ctx context.Context,
+		name string,
+		property string,
+		recursive bool,
+	) error
+	RevertToReceived(
+		ctx context.Context,
+		name string,
+		property string,
+		recursive bool,
+	) error
+	CreateDataset(ctx context.Context, options *CreateDatasetOptions) error
+	GetDataset(
+		ctx context.Context,
+		name string,
+		properties ...string,
+	) (*Dataset, error)
+	GetDatasetBySource(
+		ctx context.Context,
+		name string,
+		sources []SourceKind,
+		properties ...string,
+	) (*Dataset, error)
+	GetDatasetReceived(
+		ctx context.Context,
+		name string,
+		properties ...string,
+	) (*Dataset, error)
+	ListDatasets(
+		ctx context.Context,
+		filter string,
+		depth uint64,
+		typ DatasetType,
+		properties ...string,
+	) ([]*Dataset, error)
+	ListDatasetsWithOptions(
+		ctx context.Context,
+		options *ListDatasetsOptions,
+	) ([]*Dataset, error)
+	WalkDatasets(
+		ctx context.Context,
+		options *ListDatasetsOptions,
+		fn func(*Dataset) error,
+	) error
+	GetDatasetTree(
+		ctx context.Context,
+		root string,
+		options *DatasetTreeOptions,
+	) (*DatasetNode, error)
+	UserSpace(
+		ctx context.Context,
+		name string,
+		options *SpaceOptions,
+	) ([]*SpaceEntry, error)
+	GroupSpace(
+		ctx context.Context,
+		name string,
+		options *SpaceOptions,
+	) ([]*SpaceEntry, error)
+	ProjectSpace(
+		ctx context.Context,
+		name string,
+		options *SpaceOptions,
+	) ([]*SpaceEntry, error)
+	ListDatasetNames(
+		ctx context.Context,
+		filter string,
+		depth uint64,
+		typ DatasetType,
+	) ([]string, error)
+	DestroyDataset(
+		ctx context.Context,
+		name string,
+		flags ...DestroyDatasetFlag,
+	) error
+}
+
+// MountManager is implemented by *Manager, and covers mounting and sharing of
+// filesystems.
+type MountManager interface {
+	MountDataset(ctx context.Context, options *MountDatasetOptions) error
+	UnmountDataset(ctx context.Context, options *UnmountDatasetOptions) error
+	ShareDataset(ctx context.Context, name string) error
+	UnshareDataset(ctx context.Context, name string) error
+	ListMounts(ctx context.Context) ([]*Mount, error)
+}
+
+// KeyManager is implemented by *Manager, and covers encryption key
+// operations.
+type KeyManager interface {
+	LoadKey(ctx context.Context, options *LoadKeyOptions) error
+	UnloadKey(ctx context.Context, options *UnloadKeyOptions) error
+	ChangeKey(ctx context.Context, options *ChangeKeyOptions) error
+	LoadAllKeys(ctx context.Context, root string, provider KeyProvider) error
+}
+
+// PoolManager is implemented by *Manager, and covers all pool operations.
+type PoolManager interface {
+	GetPoolProperty(
+		ctx context.Context,
+		name string,
+		property string,
+	) (string, error)
+	SetPoolProperty(
+		ctx context.Context,
+		name string,
+		property string,
+		value string,
+	) error
+	SetPoolProperties(
+		ctx context.Context,
+		name string,
+		properties map[string]string,
+	) error
+	CreatePool(ctx context.Context, options *CreatePoolOptions) error
+	GetPool(
+		ctx context.Context,
+		name string,
+		properties ...string,
+	) (*Pool, error)
+	ListPools(ctx context.Context, properties ...string) ([]*Pool, error)
+	ListPoolNames(ctx context.Context) ([]string, error)
+	DestroyPool(ctx context.Context, name string, force bool) error
+	ImportPool(ctx context.Context, options *ImportPoolOptions) error
+	ExportPool(ctx context.Context, name string, force bool) error
+}
+
+// Interface is implemented by *Manager, and covers all of its operations.
+// Accept it instead of *Manager to allow substituting a mock, like those
+// provided by the zfsmock package.
+type Interface interface {
+	DatasetManager
+	MountManager
+	KeyManager
+	PoolManager
+}
+
+var _ Interface = &Manager{}
@@ -0,0 +1,65 @@
+// Package propval provides helpers for validating zfs and zpool property
+// values, shared by the zfsprops and zpoolprops packages.
+package propval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizeRegexp = regexp.MustCompile(
+	`^(?i)[0-9]+(\.[0-9]+)?\s?([bkmgtpez](i?b)?)?$`,
+)
+
+// Size reports whether s is a valid size value as accepted by zfs and zpool,
+// for example "512", "100G", "1.5g", or "1536MB".
+func Size(s string) bool {
+	return sizeRegexp.MatchString(s)
+}
+
+// Number reports whether s is a valid unsigned integer.
+func Number(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+
+	return err == nil
+}
+
+// OneOf reports whether s is one of values.
+func OneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Describe returns a description of the accepted values, for use in error
+// messages, for example: "a size like 10G, or one of: none, auto".
+func Describe(kind string, values []string, pattern *regexp.Regexp) string {
+	var parts []string
+	switch kind {
+	case "size":
+		parts = append(parts, "a size like 10G")
+	case "number":
+		parts = append(parts, "a number")
+	}
+	if len(values) > 0 {
+		vs := make([]string, 0, len(values))
+		for _, v := range values {
+			if v == "" {
+				v = "''"
+			}
+			vs = append(vs, v)
+		}
+		parts = append(parts, "one of: "+strings.Join(vs, ", "))
+	}
+	if pattern != nil {
+		parts = append(parts, fmt.Sprintf("matching %s", pattern))
+	}
+
+	return strings.Join(parts, ", or ")
+}
@@ -0,0 +1,90 @@
+package propval
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: "512", want: true},
+		{s: "100G", want: true},
+		{s: "100g", want: true},
+		{s: "1.5T", want: true},
+		{s: "1536MB", want: true},
+		{s: "10GiB", want: true},
+		{s: "10 G", want: true},
+		{s: "", want: false},
+		{s: "G", want: false},
+		{s: "-1G", want: false},
+		{s: "10X", want: false},
+		{s: "lots", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			assert.Equal(t, tt.want, Size(tt.s))
+		})
+	}
+}
+
+func TestNumber(t *testing.T) {
+	assert.True(t, Number("0"))
+	assert.True(t, Number("18446744073709551615"))
+	assert.False(t, Number("18446744073709551616"))
+	assert.False(t, Number("-1"))
+	assert.False(t, Number("1.5"))
+	assert.False(t, Number(""))
+}
+
+func TestOneOf(t *testing.T) {
+	assert.True(t, OneOf("on", []string{"on", "off"}))
+	assert.False(t, OneOf("On", []string{"on", "off"}))
+	assert.False(t, OneOf("on", nil))
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		values  []string
+		pattern *regexp.Regexp
+		want    string
+	}{
+		{
+			name:   "enum",
+			kind:   "enum",
+			values: []string{"on", "off"},
+			want:   "one of: on, off",
+		},
+		{
+			name:   "size with values",
+			kind:   "size",
+			values: []string{"none"},
+			want:   "a size like 10G, or one of: none",
+		},
+		{
+			name: "number",
+			kind: "number",
+			want: "a number",
+		},
+		{
+			name:    "values and pattern",
+			kind:    "string",
+			values:  []string{"none", ""},
+			pattern: regexp.MustCompile(`^/`),
+			want:    "one of: none, '', or matching ^/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Describe(tt.kind, tt.values, tt.pattern)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// KeyProvider provides encryption keys for datasets. It is used by
+// Manager.LoadAllKeys to fetch the key of each encryption root which does not
+// have its key loaded.
+//
+// Implementations should return an error wrapping ErrKeyNotFound when they
+// have no key for the given dataset.
+type KeyProvider interface {
+	Key(ctx context.Context, dataset string) ([]byte, error)
+}
+
+// KeyProviderFunc is an adapter to allow the use of ordinary functions as a
+// KeyProvider.
+type KeyProviderFunc func(ctx context.Context, dataset string) ([]byte, error)
+
+// Key calls f(ctx, dataset).
+func (f KeyProviderFunc) Key(
+	ctx context.Context,
+	dataset string,
+) ([]byte, error) {
+	return f(ctx, dataset)
+}
+
+// MemoryKeyProvider is a KeyProvider which returns keys from a map of dataset
+// names to keys.
+type MemoryKeyProvider map[string][]byte
+
+// Key returns the key for dataset from the map.
+func (p MemoryKeyProvider) Key(
+	_ context.Context,
+	dataset string,
+) ([]byte, error) {
+	if key, ok := p[dataset]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, dataset)
+}
+
+// FileKeyProvider is a KeyProvider which reads keys from files within Dir.
+//
+// The key for a dataset is read from a file at the same relative path as the
+// dataset name. For example, with a Dir of "/etc/zfs/keys", the key for
+// "tank/secret" is read from "/etc/zfs/keys/tank/secret".
+type FileKeyProvider struct {
+	Dir string
+}
+
+// Key returns the contents of the key file for dataset.
+func (p *FileKeyProvider) Key(
+	_ context.Context,
+	dataset string,
+) ([]byte, error) {
+	if err := validateDatasetName(dataset); err != nil {
+		return nil, err
+	}
+
+	key, err := os.ReadFile(filepath.Join(p.Dir, filepath.FromSlash(dataset)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, dataset)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// EnvKeyProvider is a KeyProvider which reads keys from environment variables.
+//
+// The variable name for a dataset is Prefix followed by the upper-cased dataset
+// name, with all characters other than letters and digits replaced by "_".
+// For example, with a Prefix of "ZFS_KEY_", the key for "tank/my-secret" is
+// read from "ZFS_KEY_TANK_MY_SECRET".
+type EnvKeyProvider struct {
+	Prefix string
+}
+
+// Key returns the value of the environment variable for dataset.
+func (p *EnvKeyProvider) Key(
+	_ context.Context,
+	dataset string,
+) ([]byte, error) {
+	name := p.VarName(dataset)
+	if v, ok := os.LookupEnv(name); ok {
+		return []byte(v), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s (%s)", ErrKeyNotFound, dataset, name)
+}
+
+// VarName returns the environment variable name used for dataset.
+func (p *EnvKeyProvider) VarName(dataset string) string {
+	return p.Prefix + strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII &&
+			(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, dataset)
+}
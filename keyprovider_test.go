@@ -0,0 +1,200 @@
+package zfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyProviderFunc_Key(t *testing.T) {
+	ctx := context.Background()
+	p := KeyProviderFunc(func(_ context.Context, ds string) ([]byte, error) {
+		return []byte("key-for-" + ds), nil
+	})
+
+	got, err := p.Key(ctx, "tank/secret")
+
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key-for-tank/secret"), got)
+}
+
+func TestMemoryKeyProvider_Key(t *testing.T) {
+	p := MemoryKeyProvider{
+		"tank/secret":       []byte("hunter22"),
+		"tank/other-secret": []byte("correct horse battery"),
+	}
+
+	tests := []struct {
+		name           string
+		dataset        string
+		want           []byte
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "found",
+			dataset: "tank/secret",
+			want:    []byte("hunter22"),
+		},
+		{
+			name:    "other found",
+			dataset: "tank/other-secret",
+			want:    []byte("correct horse battery"),
+		},
+		{
+			name:           "not found",
+			dataset:        "tank/nope",
+			wantErr:        "key not found: tank/nope",
+			wantErrTargets: []error{Err, ErrKeyNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Key(context.Background(), tt.dataset)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFileKeyProvider_Key(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tank"), 0o700))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "tank", "secret"), []byte("hunter22"), 0o600,
+	))
+
+	p := &FileKeyProvider{Dir: dir}
+
+	tests := []struct {
+		name           string
+		dataset        string
+		want           []byte
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "found",
+			dataset: "tank/secret",
+			want:    []byte("hunter22"),
+		},
+		{
+			name:           "not found",
+			dataset:        "tank/nope",
+			wantErr:        "key not found: tank/nope",
+			wantErrTargets: []error{Err, ErrKeyNotFound},
+		},
+		{
+			name:    "invalid name",
+			dataset: "/tank/secret",
+			wantErr: "invalid name '/tank/secret': " +
+				"leading slash in name",
+			wantErrTargets: []error{Err, ErrInvalidName},
+		},
+		{
+			name:    "parent directory",
+			dataset: "tank/../../etc/shadow",
+			wantErr: "invalid name 'tank/../../etc/shadow': " +
+				"parent reference, '..' is found in name",
+			wantErrTargets: []error{Err, ErrInvalidName},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Key(context.Background(), tt.dataset)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnvKeyProvider_VarName(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		dataset string
+		want    string
+	}{
+		{
+			name:    "no prefix",
+			dataset: "tank/secret",
+			want:    "TANK_SECRET",
+		},
+		{
+			name:    "prefix",
+			prefix:  "ZFS_KEY_",
+			dataset: "tank/my-secret",
+			want:    "ZFS_KEY_TANK_MY_SECRET",
+		},
+		{
+			name:    "special characters",
+			prefix:  "ZFS_KEY_",
+			dataset: "tank/a.b:c_d/ünï",
+			want:    "ZFS_KEY_TANK_A_B_C_D__N_",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &EnvKeyProvider{Prefix: tt.prefix}
+
+			got := p.VarName(tt.dataset)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnvKeyProvider_Key(t *testing.T) {
+	setenv(t, "GO_ZFS_TEST_KEY_TANK_SECRET", "hunter22")
+
+	p := &EnvKeyProvider{Prefix: "GO_ZFS_TEST_KEY_"}
+
+	got, err := p.Key(context.Background(), "tank/secret")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hunter22"), got)
+
+	_, err = p.Key(context.Background(), "tank/nope")
+	assert.EqualError(t, err,
+		"key not found: tank/nope (GO_ZFS_TEST_KEY_TANK_NOPE)",
+	)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// setenv sets an environment variable for the duration of the test.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	prev, ok := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/krystal/go-zfs/zfsprops"
+	"go.uber.org/multierr"
+)
+
+var errInvalidKeyOptions = multierr.Append(ErrZFS, ErrInvalidKeyOptions)
+
+const (
+	promptKeyLocation    = "prompt"
+	keyStatusUnavailable = "unavailable"
+)
+
+// LoadKeyOptions are options for loading the encryption key of one or more
+// datasets.
+type LoadKeyOptions struct {
+	// Name of the dataset to load the key for. Required unless All is set.
+	Name string
+
+	// All indicates whether to load keys for all encryption roots in all
+	// imported pools by passing the -a flag. Cannot be combined with Name.
+	All bool
+
+	// Recursive indicates whether to load keys for all encryption roots below
+	// the named dataset by passing the -r flag.
+	Recursive bool
+
+	// DryRun indicates whether to only verify the key without loading it, by
+	// passing the -n flag.
+	DryRun bool
+
+	// KeyLocation overrides the keylocation property of the dataset by passing
+	// the -L flag.
+	//
+	// Ignored when Key is set.
+	KeyLocation string
+
+	// Key is piped to stdin of the zfs command, and -L prompt is passed so the
+	// key is read from it, regardless of the dataset's keylocation property.
+	// This ensures the key never appears on the command line or on disk.
+	//
+	// Cannot be combined with All or Recursive.
+	Key io.Reader
+}
+
+// LoadKey loads the encryption key of datasets as per the given options.
+func (m *Manager) LoadKey(ctx context.Context, options *LoadKeyOptions) error {
+	if options == nil {
+		return errInvalidKeyOptions
+	}
+	if err := validateKeyTarget(options.Name, options.All); err != nil {
+		return err
+	}
+	if options.Key != nil && (options.All || options.Recursive) {
+		return fmt.Errorf(
+			"%w: key cannot be combined with all or recursive",
+			errInvalidKeyOptions,
+		)
+	}
+
+	args := []string{"load-key"}
+	if options.Recursive {
+		args = append(args, "-r")
+	}
+	if options.DryRun {
+		args = append(args, "-n")
+	}
+	switch {
+	case options.Key != nil:
+		args = append(args, "-L", promptKeyLocation)
+	case options.KeyLocation != "":
+		args = append(args, "-L", options.KeyLocation)
+	}
+	args = appendNameOrAll(args, options.Name, options.All)
+
+	_, err := m.zfsWithStdin(ctx, options.Key, args...)
+
+	return err
+}
+
+// UnloadKeyOptions are options for unloading the encryption key of one or more
+// datasets.
+type UnloadKeyOptions struct {
+	// Name of the dataset to unload the key for. Required unless All is set.
+	Name string
+
+	// All indicates whether to unload keys for all encryption roots in all
+	// imported pools by passing the -a flag. Cannot be combined with Name.
+	All bool
+
+	// Recursive indicates whether to unload keys for all encryption roots
+	// below the named dataset by passing the -r flag.
+	Recursive bool
+}
+
+// UnloadKey unloads the encryption key of datasets as per the given options.
+//
+// Datasets must be unmounted before their key can be unloaded.
+func (m *Manager) UnloadKey(
+	ctx context.Context,
+	options *UnloadKeyOptions,
+) error {
+	if options == nil {
+		return errInvalidKeyOptions
+	}
+	if err := validateKeyTarget(options.Name, options.All); err != nil {
+		return err
+	}
+
+	args := []string{"unload-key"}
+	if options.Recursive {
+		args = append(args, "-r")
+	}
+	args = appendNameOrAll(args, options.Name, options.All)
+
+	_, err := m.zfs(ctx, args...)
+
+	return err
+}
+
+// ChangeKeyOptions are options for changing the encryption key of a dataset.
+type ChangeKeyOptions struct {
+	// Name of the dataset to change the key for. (required)
+	Name string
+
+	// Inherit indicates whether the dataset should inherit its key from its
+	// parent encryption root by passing the -i flag. Effectively making the
+	// dataset no longer an encryption root.
+	//
+	// Cannot be combined with Properties or Key.
+	Inherit bool
+
+	// Load indicates whether to load the existing key before changing it, by
+	// passing the -l flag.
+	Load bool
+
+	// Properties is a map of keylocation, keyformat, and pbkdf2iters
+	// properties (-o) to set along with the new key.
+	Properties map[string]string
+
+	// Key is the new key, which is piped to stdin of the zfs command. Unless
+	// Properties specifies a keylocation, -o keylocation=prompt is passed so
+	// the key is read from stdin.
+	Key io.Reader
+}
+
+// ChangeKey changes the encryption key of a dataset as per the given options.
+func (m *Manager) ChangeKey(
+	ctx context.Context,
+	options *ChangeKeyOptions,
+) error {
+	if options == nil {
+		return errInvalidKeyOptions
+	}
+	if err := validateDatasetName(options.Name); err != nil {
+		return multierr.Combine(ErrZFS, ErrInvalidKeyOptions, err)
+	}
+	if options.Inherit && (len(options.Properties) > 0 || options.Key != nil) {
+		return fmt.Errorf(
+			"%w: inherit cannot be combined with properties or key",
+			errInvalidKeyOptions,
+		)
+	}
+
+	args := []string{"change-key"}
+	if options.Load {
+		args = append(args, "-l")
+	}
+	if options.Inherit {
+		args = append(args, "-i")
+	}
+
+	props := options.Properties
+	if options.Key != nil {
+		props = withPromptKeyLocation(props)
+	}
+
+	propArgs, err := propertyMapFlags("-o", props)
+	if err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
+	args = append(args, propArgs...)
+	args = append(args, options.Name)
+
+	_, err = m.zfsWithStdin(ctx, options.Key, args...)
+
+	return err
+}
+
+// LoadAllKeys loads the keys of all encryption roots at or below root which do
+// not currently have their key loaded. Keys are fetched from provider, and
+// passed to zfs via stdin.
+//
+// If root is empty, encryption roots in all imported pools are considered.
+// Failure to fetch or load a key does not stop keys for remaining encryption
+// roots from being loaded, and all errors encountered are returned combined.
+func (m *Manager) LoadAllKeys(
+	ctx context.Context,
+	root string,
+	provider KeyProvider,
+) error {
+	if root != "" {
+		if err := validateDatasetName(root); err != nil {
+			return multierr.Append(ErrZFS, err)
+		}
+	}
+
+	datasets, err := m.ListDatasets(
+		ctx, root, 0, JoinTypes(FilesystemType, VolumeType),
+		zfsprops.EncryptionRoot, zfsprops.KeyStatus,
+	)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for _, ds := range datasets {
+		encRoot, _ := ds.String(zfsprops.EncryptionRoot)
+		status, _ := ds.String(zfsprops.KeyStatus)
+		if encRoot == ds.Name && status == keyStatusUnavailable {
+			names = append(names, ds.Name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs error
+	for _, name := range names {
+		key, err := provider.Key(ctx, name)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+
+			continue
+		}
+
+		err = m.LoadKey(ctx, &LoadKeyOptions{
+			Name: name,
+			Key:  bytes.NewReader(key),
+		})
+		if err != nil {
+			errs = multierr.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// withPromptKeyLocation returns a copy of props with keylocation set to
+// "prompt", unless props already specifies a keylocation.
+func withPromptKeyLocation(props map[string]string) map[string]string {
+	if _, ok := props[zfsprops.KeyLocation]; ok {
+		return props
+	}
+
+	r := make(map[string]string, len(props)+1)
+	for k, v := range props {
+		r[k] = v
+	}
+	r[zfsprops.KeyLocation] = promptKeyLocation
+
+	return r
+}
+
+func validateKeyTarget(name string, all bool) error {
+	if all && name != "" {
+		return fmt.Errorf(
+			"%w: all cannot be combined with name", errInvalidKeyOptions,
+		)
+	}
+	if all {
+		return nil
+	}
+	if err := validateDatasetName(name); err != nil {
+		return multierr.Combine(ErrZFS, ErrInvalidKeyOptions, err)
+	}
+
+	return nil
+}
+
+// appendNameOrAll appends the -a flag to args if all is true, otherwise name.
+func appendNameOrAll(args []string, name string, all bool) []string {
+	if all {
+		return append(args, "-a")
+	}
+
+	return append(args, name)
+}
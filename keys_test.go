@@ -0,0 +1,793 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/krystal/go-zfs/zfsprops"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_LoadKey(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type args struct {
+		options *LoadKeyOptions
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantArgs       []string
+		wantStdin      string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			args:    args{},
+			wantErr: "zfs; invalid key options",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "empty dataset name",
+			args: args{
+				options: &LoadKeyOptions{},
+			},
+			wantErr: "zfs; invalid key options; invalid name: empty name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "all with name",
+			args: args{
+				options: &LoadKeyOptions{
+					Name: "tank/secret",
+					All:  true,
+				},
+			},
+			wantErr: "zfs; invalid key options: " +
+				"all cannot be combined with name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "name",
+			args: args{
+				options: &LoadKeyOptions{Name: "tank/secret"},
+			},
+			wantArgs: []string{"load-key", "tank/secret"},
+		},
+		{
+			name: "all",
+			args: args{
+				options: &LoadKeyOptions{All: true},
+			},
+			wantArgs: []string{"load-key", "-a"},
+		},
+		{
+			name: "recursive dry-run",
+			args: args{
+				options: &LoadKeyOptions{
+					Name:      "tank/secret",
+					Recursive: true,
+					DryRun:    true,
+				},
+			},
+			wantArgs: []string{"load-key", "-r", "-n", "tank/secret"},
+		},
+		{
+			name: "key location",
+			args: args{
+				options: &LoadKeyOptions{
+					Name:        "tank/secret",
+					KeyLocation: "file:///etc/keys/secret",
+				},
+			},
+			wantArgs: []string{
+				"load-key", "-L", "file:///etc/keys/secret", "tank/secret",
+			},
+		},
+		{
+			name: "key",
+			args: args{
+				options: &LoadKeyOptions{
+					Name:        "tank/secret",
+					KeyLocation: "file:///etc/keys/secret",
+					Key:         strings.NewReader("hunter22"),
+				},
+			},
+			wantArgs:  []string{"load-key", "-L", "prompt", "tank/secret"},
+			wantStdin: "hunter22",
+		},
+		{
+			name: "key with recursive",
+			args: args{
+				options: &LoadKeyOptions{
+					Name:      "tank/secret",
+					Recursive: true,
+					Key:       strings.NewReader("hunter22"),
+				},
+			},
+			wantErr: "zfs; invalid key options: " +
+				"key cannot be combined with all or recursive",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "key with all",
+			args: args{
+				options: &LoadKeyOptions{
+					All: true,
+					Key: strings.NewReader("hunter22"),
+				},
+			},
+			wantErr: "zfs; invalid key options: " +
+				"key cannot be combined with all or recursive",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "dataset does not exist",
+			args: args{
+				options: &LoadKeyOptions{Name: "tank/nope"},
+			},
+			wantArgs:   []string{"load-key", "tank/nope"},
+			stderr:     "cannot open 'tank/nope': dataset does not exist\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/nope': dataset does not exist",
+			wantErrTargets: []error{Err, ErrZFS, ErrNotFound},
+		},
+		{
+			name: "command error",
+			args: args{
+				options: &LoadKeyOptions{
+					Name: "tank/secret",
+					Key:  strings.NewReader("wrong"),
+				},
+			},
+			wantArgs:  []string{"load-key", "-L", "prompt", "tank/secret"},
+			wantStdin: "wrong",
+			stderr: "Key load error: Incorrect key provided for " +
+				"'tank/secret'.\n",
+			commandErr: errors.New("exit status 255"),
+			wantErr: "zfs; exit status 255: Key load error: " +
+				"Incorrect key provided for 'tank/secret'.",
+			wantErrTargets: []error{Err, ErrZFS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				var stdinMatcher gomock.Matcher = gomock.Nil()
+				if tt.wantStdin != "" {
+					stdinMatcher = gomock.Not(gomock.Nil())
+				}
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					stdinMatcher,
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					stdin io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					if stdin != nil {
+						b, err := io.ReadAll(stdin)
+						require.NoError(t, err)
+						assert.Equal(t, tt.wantStdin, string(b))
+					}
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.LoadKey(ctx, tt.args.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_UnloadKey(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type args struct {
+		options *UnloadKeyOptions
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantArgs       []string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			args:    args{},
+			wantErr: "zfs; invalid key options",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "invalid dataset name",
+			args: args{
+				options: &UnloadKeyOptions{Name: "/tank/secret"},
+			},
+			wantErr: "zfs; invalid key options; invalid name '/tank/secret': " +
+				"leading slash in name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "all with name",
+			args: args{
+				options: &UnloadKeyOptions{
+					Name: "tank/secret",
+					All:  true,
+				},
+			},
+			wantErr: "zfs; invalid key options: " +
+				"all cannot be combined with name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "name",
+			args: args{
+				options: &UnloadKeyOptions{Name: "tank/secret"},
+			},
+			wantArgs: []string{"unload-key", "tank/secret"},
+		},
+		{
+			name: "recursive",
+			args: args{
+				options: &UnloadKeyOptions{
+					Name:      "tank/secret",
+					Recursive: true,
+				},
+			},
+			wantArgs: []string{"unload-key", "-r", "tank/secret"},
+		},
+		{
+			name: "all",
+			args: args{
+				options: &UnloadKeyOptions{All: true},
+			},
+			wantArgs: []string{"unload-key", "-a"},
+		},
+		{
+			name: "command error",
+			args: args{
+				options: &UnloadKeyOptions{Name: "tank/secret"},
+			},
+			wantArgs: []string{"unload-key", "tank/secret"},
+			stderr: "Key unload error: 'tank/secret' is busy.\n" +
+				"1 / 1 keys unloaded\n",
+			commandErr: errors.New("exit status 255"),
+			wantErr: "zfs; exit status 255: Key unload error: " +
+				"'tank/secret' is busy.: 1 / 1 keys unloaded",
+			wantErrTargets: []error{Err, ErrZFS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.UnloadKey(ctx, tt.args.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_ChangeKey(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type args struct {
+		options *ChangeKeyOptions
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantArgs       []string
+		wantStdin      string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			args:    args{},
+			wantErr: "zfs; invalid key options",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "empty dataset name",
+			args: args{
+				options: &ChangeKeyOptions{},
+			},
+			wantErr: "zfs; invalid key options; invalid name: empty name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "inherit",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name:    "tank/secret/child",
+					Inherit: true,
+					Load:    true,
+				},
+			},
+			wantArgs: []string{"change-key", "-l", "-i", "tank/secret/child"},
+		},
+		{
+			name: "inherit with key",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name:    "tank/secret/child",
+					Inherit: true,
+					Key:     strings.NewReader("hunter22"),
+				},
+			},
+			wantErr: "zfs; invalid key options: " +
+				"inherit cannot be combined with properties or key",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "inherit with properties",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name:    "tank/secret/child",
+					Inherit: true,
+					Properties: map[string]string{
+						zfsprops.KeyFormat: "passphrase",
+					},
+				},
+			},
+			wantErr: "zfs; invalid key options: " +
+				"inherit cannot be combined with properties or key",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidKeyOptions,
+			},
+		},
+		{
+			name: "properties",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name: "tank/secret",
+					Properties: map[string]string{
+						zfsprops.KeyFormat:   "raw",
+						zfsprops.KeyLocation: "file:///etc/keys/secret",
+					},
+				},
+			},
+			wantArgs: []string{
+				"change-key",
+				"-o", "keyformat=raw",
+				"-o", "keylocation=file:///etc/keys/secret",
+				"tank/secret",
+			},
+		},
+		{
+			name: "key",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name: "tank/secret",
+					Properties: map[string]string{
+						zfsprops.KeyFormat:        "passphrase",
+						zfsprops.PBKDF2Iterations: "500000",
+					},
+					Key: strings.NewReader("correct horse battery"),
+				},
+			},
+			wantArgs: []string{
+				"change-key",
+				"-o", "keyformat=passphrase",
+				"-o", "keylocation=prompt",
+				"-o", "pbkdf2iters=500000",
+				"tank/secret",
+			},
+			wantStdin: "correct horse battery",
+		},
+		{
+			name: "key with keylocation",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name: "tank/secret",
+					Properties: map[string]string{
+						zfsprops.KeyLocation: "prompt",
+					},
+					Key: strings.NewReader("correct horse battery"),
+				},
+			},
+			wantArgs: []string{
+				"change-key", "-o", "keylocation=prompt", "tank/secret",
+			},
+			wantStdin: "correct horse battery",
+		},
+		{
+			name: "invalid 'all' property",
+			args: args{
+				options: &ChangeKeyOptions{
+					Name: "tank/secret",
+					Properties: map[string]string{
+						"all": "on",
+					},
+				},
+			},
+			wantErr: "zfs; invalid property: 'all' is not a valid property",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidProperty,
+			},
+		},
+		{
+			name: "command error",
+			args: args{
+				options: &ChangeKeyOptions{Name: "tank/secret"},
+			},
+			wantArgs: []string{"change-key", "tank/secret"},
+			stderr: "Key change error: Key must be loaded for " +
+				"'tank/secret'.\n",
+			commandErr: errors.New("exit status 255"),
+			wantErr: "zfs; key not loaded; exit status 255: " +
+				"Key change error: Key must be loaded for 'tank/secret'.",
+			wantErrTargets: []error{Err, ErrZFS, ErrKeyNotLoaded},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				var stdinMatcher gomock.Matcher = gomock.Nil()
+				if tt.wantStdin != "" {
+					stdinMatcher = gomock.Not(gomock.Nil())
+				}
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					stdinMatcher,
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					stdin io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					if stdin != nil {
+						b, err := io.ReadAll(stdin)
+						require.NoError(t, err)
+						assert.Equal(t, tt.wantStdin, string(b))
+					}
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.ChangeKey(ctx, tt.args.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_LoadAllKeys(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type command struct {
+		args       []string
+		stdin      string
+		stdout     string
+		stderr     string
+		commandErr error
+	}
+	listArgs := func(root string) []string {
+		args := []string{
+			"get", "-Hp", "-o", "name,property,value,source", "-r",
+			"-t", "filesystem,volume", "encryptionroot,keystatus",
+		}
+		if root != "" {
+			args = append(args, root)
+		}
+
+		return args
+	}
+	listStdout := `tank	encryptionroot	-	-
+tank	keystatus	-	-
+tank/a	encryptionroot	tank/a	-
+tank/a	keystatus	unavailable	-
+tank/a/child	encryptionroot	tank/a	-
+tank/a/child	keystatus	unavailable	-
+tank/b	encryptionroot	tank/b	-
+tank/b	keystatus	available	-
+tank/c	encryptionroot	tank/c	-
+tank/c	keystatus	unavailable	-
+`
+
+	tests := []struct {
+		name           string
+		root           string
+		provider       KeyProvider
+		commands       []command
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:           "invalid root",
+			root:           "tank/",
+			wantErr:        "zfs; invalid name 'tank/': trailing slash in name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
+		{
+			name: "all pools",
+			provider: MemoryKeyProvider{
+				"tank/a": []byte("key-a"),
+				"tank/b": []byte("key-b"),
+				"tank/c": []byte("key-c"),
+			},
+			commands: []command{
+				{args: listArgs(""), stdout: listStdout},
+				{
+					args:  []string{"load-key", "-L", "prompt", "tank/a"},
+					stdin: "key-a",
+				},
+				{
+					args:  []string{"load-key", "-L", "prompt", "tank/c"},
+					stdin: "key-c",
+				},
+			},
+		},
+		{
+			name: "root",
+			root: "tank/a",
+			provider: MemoryKeyProvider{
+				"tank/a": []byte("key-a"),
+			},
+			commands: []command{
+				{
+					args: listArgs("tank/a"),
+					stdout: "tank/a\tencryptionroot\ttank/a\t-\n" +
+						"tank/a\tkeystatus\tunavailable\t-\n",
+				},
+				{
+					args:  []string{"load-key", "-L", "prompt", "tank/a"},
+					stdin: "key-a",
+				},
+			},
+		},
+		{
+			name:     "nothing to load",
+			root:     "tank/b",
+			provider: MemoryKeyProvider{},
+			commands: []command{
+				{
+					args: listArgs("tank/b"),
+					stdout: "tank/b\tencryptionroot\ttank/b\t-\n" +
+						"tank/b\tkeystatus\tavailable\t-\n",
+				},
+			},
+		},
+		{
+			name: "missing and failing keys",
+			provider: MemoryKeyProvider{
+				"tank/c": []byte("wrong"),
+			},
+			commands: []command{
+				{args: listArgs(""), stdout: listStdout},
+				{
+					args:  []string{"load-key", "-L", "prompt", "tank/c"},
+					stdin: "wrong",
+					stderr: "Key load error: Incorrect key provided " +
+						"for 'tank/c'.\n",
+					commandErr: errors.New("exit status 255"),
+				},
+			},
+			wantErr: "key not found: tank/a; zfs; exit status 255: " +
+				"Key load error: Incorrect key provided for 'tank/c'.",
+			wantErrTargets: []error{Err, ErrZFS, ErrKeyNotFound},
+		},
+		{
+			name:     "list error",
+			root:     "tank/nope",
+			provider: MemoryKeyProvider{},
+			commands: []command{
+				{
+					args: listArgs("tank/nope"),
+					stderr: "cannot open 'tank/nope': " +
+						"dataset does not exist\n",
+					commandErr: errors.New("exit status 1"),
+				},
+			},
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/nope': dataset does not exist",
+			wantErrTargets: []error{Err, ErrZFS, ErrNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+
+			calls := []*gomock.Call{}
+			for _, cmd := range tt.commands {
+				cmd := cmd
+				var stdinMatcher gomock.Matcher = gomock.Nil()
+				if cmd.stdin != "" {
+					stdinMatcher = gomock.Not(gomock.Nil())
+				}
+				calls = append(calls, r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					stdinMatcher,
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					cmd.args,
+				).DoAndReturn(func(
+					_ context.Context,
+					stdin io.Reader,
+					stdout io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					if stdin != nil {
+						b, err := io.ReadAll(stdin)
+						require.NoError(t, err)
+						assert.Equal(t, cmd.stdin, string(b))
+					}
+					_, _ = stdout.Write([]byte(cmd.stdout))
+					_, _ = stderr.Write([]byte(cmd.stderr))
+
+					return cmd.commandErr
+				}))
+			}
+			gomock.InOrder(calls...)
+
+			m := &Manager{Runner: r}
+
+			err := m.LoadAllKeys(ctx, tt.root, tt.provider)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package zfs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		properties []string
+		want       []*Dataset
+	}{
+		{
+			name:       "empty",
+			data:       "",
+			properties: []string{"used"},
+			want:       []*Dataset{},
+		},
+		{
+			name:       "datasets",
+			data:       "tank\t300\t-\ntank/a\t100\t/mnt/a\n",
+			properties: []string{"used", "mountpoint"},
+			want: []*Dataset{
+				{
+					Name: "tank",
+					Properties: Properties{
+						"used": {
+							Name: "tank", Property: "used", Value: "300",
+						},
+						"mountpoint": {
+							Name: "tank", Property: "mountpoint", Value: "-",
+						},
+					},
+				},
+				{
+					Name: "tank/a",
+					Properties: Properties{
+						"used": {
+							Name: "tank/a", Property: "used", Value: "100",
+						},
+						"mountpoint": {
+							Name:     "tank/a",
+							Property: "mountpoint",
+							Value:    "/mnt/a",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "without trailing newline",
+			data:       "tank/a@snap\t0",
+			properties: []string{"used"},
+			want: []*Dataset{
+				{
+					Name: "tank/a@snap",
+					Properties: Properties{
+						"used": {
+							Name: "tank/a@snap", Property: "used", Value: "0",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "empty values",
+			data:       "tank\t\t\n",
+			properties: []string{"comment", "other"},
+			want: []*Dataset{
+				{
+					Name: "tank",
+					Properties: Properties{
+						"comment": {Name: "tank", Property: "comment"},
+						"other":   {Name: "tank", Property: "other"},
+					},
+				},
+			},
+		},
+		{
+			name:       "ignores lines with wrong number of fields",
+			data:       "tank\t300\ntank/a\t100\t1\ntank/b\n\ntank/c\t200\n",
+			properties: []string{"used"},
+			want: []*Dataset{
+				{
+					Name: "tank",
+					Properties: Properties{
+						"used": {
+							Name: "tank", Property: "used", Value: "300",
+						},
+					},
+				},
+				{
+					Name: "tank/c",
+					Properties: Properties{
+						"used": {
+							Name: "tank/c", Property: "used", Value: "200",
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseList([]byte(tt.data), tt.properties)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+var benchmarkProperties = []string{
+	"used", "avail", "refer", "mountpoint", "compression", "createtxg",
+}
+
+// benchmarkOutput returns fake output for n snapshots in the format of zfs
+// list when list is true, and zfs get otherwise.
+func benchmarkOutput(n int, list bool) []byte {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("tank/data/set%d@snap%d", i%100, i)
+		values := []string{
+			"1048576", "-", "52428800", "-", "lz4", fmt.Sprint(i),
+		}
+		if list {
+			fmt.Fprintf(&b, "%s\t%s\n", name, strings.Join(values, "\t"))
+
+			continue
+		}
+		for j, prop := range benchmarkProperties {
+			fmt.Fprintf(&b, "%s\t%s\t%s\t-\n", name, prop, values[j])
+		}
+	}
+
+	return []byte(b.String())
+}
+
+func BenchmarkParseList(b *testing.B) {
+	data := benchmarkOutput(10000, true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parseList(data, benchmarkProperties)
+	}
+}
+
+func BenchmarkParseTabularNewProperties(b *testing.B) {
+	data := benchmarkOutput(10000, false)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		records := parseTabular(data)
+		props := newProperties(records)
+		for _, name := range recordNames(records) {
+			NewDataset(name, props[name])
+		}
+	}
+}
@@ -18,6 +18,13 @@ var (
 	ErrInvalidName          = fmt.Errorf("%winvalid name", Err)
 	ErrInvalidProperty      = fmt.Errorf("%winvalid property", Err)
 	ErrInvalidCreateOptions = fmt.Errorf("%winvalid create options", Err)
+	ErrInvalidImportOptions = fmt.Errorf("%winvalid import options", Err)
+	ErrInvalidKeyOptions    = fmt.Errorf("%winvalid key options", Err)
+	ErrKeyNotFound          = fmt.Errorf("%wkey not found", Err)
+	ErrInvalidMountOptions  = fmt.Errorf("%winvalid mount options", Err)
+	ErrInvalidListOptions   = fmt.Errorf("%winvalid list options", Err)
+	ErrInvalidStruct        = fmt.Errorf("%winvalid struct", Err)
+	ErrInvalidSpaceOptions  = fmt.Errorf("%winvalid space options", Err)
 )
 
 // Manager is used to perform all zfs and zpool operations.
@@ -27,6 +34,20 @@ var (
 // example provides a "Sudo" runner struct that executes all commands via sudo.
 type Manager struct {
 	Runner runner.Runner
+
+	// RetryPolicy optionally enables retrying of failed commands. If nil,
+	// commands are not retried. It can be overridden for individual calls with
+	// WithRetryPolicy.
+	RetryPolicy *RetryPolicy
+
+	// Hooks are notified around every zfs and zpool command run, in the order
+	// given. Useful for logging, metrics, and audit trails.
+	Hooks []Hook
+
+	// Plan optionally enables dry-run mode. If set, mutating commands like
+	// create, destroy, set, inherit, import, and export are recorded in Plan
+	// instead of being run, while read-only commands still run as normal.
+	Plan *Plan
 }
 
 // New returns a new Manager instance which is used to perform all zfs and zpool
@@ -0,0 +1,365 @@
+package zfs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// propertyTag is the struct tag key used by UnmarshalProperties,
+// MarshalProperties, and PropertyNames.
+const propertyTag = "zfs"
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	propertyType = reflect.TypeOf(Property{})
+)
+
+// propertyField describes a struct field tagged with a property name.
+type propertyField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	percent   bool
+	readonly  bool
+	omitempty bool
+}
+
+// propertyFields returns the tagged fields of struct type t, including those
+// of embedded structs.
+//
+// Tags are of the form `zfs:"name[,option...]"`, where options are:
+//
+//  - percent: parse the value with Properties.Percent instead of Bytes.
+//  - readonly: skip the field in MarshalProperties.
+//  - omitempty: skip the field in MarshalProperties if it has a zero value.
+//
+// Fields without a tag, or with a tag of "-", are ignored.
+func propertyFields(t reflect.Type) ([]propertyField, error) {
+	fields := []propertyField{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(propertyTag)
+
+		if !ok && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			embedded, err := propertyFields(sf.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range embedded {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+
+			continue
+		}
+		if !ok || tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		f := propertyField{name: parts[0], index: []int{i}, typ: sf.Type}
+		if f.name == "" || f.name == allProperty {
+			return nil, fmt.Errorf(
+				"%w: field %s: invalid property name '%s'",
+				ErrInvalidStruct, sf.Name, f.name,
+			)
+		}
+		if sf.PkgPath != "" {
+			return nil, fmt.Errorf(
+				"%w: field %s: tagged field is not exported",
+				ErrInvalidStruct, sf.Name,
+			)
+		}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "percent":
+				f.percent = true
+			case "readonly":
+				f.readonly = true
+			case "omitempty":
+				f.omitempty = true
+			default:
+				return nil, fmt.Errorf(
+					"%w: field %s: unknown tag option '%s'",
+					ErrInvalidStruct, sf.Name, opt,
+				)
+			}
+		}
+		if !supportedPropertyType(sf.Type) {
+			return nil, fmt.Errorf(
+				"%w: field %s: unsupported type %s",
+				ErrInvalidStruct, sf.Name, sf.Type,
+			)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+func supportedPropertyType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == propertyType {
+		return true
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// structValue returns the struct value v points to, or v itself if it is a
+// struct and ptr is false.
+func structValue(v interface{}, ptr bool) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	} else if ptr {
+		return reflect.Value{}, fmt.Errorf(
+			"%w: expected non-nil pointer to struct, got %T",
+			ErrInvalidStruct, v,
+		)
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf(
+			"%w: expected struct, got %T", ErrInvalidStruct, v,
+		)
+	}
+
+	return rv, nil
+}
+
+// PropertyNames returns the property names of the tagged fields of the struct
+// v, or which v points to, in field order. It is useful to only fetch the
+// properties needed by a struct, for example with ListDatasets or GetDataset.
+func PropertyNames(v interface{}) ([]string, error) {
+	rv, err := structValue(v, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := propertyFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+
+	return names, nil
+}
+
+// Unmarshal populates the tagged fields of the struct v points to with the
+// values of their properties, for example:
+//
+//  var s struct {
+//      Quota       uint64    `zfs:"quota"`
+//      Compression string    `zfs:"compression"`
+//      Atime       bool      `zfs:"atime"`
+//      Creation    time.Time `zfs:"creation"`
+//      Ratio       float64   `zfs:"compressratio"`
+//      Owner       *string   `zfs:"com.example:owner"`
+//  }
+//  err := dataset.Unmarshal(&s)
+//
+// Values are parsed based on the field type: strings with String, bools with
+// Bool, integers with Bytes (or Percent with the "percent" tag option), floats
+// with Ratio, and time.Time with Time. Property fields receive the full
+// property, including its source. Pointer fields are allocated as needed.
+//
+// Fields of properties which are absent or have no value ("-") are left
+// unchanged. A value which cannot be parsed returns an error wrapping
+// ErrInvalidProperty.
+func (p Properties) Unmarshal(v interface{}) error {
+	rv, err := structValue(v, true)
+	if err != nil {
+		return err
+	}
+
+	fields, err := propertyFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		prop, ok := p[f.name]
+		if !ok {
+			continue
+		}
+
+		typ := f.typ
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ != propertyType && (prop.Value == "-" ||
+			(prop.Value == "" && typ.Kind() != reflect.String)) {
+			continue
+		}
+
+		val, err := p.parseField(f, typ)
+		if err != nil {
+			return err
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		if f.typ.Kind() == reflect.Ptr {
+			ptr := reflect.New(typ)
+			ptr.Elem().Set(val)
+			val = ptr
+		}
+		fv.Set(val)
+	}
+
+	return nil
+}
+
+// parseField parses the value of the property of f into a value of type typ.
+func (p Properties) parseField(
+	f propertyField,
+	typ reflect.Type,
+) (reflect.Value, error) {
+	invalid := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf(
+			"%w: %s: cannot parse '%s' as %s",
+			ErrInvalidProperty, f.name, p[f.name].Value, typ,
+		)
+	}
+
+	switch typ {
+	case propertyType:
+		return reflect.ValueOf(p[f.name]), nil
+	case timeType:
+		t, ok := p.Time(f.name)
+		if !ok {
+			return invalid()
+		}
+
+		return reflect.ValueOf(t), nil
+	}
+
+	val := reflect.New(typ).Elem()
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.String:
+		s, _ := p.String(f.name)
+		val.SetString(s)
+	case reflect.Bool:
+		b, _ := p.Bool(f.name)
+		val.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		r, ok := p.Ratio(f.name)
+		if !ok || val.OverflowFloat(r) {
+			return invalid()
+		}
+		val.SetFloat(r)
+	default:
+		n, ok := p.Bytes(f.name)
+		if f.percent {
+			n, ok = p.Percent(f.name)
+		}
+		if !ok {
+			return invalid()
+		}
+
+		switch typ.Kind() { //nolint:exhaustive
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Int64:
+			if n > uint64(1<<63-1) || val.OverflowInt(int64(n)) {
+				return invalid()
+			}
+			val.SetInt(int64(n))
+		default:
+			if val.OverflowUint(n) {
+				return invalid()
+			}
+			val.SetUint(n)
+		}
+	}
+
+	return val, nil
+}
+
+// MarshalProperties returns the values of the tagged fields of the struct v,
+// or which v points to, as a property map suitable for SetDatasetProperties,
+// SetPoolProperties, and create options.
+//
+// Fields with the "readonly" tag option are skipped, as are nil pointer
+// fields, and zero value fields with the "omitempty" tag option. Bools are
+// formatted as "on" or "off", integers as plain numbers, floats as plain
+// decimals, time.Time as a unix timestamp, and Property fields as their value.
+func MarshalProperties(v interface{}) (map[string]string, error) {
+	rv, err := structValue(v, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := propertyFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	r := map[string]string{}
+	for _, f := range fields {
+		if f.readonly {
+			continue
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if f.omitempty && fv.IsZero() {
+			continue
+		}
+
+		r[f.name] = formatField(fv)
+	}
+
+	return r, nil
+}
+
+func formatField(v reflect.Value) string {
+	switch v.Type() {
+	case propertyType:
+		return v.Interface().(Property).Value
+	case timeType:
+		return strconv.FormatInt(v.Interface().(time.Time).Unix(), 10)
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		if v.Bool() {
+			return "on"
+		}
+
+		return "off"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,248 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBaseProps struct {
+	Name   string `zfs:"name"`
+	Ignore string
+}
+
+type testDatasetProps struct {
+	testBaseProps
+
+	Quota       uint64    `zfs:"quota,omitempty"`
+	RefQuota    int64     `zfs:"refquota"`
+	Compression string    `zfs:"compression"`
+	Atime       bool      `zfs:"atime"`
+	Creation    time.Time `zfs:"creation,readonly"`
+	Ratio       float64   `zfs:"compressratio,readonly"`
+	Capacity    uint8     `zfs:"capacity,percent,readonly"`
+	Owner       *string   `zfs:"com.example:owner"`
+	Sync        Property  `zfs:"sync"`
+	Skipped     string    `zfs:"-"`
+}
+
+func testDatasetProperties() Properties {
+	props := Properties{}
+	for _, r := range [][]string{
+		{"name", "tank/data", "-"},
+		{"quota", "10737418240", "local"},
+		{"refquota", "1G", "local"},
+		{"compression", "lz4", "inherited from tank"},
+		{"atime", "off", "default"},
+		{"creation", "1634305200", "-"},
+		{"compressratio", "1.50x", "-"},
+		{"capacity", "42%", "-"},
+		{"com.example:owner", "web", "local"},
+		{"sync", "standard", "default"},
+	} {
+		props[r[0]] = Property{
+			Name: "tank/data", Property: r[0], Value: r[1], Source: r[2],
+		}
+	}
+
+	return props
+}
+
+func TestPropertyNames(t *testing.T) {
+	got, err := PropertyNames(&testDatasetProps{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"name", "quota", "refquota", "compression", "atime", "creation",
+		"compressratio", "capacity", "com.example:owner", "sync",
+	}, got)
+
+	got, err = PropertyNames(testDatasetProps{})
+	require.NoError(t, err)
+	assert.Len(t, got, 10)
+}
+
+func TestProperties_Unmarshal(t *testing.T) {
+	var got testDatasetProps
+	err := testDatasetProperties().Unmarshal(&got)
+	require.NoError(t, err)
+
+	owner := "web"
+	assert.Equal(t, testDatasetProps{
+		testBaseProps: testBaseProps{Name: "tank/data"},
+		Quota:         10737418240,
+		RefQuota:      1073741824,
+		Compression:   "lz4",
+		Atime:         false,
+		Creation:      time.Unix(1634305200, 0).UTC(),
+		Ratio:         1.5,
+		Capacity:      42,
+		Owner:         &owner,
+		Sync: Property{
+			Name:     "tank/data",
+			Property: "sync",
+			Value:    "standard",
+			Source:   "default",
+		},
+	}, got)
+}
+
+func TestProperties_Unmarshal_absent(t *testing.T) {
+	got := testDatasetProps{Compression: "keep", Quota: 42}
+	err := Properties{
+		"quota": {Property: "quota", Value: "-"},
+		"atime": {Property: "atime", Value: ""},
+	}.Unmarshal(&got)
+	require.NoError(t, err)
+
+	assert.Equal(t, testDatasetProps{Compression: "keep", Quota: 42}, got)
+}
+
+func TestProperties_Unmarshal_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		props   Properties
+		v       interface{}
+		wantErr string
+		target  error
+	}{
+		{
+			name: "nil",
+			v:    nil,
+			wantErr: "invalid struct: expected non-nil pointer to struct, " +
+				"got <nil>",
+			target: ErrInvalidStruct,
+		},
+		{
+			name: "non-pointer",
+			v:    testDatasetProps{},
+			wantErr: "invalid struct: expected non-nil pointer to struct, " +
+				"got zfs.testDatasetProps",
+			target: ErrInvalidStruct,
+		},
+		{
+			name:    "non-struct",
+			v:       new(string),
+			wantErr: "invalid struct: expected struct, got *string",
+			target:  ErrInvalidStruct,
+		},
+		{
+			name: "unsupported type",
+			v: &struct {
+				Values []string `zfs:"values"`
+			}{},
+			wantErr: "invalid struct: field Values: unsupported type []string",
+			target:  ErrInvalidStruct,
+		},
+		{
+			name: "unknown option",
+			v: &struct {
+				Quota uint64 `zfs:"quota,bytes"`
+			}{},
+			wantErr: "invalid struct: field Quota: " +
+				"unknown tag option 'bytes'",
+			target: ErrInvalidStruct,
+		},
+		{
+			name: "empty name",
+			v: &struct {
+				Quota uint64 `zfs:",omitempty"`
+			}{},
+			wantErr: "invalid struct: field Quota: invalid property name ''",
+			target:  ErrInvalidStruct,
+		},
+		{
+			name: "unexported field",
+			v: &struct {
+				quota uint64 `zfs:"quota"`
+			}{},
+			wantErr: "invalid struct: field quota: " +
+				"tagged field is not exported",
+			target: ErrInvalidStruct,
+		},
+		{
+			name:  "unparsable value",
+			props: Properties{"quota": {Property: "quota", Value: "lots"}},
+			v: &struct {
+				Quota uint64 `zfs:"quota"`
+			}{},
+			wantErr: "invalid property: quota: cannot parse 'lots' as uint64",
+			target:  ErrInvalidProperty,
+		},
+		{
+			name:  "overflow",
+			props: Properties{"quota": {Property: "quota", Value: "1G"}},
+			v: &struct {
+				Quota uint16 `zfs:"quota"`
+			}{},
+			wantErr: "invalid property: quota: cannot parse '1G' as uint16",
+			target:  ErrInvalidProperty,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.props.Unmarshal(tt.v)
+
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, tt.target)
+		})
+	}
+}
+
+func TestMarshalProperties(t *testing.T) {
+	owner := "web"
+	v := &testDatasetProps{
+		testBaseProps: testBaseProps{Name: "tank/data"},
+		RefQuota:      1073741824,
+		Compression:   "zstd",
+		Atime:         true,
+		Creation:      time.Unix(1634305200, 0),
+		Owner:         &owner,
+		Sync:          Property{Value: "disabled"},
+	}
+
+	got, err := MarshalProperties(v)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"name":              "tank/data",
+		"refquota":          "1073741824",
+		"compression":       "zstd",
+		"atime":             "on",
+		"com.example:owner": "web",
+		"sync":              "disabled",
+	}, got)
+
+	v.Owner = nil
+	v.Quota = 10
+	got, err = MarshalProperties(*v)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10", got["quota"])
+	assert.NotContains(t, got, "com.example:owner")
+
+	_, err = MarshalProperties("nope")
+	assert.ErrorIs(t, err, ErrInvalidStruct)
+}
+
+func TestDataset_Unmarshal_roundtrip(t *testing.T) {
+	ds := NewDataset("tank/data", testDatasetProperties())
+
+	var v testDatasetProps
+	require.NoError(t, ds.Unmarshal(&v))
+
+	got, err := MarshalProperties(&v)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"name":              "tank/data",
+		"quota":             "10737418240",
+		"refquota":          "1073741824",
+		"compression":       "lz4",
+		"atime":             "off",
+		"com.example:owner": "web",
+		"sync":              "standard",
+	}, got)
+}
@@ -0,0 +1,202 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+var errInvalidMountOptions = multierr.Append(ErrZFS, ErrInvalidMountOptions)
+
+// Mount represents a mounted ZFS filesystem, as reported by zfs mount.
+type Mount struct {
+	// Dataset is the name of the mounted filesystem.
+	Dataset string
+
+	// Mountpoint is the path the filesystem is mounted at.
+	Mountpoint string
+}
+
+// MountDatasetOptions are options for mounting one or more filesystems.
+type MountDatasetOptions struct {
+	// Name of the filesystem to mount. Required unless All is set.
+	Name string
+
+	// All indicates whether to mount all available filesystems by passing the
+	// -a flag. Cannot be combined with Name.
+	All bool
+
+	// Options is a list of temporary mount options (-o) to use for the
+	// duration of the mount.
+	Options []string
+
+	// Overlay indicates whether to allow mounting over a non-empty directory
+	// by passing the -O flag.
+	Overlay bool
+
+	// LoadKey indicates whether to load the encryption key of the filesystem
+	// before mounting it, by passing the -l flag.
+	LoadKey bool
+}
+
+// MountDataset mounts filesystems as per the given options.
+func (m *Manager) MountDataset(
+	ctx context.Context,
+	options *MountDatasetOptions,
+) error {
+	if options == nil {
+		return errInvalidMountOptions
+	}
+	err := validateMountTarget(options.Name, options.All, validateDatasetName)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"mount"}
+	if options.Overlay {
+		args = append(args, "-O")
+	}
+	if options.LoadKey {
+		args = append(args, "-l")
+	}
+	if len(options.Options) > 0 {
+		args = append(args, "-o", strings.Join(options.Options, ","))
+	}
+	args = appendNameOrAll(args, options.Name, options.All)
+
+	_, err = m.zfs(ctx, args...)
+
+	return err
+}
+
+// UnmountDatasetOptions are options for unmounting one or more filesystems.
+type UnmountDatasetOptions struct {
+	// Name of the filesystem, or path of its mountpoint, to unmount. Required
+	// unless All is set.
+	Name string
+
+	// All indicates whether to unmount all mounted filesystems by passing the
+	// -a flag. Cannot be combined with Name.
+	All bool
+
+	// Force indicates whether to forcefully unmount filesystems, even if they
+	// are currently in use, by passing the -f flag.
+	Force bool
+
+	// UnloadKey indicates whether to unload the encryption key of the
+	// filesystem after unmounting it, by passing the -u flag.
+	UnloadKey bool
+}
+
+// UnmountDataset unmounts filesystems as per the given options.
+func (m *Manager) UnmountDataset(
+	ctx context.Context,
+	options *UnmountDatasetOptions,
+) error {
+	if options == nil {
+		return errInvalidMountOptions
+	}
+	err := validateMountTarget(
+		options.Name, options.All, validateDatasetOrMountpoint,
+	)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"unmount"}
+	if options.Force {
+		args = append(args, "-f")
+	}
+	if options.UnloadKey {
+		args = append(args, "-u")
+	}
+	args = appendNameOrAll(args, options.Name, options.All)
+
+	_, err = m.zfs(ctx, args...)
+
+	return err
+}
+
+// ShareDataset shares the named filesystem over NFS and/or SMB, as per its
+// sharenfs and sharesmb properties.
+func (m *Manager) ShareDataset(ctx context.Context, name string) error {
+	if err := validateDatasetName(name); err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
+
+	_, err := m.zfs(ctx, "share", name)
+
+	return err
+}
+
+// UnshareDataset stops sharing the named filesystem over NFS and SMB. The name
+// can either be the name of a filesystem, or the path of its mountpoint.
+func (m *Manager) UnshareDataset(ctx context.Context, name string) error {
+	if err := validateDatasetOrMountpoint(name); err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
+
+	_, err := m.zfs(ctx, "unshare", name)
+
+	return err
+}
+
+// ListMounts returns all currently mounted ZFS filesystems, in the order
+// reported by zfs mount.
+func (m *Manager) ListMounts(ctx context.Context) ([]*Mount, error) {
+	records, err := m.zfs(ctx, "mount")
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := []*Mount{}
+	for _, record := range records {
+		// zfs mount does not support -H, so columns are space padded instead
+		// of tab-delimited. Dataset names cannot contain spaces, but
+		// mountpoints can, so only split on the first run of spaces.
+		line := strings.TrimSpace(strings.Join(record, "\t"))
+		i := strings.IndexAny(line, " \t")
+		if i == -1 {
+			continue
+		}
+
+		mounts = append(mounts, &Mount{
+			Dataset:    line[:i],
+			Mountpoint: strings.TrimSpace(line[i:]),
+		})
+	}
+
+	return mounts, nil
+}
+
+// validateDatasetOrMountpoint returns an error if name is neither a valid
+// dataset name nor an absolute path, as accepted by zfs unmount and unshare.
+func validateDatasetOrMountpoint(name string) error {
+	if strings.HasPrefix(name, "/") {
+		return nil
+	}
+
+	return validateDatasetName(name)
+}
+
+func validateMountTarget(
+	name string,
+	all bool,
+	validate func(string) error,
+) error {
+	if all && name != "" {
+		return fmt.Errorf(
+			"%w: all cannot be combined with name", errInvalidMountOptions,
+		)
+	}
+	if all {
+		return nil
+	}
+	if err := validate(name); err != nil {
+		return multierr.Combine(ErrZFS, ErrInvalidMountOptions, err)
+	}
+
+	return nil
+}
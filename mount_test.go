@@ -0,0 +1,595 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_MountDataset(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type args struct {
+		options *MountDatasetOptions
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantArgs       []string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			args:    args{},
+			wantErr: "zfs; invalid mount options",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+			},
+		},
+		{
+			name: "empty dataset name",
+			args: args{
+				options: &MountDatasetOptions{},
+			},
+			wantErr: "zfs; invalid mount options; invalid name: empty name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "mountpoint path",
+			args: args{
+				options: &MountDatasetOptions{Name: "/mnt/data"},
+			},
+			wantErr: "zfs; invalid mount options; invalid name '/mnt/data': " +
+				"leading slash in name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "all with name",
+			args: args{
+				options: &MountDatasetOptions{
+					Name: "tank/data",
+					All:  true,
+				},
+			},
+			wantErr: "zfs; invalid mount options: " +
+				"all cannot be combined with name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+			},
+		},
+		{
+			name: "name",
+			args: args{
+				options: &MountDatasetOptions{Name: "tank/data"},
+			},
+			wantArgs: []string{"mount", "tank/data"},
+		},
+		{
+			name: "all",
+			args: args{
+				options: &MountDatasetOptions{All: true},
+			},
+			wantArgs: []string{"mount", "-a"},
+		},
+		{
+			name: "all options",
+			args: args{
+				options: &MountDatasetOptions{
+					Name:    "tank/data",
+					Options: []string{"ro", "noatime"},
+					Overlay: true,
+					LoadKey: true,
+				},
+			},
+			wantArgs: []string{
+				"mount", "-O", "-l", "-o", "ro,noatime", "tank/data",
+			},
+		},
+		{
+			name: "dataset does not exist",
+			args: args{
+				options: &MountDatasetOptions{Name: "tank/nope"},
+			},
+			wantArgs:   []string{"mount", "tank/nope"},
+			stderr:     "cannot open 'tank/nope': dataset does not exist\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/nope': dataset does not exist",
+			wantErrTargets: []error{Err, ErrZFS, ErrNotFound},
+		},
+		{
+			name: "command error",
+			args: args{
+				options: &MountDatasetOptions{Name: "tank/data"},
+			},
+			wantArgs: []string{"mount", "tank/data"},
+			stderr: "cannot mount 'tank/data': " +
+				"filesystem already mounted\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; exit status 1: " +
+				"cannot mount 'tank/data': filesystem already mounted",
+			wantErrTargets: []error{Err, ErrZFS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.MountDataset(ctx, tt.args.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_UnmountDataset(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type args struct {
+		options *UnmountDatasetOptions
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantArgs       []string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			args:    args{},
+			wantErr: "zfs; invalid mount options",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+			},
+		},
+		{
+			name: "empty dataset name",
+			args: args{
+				options: &UnmountDatasetOptions{},
+			},
+			wantErr: "zfs; invalid mount options; invalid name: empty name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "all with name",
+			args: args{
+				options: &UnmountDatasetOptions{
+					Name: "tank/data",
+					All:  true,
+				},
+			},
+			wantErr: "zfs; invalid mount options: " +
+				"all cannot be combined with name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidMountOptions,
+			},
+		},
+		{
+			name: "name",
+			args: args{
+				options: &UnmountDatasetOptions{Name: "tank/data"},
+			},
+			wantArgs: []string{"unmount", "tank/data"},
+		},
+		{
+			name: "mountpoint path",
+			args: args{
+				options: &UnmountDatasetOptions{Name: "/mnt/data"},
+			},
+			wantArgs: []string{"unmount", "/mnt/data"},
+		},
+		{
+			name: "all",
+			args: args{
+				options: &UnmountDatasetOptions{All: true},
+			},
+			wantArgs: []string{"unmount", "-a"},
+		},
+		{
+			name: "all options",
+			args: args{
+				options: &UnmountDatasetOptions{
+					Name:      "tank/data",
+					Force:     true,
+					UnloadKey: true,
+				},
+			},
+			wantArgs: []string{"unmount", "-f", "-u", "tank/data"},
+		},
+		{
+			name: "command error",
+			args: args{
+				options: &UnmountDatasetOptions{Name: "tank/data"},
+			},
+			wantArgs: []string{"unmount", "tank/data"},
+			stderr: "cannot unmount '/mnt/data': pool or dataset is busy\n" +
+				"umount: /mnt/data: target is busy.\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; busy; exit status 1: " +
+				"cannot unmount '/mnt/data': pool or dataset is busy: " +
+				"umount: /mnt/data: target is busy.",
+			wantErrTargets: []error{Err, ErrZFS, ErrBusy},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.UnmountDataset(ctx, tt.args.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_ShareDataset(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name           string
+		dataset        string
+		wantArgs       []string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:           "empty dataset name",
+			dataset:        "",
+			wantErr:        "zfs; invalid name: empty name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
+		{
+			name:    "mountpoint path",
+			dataset: "/mnt/data",
+			wantErr: "zfs; invalid name '/mnt/data': " +
+				"leading slash in name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
+		{
+			name:     "name",
+			dataset:  "tank/data",
+			wantArgs: []string{"share", "tank/data"},
+		},
+		{
+			name:     "command error",
+			dataset:  "tank/data",
+			wantArgs: []string{"share", "tank/data"},
+			stderr: "cannot share 'tank/data': legacy share\n" +
+				"use exports(5) or smb.conf(5)\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; exit status 1: cannot share 'tank/data': " +
+				"legacy share: use exports(5) or smb.conf(5)",
+			wantErrTargets: []error{Err, ErrZFS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.ShareDataset(ctx, tt.dataset)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_UnshareDataset(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name           string
+		dataset        string
+		wantArgs       []string
+		stderr         string
+		commandErr     error
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:           "empty dataset name",
+			dataset:        "",
+			wantErr:        "zfs; invalid name: empty name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
+		{
+			name:     "name",
+			dataset:  "tank/data",
+			wantArgs: []string{"unshare", "tank/data"},
+		},
+		{
+			name:     "mountpoint path",
+			dataset:  "/mnt/data",
+			wantArgs: []string{"unshare", "/mnt/data"},
+		},
+		{
+			name:     "command error",
+			dataset:  "tank/data",
+			wantArgs: []string{"unshare", "tank/data"},
+			stderr: "cannot unshare 'tank/data': " +
+				"not currently shared\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; exit status 1: " +
+				"cannot unshare 'tank/data': not currently shared",
+			wantErrTargets: []error{Err, ErrZFS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					_ io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.UnshareDataset(ctx, tt.dataset)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_ListMounts(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name           string
+		stdout         string
+		stderr         string
+		commandErr     error
+		want           []*Mount
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:   "none",
+			stdout: "",
+			want:   []*Mount{},
+		},
+		{
+			name: "mounts",
+			stdout: "tank                            /tank\n" +
+				"tank/data                       /mnt/data\n" +
+				"tank/home/with-a-very-long-name-indeed  /home/long\n" +
+				"tank/spaces                     /mnt/with some spaces\n",
+			want: []*Mount{
+				{Dataset: "tank", Mountpoint: "/tank"},
+				{Dataset: "tank/data", Mountpoint: "/mnt/data"},
+				{
+					Dataset:    "tank/home/with-a-very-long-name-indeed",
+					Mountpoint: "/home/long",
+				},
+				{
+					Dataset:    "tank/spaces",
+					Mountpoint: "/mnt/with some spaces",
+				},
+			},
+		},
+		{
+			name:       "command error",
+			stderr:     "permission denied\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; permission denied; exit status 1: " +
+				"permission denied",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrPermissionDenied,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				gomock.Nil(),
+				gomock.AssignableToTypeOf(ioWriter),
+				gomock.AssignableToTypeOf(ioWriter),
+				"zfs",
+				"mount",
+			).DoAndReturn(func(
+				_ context.Context,
+				_ io.Reader,
+				stdout io.Writer,
+				stderr io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				_, _ = stdout.Write([]byte(tt.stdout))
+				_, _ = stderr.Write([]byte(tt.stderr))
+
+				return tt.commandErr
+			})
+
+			m := &Manager{Runner: r}
+
+			got, err := m.ListMounts(ctx)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package zfs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDatasetNameLen is the maximum length of a dataset name, as ZFS limits
+// names to 256 bytes including the terminating NUL byte.
+const maxDatasetNameLen = 255
+
+// reservedPoolPrefixes are prefixes which names of new pools cannot start
+// with, as they are used for vdev types.
+var reservedPoolPrefixes = []string{"mirror", "raidz", "draid", "spare"}
+
+// DatasetName is a parsed and validated name of a filesystem, volume,
+// snapshot, or bookmark, as returned by ParseDatasetName.
+//
+// The zero value is not a valid name, and is returned by methods like Parent
+// when there is no such name.
+type DatasetName struct {
+	pool     string
+	path     []string
+	snapshot string
+	bookmark string
+}
+
+// ParseDatasetName parses and validates name as per the naming rules of ZFS,
+// returning an error wrapping ErrInvalidName which describes the problem if
+// name is not valid.
+//
+// Names consist of a pool name, followed by any number of "/" separated path
+// components, optionally followed by "@" and a snapshot name, or "#" and a
+// bookmark name. Each part may only contain alphanumeric characters, "-",
+// "_", ".", ":", and " ", while "." and ".." are not allowed as parts. Path
+// components may also start with "%", as used by hidden datasets like
+// "tank/data/%recv". Pool names must start with a letter. The whole name
+// cannot be longer than 255 characters.
+//
+// Rules which only apply when creating a pool, or importing it under a new
+// name, like reserved pool names, are not checked, so names on existing pools
+// are always accepted.
+func ParseDatasetName(name string) (DatasetName, error) {
+	switch {
+	case name == "":
+		return DatasetName{}, fmt.Errorf("%w: empty name", ErrInvalidName)
+	case len(name) > maxDatasetNameLen:
+		return DatasetName{}, invalidNameError(name, "name is too long")
+	case name[0] == '/':
+		return DatasetName{}, invalidNameError(name, "leading slash in name")
+	case name[len(name)-1] == '/':
+		return DatasetName{}, invalidNameError(name, "trailing slash in name")
+	}
+
+	dataset, delim, suffix := name, byte(0), ""
+	if i := strings.IndexAny(name, "@#"); i >= 0 {
+		dataset, delim, suffix = name[:i], name[i], name[i+1:]
+		if strings.ContainsAny(suffix, "@#") {
+			return DatasetName{}, invalidNameError(name,
+				"multiple '@' and/or '#' delimiters in name",
+			)
+		}
+	}
+
+	parts := strings.Split(dataset, "/")
+	for i, part := range parts {
+		if reason := componentProblem(part, i > 0); reason != "" {
+			return DatasetName{}, invalidNameError(name, reason)
+		}
+	}
+	if reason := poolProblem(parts[0]); reason != "" {
+		return DatasetName{}, invalidNameError(name, reason)
+	}
+
+	n := DatasetName{pool: parts[0], path: parts[1:]}
+	if delim != 0 {
+		if reason := componentProblem(suffix, false); reason != "" {
+			return DatasetName{}, invalidNameError(name, reason)
+		}
+		if delim == '@' {
+			n.snapshot = suffix
+		} else {
+			n.bookmark = suffix
+		}
+	}
+
+	return n, nil
+}
+
+func invalidNameError(name string, reason string) error {
+	return fmt.Errorf("%w '%s': %s", ErrInvalidName, name, reason)
+}
+
+// componentProblem returns a description of why s is not a valid component
+// of a name, or an empty string if it is valid. A leading "%" is allowed if
+// hidden is true.
+func componentProblem(s string, hidden bool) string {
+	switch s {
+	case "":
+		return "empty component or misplaced '@' or '#' delimiter in name"
+	case ".":
+		return "self reference, '.' is found in name"
+	case "..":
+		return "parent reference, '..' is found in name"
+	}
+
+	for i, c := range s {
+		if !validNameChar(c) && !(c == '%' && i == 0 && hidden) {
+			return fmt.Sprintf("invalid character '%c' in name", c)
+		}
+	}
+
+	return ""
+}
+
+func validNameChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-' || c == '_' || c == '.' || c == ':' || c == ' '
+}
+
+// poolProblem returns a description of why pool is not a valid pool name, or
+// an empty string if it is valid. Characters are validated by
+// componentProblem.
+func poolProblem(pool string) string {
+	c := pool[0]
+	if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+		return "pool doesn't begin with a letter"
+	}
+
+	return ""
+}
+
+// newPoolProblem returns a description of why pool cannot be the name of a
+// new pool, or an empty string if it can. It only checks rules which ZFS
+// applies when creating or renaming pools, in addition to those checked by
+// poolProblem.
+func newPoolProblem(pool string) string {
+	if pool == "log" {
+		return "name is reserved"
+	}
+	for _, prefix := range reservedPoolPrefixes {
+		if strings.HasPrefix(pool, prefix) {
+			return "name is reserved"
+		}
+	}
+	if pool[0] == 'c' && len(pool) > 1 && pool[1] >= '0' && pool[1] <= '9' {
+		return "reserved disk name"
+	}
+
+	return ""
+}
+
+// String returns the full name, for example "tank/data@snap".
+func (n DatasetName) String() string {
+	if n.pool == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(n.pool)
+	for _, part := range n.path {
+		b.WriteByte('/')
+		b.WriteString(part)
+	}
+	switch {
+	case n.snapshot != "":
+		b.WriteByte('@')
+		b.WriteString(n.snapshot)
+	case n.bookmark != "":
+		b.WriteByte('#')
+		b.WriteString(n.bookmark)
+	}
+
+	return b.String()
+}
+
+// Pool returns the name of the pool, for example "tank" for
+// "tank/data@snap".
+func (n DatasetName) Pool() string {
+	return n.pool
+}
+
+// Path returns the components of the name below the pool, excluding any
+// snapshot or bookmark name, for example ["data", "www"] for
+// "tank/data/www@snap".
+func (n DatasetName) Path() []string {
+	return append([]string{}, n.path...)
+}
+
+// IsSnapshot reports whether the name is the name of a snapshot.
+func (n DatasetName) IsSnapshot() bool {
+	return n.snapshot != ""
+}
+
+// IsBookmark reports whether the name is the name of a bookmark.
+func (n DatasetName) IsBookmark() bool {
+	return n.bookmark != ""
+}
+
+// SnapshotName returns the part after "@" of snapshot names, for example
+// "snap" for "tank/data@snap", and an empty string for other names.
+func (n DatasetName) SnapshotName() string {
+	return n.snapshot
+}
+
+// BookmarkName returns the part after "#" of bookmark names, for example
+// "mark" for "tank/data#mark", and an empty string for other names.
+func (n DatasetName) BookmarkName() string {
+	return n.bookmark
+}
+
+// Base returns the last component of the name, including any snapshot or
+// bookmark name, for example "www" for "tank/data/www", and "www@snap" for
+// "tank/data/www@snap".
+func (n DatasetName) Base() string {
+	base := n.pool
+	if len(n.path) > 0 {
+		base = n.path[len(n.path)-1]
+	}
+
+	switch {
+	case n.snapshot != "":
+		return base + "@" + n.snapshot
+	case n.bookmark != "":
+		return base + "#" + n.bookmark
+	}
+
+	return base
+}
+
+// Parent returns the name of the parent dataset, for example "tank/data" for
+// both "tank/data/www" and "tank/data@snap".
+//
+// The second return value is false if the name has no parent, as it is the
+// name of a pool's root dataset.
+func (n DatasetName) Parent() (DatasetName, bool) {
+	switch {
+	case n.snapshot != "" || n.bookmark != "":
+		return DatasetName{pool: n.pool, path: n.Path()}, true
+	case len(n.path) == 0:
+		return DatasetName{}, false
+	}
+
+	return DatasetName{
+		pool: n.pool,
+		path: append([]string{}, n.path[:len(n.path)-1]...),
+	}, true
+}
+
+// Snapshot returns the name of the named snapshot of the dataset, for example
+// "tank/data@snap" for "tank/data" and "snap". An error wrapping
+// ErrInvalidName is returned if name is not a valid snapshot name, or if the
+// name already is a snapshot or bookmark name.
+func (n DatasetName) Snapshot(name string) (DatasetName, error) {
+	if n.snapshot != "" || n.bookmark != "" {
+		return DatasetName{}, invalidNameError(n.String(),
+			"snapshots and bookmarks cannot have snapshots",
+		)
+	}
+
+	return ParseDatasetName(n.String() + "@" + name)
+}
+
+// validateDatasetName returns an error wrapping ErrInvalidName which
+// describes the problem if name is not a valid dataset, snapshot, or bookmark
+// name.
+func validateDatasetName(name string) error {
+	_, err := ParseDatasetName(name)
+
+	return err
+}
+
+func validDatasetName(name string) bool {
+	return validateDatasetName(name) == nil
+}
+
+// validatePoolName returns an error wrapping ErrInvalidName which describes
+// the problem if name is not a valid name of an existing pool, as per the
+// rules of ParseDatasetName for a name without '/', '@', or '#'.
+func validatePoolName(name string) error {
+	if i := strings.IndexAny(name, "/@#"); i >= 0 {
+		return invalidNameError(name,
+			fmt.Sprintf("invalid character '%c' in pool name", name[i]),
+		)
+	}
+
+	return validateDatasetName(name)
+}
+
+// validateNewPoolName returns an error wrapping ErrInvalidName which
+// describes the problem if name cannot be used for a new pool, as when
+// creating a pool, or importing it under a new name. In addition to the rules
+// of validatePoolName, such names cannot be reserved, like "log", start with
+// a vdev type, like "mirror", or look like a disk name, like "c0t0d0".
+func validateNewPoolName(name string) error {
+	if err := validatePoolName(name); err != nil {
+		return err
+	}
+	if reason := newPoolProblem(name); reason != "" {
+		return invalidNameError(name, reason)
+	}
+
+	return nil
+}
+
+// validateDestroyName returns an error wrapping ErrInvalidName which
+// describes the problem if name is not valid for zfs destroy. In addition to
+// dataset, snapshot, and bookmark names, zfs destroy accepts multiple
+// snapshots of a dataset as a comma separated list of snapshot names and
+// ranges, like "tank/data@a,c%e", where either end of a range may be omitted.
+// The length limit applies to the full name of each snapshot, not the list.
+func validateDestroyName(name string) error {
+	i := strings.IndexByte(name, '@')
+	if i < 0 || !strings.ContainsAny(name[i+1:], ",%") {
+		return validateDatasetName(name)
+	}
+	if err := validateDatasetName(name[:i]); err != nil {
+		return err
+	}
+	for _, item := range strings.Split(name[i+1:], ",") {
+		snapshots := strings.SplitN(item, "%", 2)
+		for _, snapshot := range snapshots {
+			if snapshot == "" && len(snapshots) == 2 {
+				continue
+			}
+			if reason := componentProblem(snapshot, false); reason != "" {
+				return invalidNameError(name, reason)
+			}
+			if i+1+len(snapshot) > maxDatasetNameLen {
+				return invalidNameError(name, "name is too long")
+			}
+		}
+	}
+
+	return nil
+}
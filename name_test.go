@@ -0,0 +1,463 @@
+package zfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDatasetName(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantPool     string
+		wantPath     []string
+		wantSnapshot string
+		wantBookmark string
+		wantErr      string
+	}{
+		{
+			name:     "pool",
+			input:    "tank",
+			wantPool: "tank",
+			wantPath: []string{},
+		},
+		{
+			name:     "filesystem",
+			input:    "tank/data/www",
+			wantPool: "tank",
+			wantPath: []string{"data", "www"},
+		},
+		{
+			name:         "snapshot",
+			input:        "tank/data@daily-2022.01.01",
+			wantPool:     "tank",
+			wantPath:     []string{"data"},
+			wantSnapshot: "daily-2022.01.01",
+		},
+		{
+			name:         "pool snapshot",
+			input:        "tank@snap",
+			wantPool:     "tank",
+			wantPath:     []string{},
+			wantSnapshot: "snap",
+		},
+		{
+			name:         "bookmark",
+			input:        "tank/data#mark",
+			wantPool:     "tank",
+			wantPath:     []string{"data"},
+			wantBookmark: "mark",
+		},
+		{
+			name:     "all valid characters",
+			input:    "Tank_1/a-b_c.d:e f",
+			wantPool: "Tank_1",
+			wantPath: []string{"a-b_c.d:e f"},
+		},
+		{
+			name:     "hidden dataset",
+			input:    "tank/data/%recv",
+			wantPool: "tank",
+			wantPath: []string{"data", "%recv"},
+		},
+		{
+			name:     "pool with reserved prefix",
+			input:    "mirrored/x",
+			wantPool: "mirrored",
+			wantPath: []string{"x"},
+		},
+		{
+			name:     "disk like pool",
+			input:    "c0t0d0/data",
+			wantPool: "c0t0d0",
+			wantPath: []string{"data"},
+		},
+		{
+			name:     "maximum length",
+			input:    "tank/" + strings.Repeat("a", 250),
+			wantPool: "tank",
+			wantPath: []string{strings.Repeat("a", 250)},
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: "invalid name: empty name",
+		},
+		{
+			name:  "too long",
+			input: "tank/" + strings.Repeat("a", 251),
+			wantErr: "invalid name 'tank/" + strings.Repeat("a", 251) +
+				"': name is too long",
+		},
+		{
+			name:    "leading slash",
+			input:   "/tank/data",
+			wantErr: "invalid name '/tank/data': leading slash in name",
+		},
+		{
+			name:    "trailing slash",
+			input:   "tank/data/",
+			wantErr: "invalid name 'tank/data/': trailing slash in name",
+		},
+		{
+			name:  "empty component",
+			input: "tank//data",
+			wantErr: "invalid name 'tank//data': " +
+				"empty component or misplaced '@' or '#' delimiter in name",
+		},
+		{
+			name:  "empty snapshot name",
+			input: "tank/data@",
+			wantErr: "invalid name 'tank/data@': " +
+				"empty component or misplaced '@' or '#' delimiter in name",
+		},
+		{
+			name:  "snapshot without dataset",
+			input: "@snap",
+			wantErr: "invalid name '@snap': " +
+				"empty component or misplaced '@' or '#' delimiter in name",
+		},
+		{
+			name:  "self reference",
+			input: "tank/./data",
+			wantErr: "invalid name 'tank/./data': " +
+				"self reference, '.' is found in name",
+		},
+		{
+			name:  "parent reference",
+			input: "tank/data/..",
+			wantErr: "invalid name 'tank/data/..': " +
+				"parent reference, '..' is found in name",
+		},
+		{
+			name:  "parent reference snapshot",
+			input: "tank/data@..",
+			wantErr: "invalid name 'tank/data@..': " +
+				"parent reference, '..' is found in name",
+		},
+		{
+			name:  "invalid character",
+			input: "tank/da*ta",
+			wantErr: "invalid name 'tank/da*ta': " +
+				"invalid character '*' in name",
+		},
+		{
+			name:  "invalid unicode character",
+			input: "tank/dätä",
+			wantErr: "invalid name 'tank/dätä': " +
+				"invalid character 'ä' in name",
+		},
+		{
+			name:  "percent within component",
+			input: "tank/data%recv",
+			wantErr: "invalid name 'tank/data%recv': " +
+				"invalid character '%' in name",
+		},
+		{
+			name:  "percent in pool",
+			input: "%tank/data",
+			wantErr: "invalid name '%tank/data': " +
+				"invalid character '%' in name",
+		},
+		{
+			name:  "percent in snapshot",
+			input: "tank/data@%snap",
+			wantErr: "invalid name 'tank/data@%snap': " +
+				"invalid character '%' in name",
+		},
+		{
+			name:  "slash in snapshot",
+			input: "tank/data@snap/x",
+			wantErr: "invalid name 'tank/data@snap/x': " +
+				"invalid character '/' in name",
+		},
+		{
+			name:  "multiple delimiters",
+			input: "tank/data@snap#mark",
+			wantErr: "invalid name 'tank/data@snap#mark': " +
+				"multiple '@' and/or '#' delimiters in name",
+		},
+		{
+			name:  "pool not starting with letter",
+			input: "1tank/data",
+			wantErr: "invalid name '1tank/data': " +
+				"pool doesn't begin with a letter",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDatasetName(tt.input)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, Err)
+				assert.ErrorIs(t, err, ErrInvalidName)
+				assert.Equal(t, DatasetName{}, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.input, got.String())
+			assert.Equal(t, tt.wantPool, got.Pool())
+			assert.Equal(t, tt.wantPath, got.Path())
+			assert.Equal(t, tt.wantSnapshot, got.SnapshotName())
+			assert.Equal(t, tt.wantBookmark, got.BookmarkName())
+			assert.Equal(t, tt.wantSnapshot != "", got.IsSnapshot())
+			assert.Equal(t, tt.wantBookmark != "", got.IsBookmark())
+		})
+	}
+}
+
+func TestDatasetName_Base(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "tank", want: "tank"},
+		{name: "tank/data/www", want: "www"},
+		{name: "tank/data@snap", want: "data@snap"},
+		{name: "tank@snap", want: "tank@snap"},
+		{name: "tank/data#mark", want: "data#mark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := ParseDatasetName(tt.name)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, n.Base())
+		})
+	}
+}
+
+func TestDatasetName_Parent(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "tank", want: "", wantOK: false},
+		{name: "tank/data", want: "tank", wantOK: true},
+		{name: "tank/data/www", want: "tank/data", wantOK: true},
+		{name: "tank/data@snap", want: "tank/data", wantOK: true},
+		{name: "tank@snap", want: "tank", wantOK: true},
+		{name: "tank/data#mark", want: "tank/data", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := ParseDatasetName(tt.name)
+			require.NoError(t, err)
+
+			got, ok := n.Parent()
+
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestDatasetName_Snapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataset  string
+		snapshot string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "filesystem",
+			dataset:  "tank/data",
+			snapshot: "daily",
+			want:     "tank/data@daily",
+		},
+		{
+			name:     "pool",
+			dataset:  "tank",
+			snapshot: "daily",
+			want:     "tank@daily",
+		},
+		{
+			name:     "invalid snapshot name",
+			dataset:  "tank/data",
+			snapshot: "da/ily",
+			wantErr: "invalid name 'tank/data@da/ily': " +
+				"invalid character '/' in name",
+		},
+		{
+			name:     "empty snapshot name",
+			dataset:  "tank/data",
+			snapshot: "",
+			wantErr: "invalid name 'tank/data@': " +
+				"empty component or misplaced '@' or '#' delimiter in name",
+		},
+		{
+			name:     "snapshot of snapshot",
+			dataset:  "tank/data@snap",
+			snapshot: "daily",
+			wantErr: "invalid name 'tank/data@snap': " +
+				"snapshots and bookmarks cannot have snapshots",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := ParseDatasetName(tt.dataset)
+			require.NoError(t, err)
+
+			got, err := n.Snapshot(tt.snapshot)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrInvalidName)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, got.IsSnapshot())
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func Test_validateNewPoolName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "tank"},
+		{name: "logs"},
+		{name: "Mirror"},
+		{
+			name:    "",
+			wantErr: "invalid name: empty name",
+		},
+		{
+			name: "tank/data",
+			wantErr: "invalid name 'tank/data': " +
+				"invalid character '/' in pool name",
+		},
+		{
+			name: "tank@snap",
+			wantErr: "invalid name 'tank@snap': " +
+				"invalid character '@' in pool name",
+		},
+		{
+			name:    "1tank",
+			wantErr: "invalid name '1tank': pool doesn't begin with a letter",
+		},
+		{
+			name:    "mirror",
+			wantErr: "invalid name 'mirror': name is reserved",
+		},
+		{
+			name:    "mirrored",
+			wantErr: "invalid name 'mirrored': name is reserved",
+		},
+		{
+			name:    "raidz2pool",
+			wantErr: "invalid name 'raidz2pool': name is reserved",
+		},
+		{
+			name:    "log",
+			wantErr: "invalid name 'log': name is reserved",
+		},
+		{
+			name:    "c0t0d0",
+			wantErr: "invalid name 'c0t0d0': reserved disk name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNewPoolName(tt.name)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrInvalidName)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_validateDestroyName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "tank/ds"},
+		{name: "tank/ds@snap"},
+		{name: "tank/ds#mark"},
+		{name: "tank/ds@a%b"},
+		{name: "tank/ds@a,b"},
+		{name: "tank/ds@a,c%e,g"},
+		{name: "tank/ds@%b"},
+		{name: "tank/ds@a%"},
+		{name: "tank/ds@%"},
+		{
+			name:    "tank/ds/",
+			wantErr: "invalid name 'tank/ds/': trailing slash in name",
+		},
+		{
+			name:    "/tank/ds@a,b",
+			wantErr: "invalid name '/tank/ds': leading slash in name",
+		},
+		{
+			name: "tank/ds@a,,b",
+			wantErr: "invalid name 'tank/ds@a,,b': " +
+				"empty component or misplaced '@' or '#' delimiter in name",
+		},
+		{
+			name: "tank/ds@a%b%c",
+			wantErr: "invalid name 'tank/ds@a%b%c': " +
+				"invalid character '%' in name",
+		},
+		{
+			name: "tank/ds@a,b/c",
+			wantErr: "invalid name 'tank/ds@a,b/c': " +
+				"invalid character '/' in name",
+		},
+		{
+			name: "tank/ds@a,b@c",
+			wantErr: "invalid name 'tank/ds@a,b@c': " +
+				"invalid character '@' in name",
+		},
+		{
+			name: "tank/ds#a,b",
+			wantErr: "invalid name 'tank/ds#a,b': " +
+				"invalid character ',' in name",
+		},
+		{name: "tank/" + strings.Repeat("a", 240) + "@a,b%c,d,e,f,g"},
+		{name: "tank/ds@" + strings.Repeat("daily-0001,", 60) + "last"},
+		{
+			name: "tank/" + strings.Repeat("a", 240) + "@a,b%" +
+				strings.Repeat("c", 10),
+			wantErr: "invalid name 'tank/" + strings.Repeat("a", 240) +
+				"@a,b%" + strings.Repeat("c", 10) + "': name is too long",
+		},
+		{
+			name: "tank/ds@a," + strings.Repeat("b", 250),
+			wantErr: "invalid name 'tank/ds@a," + strings.Repeat("b", 250) +
+				"': name is too long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDestroyName(tt.name)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrInvalidName)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+// PlannedCommand is a mutating zfs or zpool command which was recorded in a
+// Plan instead of being run.
+type PlannedCommand struct {
+	// Command is the command which would have been run, either "zfs" or
+	// "zpool".
+	Command string
+
+	// Args are the arguments which would have been passed to Command.
+	Args []string
+
+	// DryRunOutput is the output of running the command with its dry-run flag
+	// (-n) instead, for commands which support it, like "zfs create", "zfs
+	// destroy" and "zpool create". Empty for commands without a dry-run flag.
+	DryRunOutput string
+}
+
+// CommandLine returns the command and its arguments as a single string, in the
+// same format as CommandError.CommandLine.
+func (c *PlannedCommand) CommandLine() string {
+	return commandLine(c.Command, c.Args)
+}
+
+// Plan records mutating zfs and zpool commands instead of running them, when
+// assigned to a Manager's Plan field. It is safe for concurrent use, and its
+// zero value is ready for use.
+type Plan struct {
+	mu       sync.Mutex
+	commands []*PlannedCommand
+}
+
+// Commands returns all commands recorded so far, in the order they were
+// recorded.
+func (p *Plan) Commands() []*PlannedCommand {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	commands := make([]*PlannedCommand, len(p.commands))
+	copy(commands, p.commands)
+
+	return commands
+}
+
+// String returns the command lines of all recorded commands, one per line.
+func (p *Plan) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	for _, c := range p.commands {
+		b.WriteString(c.CommandLine())
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Reset clears all recorded commands.
+func (p *Plan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.commands = nil
+}
+
+func (p *Plan) record(c *PlannedCommand) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.commands = append(p.commands, c)
+}
+
+// mutatingCommands are the zfs and zpool subcommands which modify the system.
+var mutatingCommands = map[string]map[string]bool{
+	"zfs": {
+		"allow":      true,
+		"bookmark":   true,
+		"change-key": true,
+		"clone":      true,
+		"create":     true,
+		"destroy":    true,
+		"hold":       true,
+		"inherit":    true,
+		"load-key":   true,
+		"mount":      true,
+		"promote":    true,
+		"receive":    true,
+		"recv":       true,
+		"release":    true,
+		"rename":     true,
+		"rollback":   true,
+		"set":        true,
+		"share":      true,
+		"snapshot":   true,
+		"unallow":    true,
+		"unload-key": true,
+		"unmount":    true,
+		"unshare":    true,
+		"upgrade":    true,
+	},
+	"zpool": {
+		"add":        true,
+		"attach":     true,
+		"clear":      true,
+		"create":     true,
+		"destroy":    true,
+		"detach":     true,
+		"export":     true,
+		"import":     true,
+		"initialize": true,
+		"labelclear": true,
+		"offline":    true,
+		"online":     true,
+		"reguid":     true,
+		"remove":     true,
+		"replace":    true,
+		"scrub":      true,
+		"set":        true,
+		"split":      true,
+		"trim":       true,
+		"upgrade":    true,
+	},
+}
+
+// dryRunFlags are the flags which turn a mutating subcommand into a dry-run
+// which reports what it would do.
+var dryRunFlags = map[string]map[string][]string{
+	"zfs": {
+		"create":   {"-n", "-v"},
+		"destroy":  {"-n", "-v"},
+		"load-key": {"-n"},
+	},
+	"zpool": {
+		"create": {"-n"},
+	},
+}
+
+// mutating reports whether command with args modifies the system. Commands
+// which already include a dry-run flag (-n) are not considered mutating.
+func mutating(command string, args []string) bool {
+	if len(args) == 0 || !mutatingCommands[command][args[0]] {
+		return false
+	}
+
+	// "zfs mount" without arguments lists mounted filesystems.
+	if command == "zfs" && args[0] == "mount" && len(args) == 1 {
+		return false
+	}
+
+	// "zpool import" without -a or a pool lists pools available for import.
+	if command == "zpool" && args[0] == "import" && !importsPool(args[1:]) {
+		return false
+	}
+
+	// Both "zpool import -F -n" and the dry-run flags of create, destroy, and
+	// load-key do not modify the system, including when combined with other
+	// flags, like "zfs destroy -rn".
+	if _, ok := dryRunFlags[command][args[0]]; ok || args[0] == "import" {
+		if hasShortFlag(args[1:], 'n', valueFlags[command][args[0]]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// importValueFlags are the flags of zpool import which take a value.
+const importValueFlags = "cdoRT"
+
+// valueFlags are the flags which take a value of subcommands that support a
+// dry-run flag.
+var valueFlags = map[string]map[string]string{
+	"zfs": {
+		"create":   "boV",
+		"load-key": "L",
+	},
+	"zpool": {
+		"create": "moORt",
+		"import": importValueFlags,
+	},
+}
+
+// hasShortFlag reports whether args include the short flag c, either on its
+// own, like "-n", or combined with other flags, like "-rn". Values of flags in
+// valueFlags are skipped, and parsing stops at the first argument which is
+// not a flag.
+func hasShortFlag(args []string, c rune, valueFlags string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return false
+		case strings.HasPrefix(arg, "--"):
+			continue
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			return false
+		}
+
+		for j, f := range arg[1:] {
+			if f == c {
+				return true
+			}
+			if strings.ContainsRune(valueFlags, f) {
+				// The value is either the rest of arg, or the next arg.
+				if j == len(arg)-2 {
+					i++
+				}
+
+				break
+			}
+		}
+	}
+
+	return false
+}
+
+// importsPool reports whether zpool import args import one or more pools,
+// which is the case when the -a flag, or a pool name or GUID is given.
+func importsPool(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			continue
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			return true
+		}
+
+		for j, c := range arg[1:] {
+			if c == 'a' {
+				return true
+			}
+			if strings.ContainsRune(importValueFlags, c) {
+				// The value is either the rest of arg, or the next arg.
+				if j == len(arg)-2 {
+					i++
+				}
+
+				break
+			}
+		}
+	}
+
+	return false
+}
+
+// plan records command with args in the Manager's Plan. For commands which
+// support a dry-run flag, the dry-run variant of the command is run, and its
+// output recorded along with the command. If the dry-run fails, its error is
+// returned, and the command is not recorded.
+func (m *Manager) plan(
+	ctx context.Context,
+	stdin io.Reader,
+	kind error,
+	command string,
+	args []string,
+) error {
+	planned := &PlannedCommand{Command: command, Args: args}
+
+	if flags, ok := dryRunFlags[command][args[0]]; ok {
+		dryArgs := make([]string, 0, len(args)+len(flags))
+		dryArgs = append(dryArgs, args[0])
+		dryArgs = append(dryArgs, flags...)
+		dryArgs = append(dryArgs, args[1:]...)
+
+		var stdout []byte
+		_, err := m.retry(ctx, stdin, func() ([][]string, error) {
+			var err error
+			stdout, err = m.exec(ctx, stdin, kind, command, dryArgs...)
+
+			return nil, err
+		})
+		if err != nil {
+			return err
+		}
+
+		planned.DryRunOutput = string(bytes.TrimSpace(stdout))
+	}
+
+	m.Plan.record(planned)
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutating(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    bool
+	}{
+		{"zfs", nil, false},
+		{"zfs", []string{"get", "-Hp", "all"}, false},
+		{"zfs", []string{"list", "-Hp"}, false},
+		{"zfs", []string{"mount"}, false},
+		{"zfs", []string{"mount", "tank/a"}, true},
+		{"zfs", []string{"set", "atime=off", "tank"}, true},
+		{"zfs", []string{"inherit", "atime", "tank"}, true},
+		{"zfs", []string{"create", "tank/a"}, true},
+		{"zfs", []string{"create", "-n", "tank/a"}, false},
+		{"zfs", []string{"create", "-nv", "tank/a"}, false},
+		{"zfs", []string{"create", "-pn", "-o", "atime=off", "tank/a"}, false},
+		{"zfs", []string{"create", "-o", "atime=off", "-n", "tank/a"}, false},
+		{"zfs", []string{"create", "-V", "-n", "tank/a"}, true},
+		{"zfs", []string{"create", "-Vn", "tank/a"}, true},
+		{"zfs", []string{"create", "tank/a", "-n"}, true},
+		{"zfs", []string{"destroy", "tank/a"}, true},
+		{"zfs", []string{"destroy", "-rn", "tank/a"}, false},
+		{"zfs", []string{"destroy", "-r", "tank/-n"}, true},
+		{"zfs", []string{"rename", "tank/a", "tank/b"}, true},
+		{"zfs", []string{"load-key", "tank/a"}, true},
+		{"zfs", []string{"load-key", "-n", "tank/a"}, false},
+		{"zfs", []string{"unload-key", "tank/a"}, true},
+		{"zpool", []string{"list", "-Hp"}, false},
+		{"zpool", []string{"get", "all", "tank"}, false},
+		{"zpool", []string{"create", "tank", "sdb"}, true},
+		{"zpool", []string{"set", "comment=a", "tank"}, true},
+		{"zpool", []string{"import"}, false},
+		{"zpool", []string{"import", "-d", "/dev/disk/by-id"}, false},
+		{"zpool", []string{"import", "-o", "readonly=on", "-D"}, false},
+		{"zpool", []string{"import", "tank"}, true},
+		{"zpool", []string{"import", "-d", "/dev", "1234567890"}, true},
+		{"zpool", []string{"import", "-f", "-a"}, true},
+		{"zpool", []string{"import", "-Na"}, true},
+		{"zpool", []string{"import", "-dfoo", "tank", "new"}, true},
+		{"zpool", []string{"import", "-F", "-n", "tank"}, false},
+		{"zpool", []string{"import", "-Fn", "tank"}, false},
+		{"zpool", []string{"create", "-fn", "tank", "sdb"}, false},
+		{"zpool", []string{"create", "-m", "-n", "tank", "sdb"}, true},
+		{"zpool", []string{"export", "tank"}, true},
+		{"zpool", []string{"destroy", "tank"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(commandLine(tt.command, tt.args), func(t *testing.T) {
+			got := mutating(tt.command, tt.args)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// fakeRun sets up r to respond to any command by looking up its command line
+// in outputs, writing the stdout value, or failing with the stderr value.
+func fakeRun(
+	r *mock_runner.MockRunner,
+	outputs map[string][2]string,
+	ran *[]string,
+) {
+	r.EXPECT().RunContext(
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+		gomock.Any(),
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout io.Writer,
+		stderr io.Writer,
+		command string,
+		args ...string,
+	) error {
+		line := commandLine(command, args)
+		*ran = append(*ran, line)
+
+		out := outputs[line]
+		_, _ = stdout.Write([]byte(out[0]))
+		if out[1] != "" {
+			_, _ = stderr.Write([]byte(out[1]))
+
+			return errors.New("exit status 1")
+		}
+
+		return nil
+	}).AnyTimes()
+}
+
+func TestManager_Plan(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	ran := []string{}
+	fakeRun(r, map[string][2]string{
+		"zfs get -Hp -o value atime tank/a": {"on\n", ""},
+		"zfs create -n -v -o compression=lz4 tank/b": {
+			"would create 'tank/b'\n\tcompression=lz4\n", "",
+		},
+		"zfs destroy -n -v tank/c": {
+			"", "cannot open 'tank/c': dataset does not exist\n",
+		},
+		"zpool create -n scratch /dev/sdc": {
+			"would create 'scratch' with the following layout:\n\n" +
+				"\tscratch\n\t  /dev/sdc\n", "",
+		},
+	}, &ran)
+
+	plan := &Plan{}
+	m := &Manager{Runner: r, Plan: plan}
+
+	got, err := m.GetDatasetProperty(ctx, "tank/a", "atime")
+	require.NoError(t, err)
+	assert.Equal(t, "on", got)
+
+	err = m.SetDatasetProperty(ctx, "tank/a", "atime", "off")
+	require.NoError(t, err)
+
+	err = m.CreateDataset(ctx, &CreateDatasetOptions{
+		Name:       "tank/b",
+		Properties: map[string]string{"compression": "lz4"},
+	})
+	require.NoError(t, err)
+
+	err = m.DestroyDataset(ctx, "tank/c")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	err = m.CreatePool(ctx, &CreatePoolOptions{
+		Name:  "scratch",
+		Vdevs: []string{"/dev/sdc"},
+	})
+	require.NoError(t, err)
+
+	err = m.ExportPool(ctx, "scratch", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"zfs get -Hp -o value atime tank/a",
+		"zfs create -n -v -o compression=lz4 tank/b",
+		"zfs destroy -n -v tank/c",
+		"zpool create -n scratch /dev/sdc",
+	}, ran)
+	assert.Equal(t, []*PlannedCommand{
+		{
+			Command: "zfs",
+			Args:    []string{"set", "atime=off", "tank/a"},
+		},
+		{
+			Command:      "zfs",
+			Args:         []string{"create", "-o", "compression=lz4", "tank/b"},
+			DryRunOutput: "would create 'tank/b'\n\tcompression=lz4",
+		},
+		{
+			Command: "zpool",
+			Args:    []string{"create", "scratch", "/dev/sdc"},
+			DryRunOutput: "would create 'scratch' with the following " +
+				"layout:\n\n\tscratch\n\t  /dev/sdc",
+		},
+		{
+			Command: "zpool",
+			Args:    []string{"export", "scratch"},
+		},
+	}, plan.Commands())
+	assert.Equal(t,
+		"zfs set atime=off tank/a\n"+
+			"zfs create -o compression=lz4 tank/b\n"+
+			"zpool create scratch /dev/sdc\n"+
+			"zpool export scratch\n",
+		plan.String(),
+	)
+
+	plan.Reset()
+	assert.Empty(t, plan.Commands())
+	assert.Equal(t, "", plan.String())
+}
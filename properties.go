@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/krystal/go-zfs/zfsprops"
+	"github.com/krystal/go-zfs/zpoolprops"
 )
 
 const allProperty = "all"
@@ -64,6 +66,11 @@ type Property struct {
 
 	// Source is the source of the property.
 	Source string
+
+	// Received is the value received with zfs receive, or "-" if no value was
+	// received. It is only populated by methods which explicitly request
+	// received values, like GetDatasetReceived, and is empty otherwise.
+	Received string
 }
 
 // Properties is a collection of ZFS properties, that includes typed accessor
@@ -71,7 +78,9 @@ type Property struct {
 type Properties map[string]Property
 
 // newProperties accepts a "records" slice of string slices, typically as
-// returned by parseTabular, and returns a Properties map.
+// returned by parseTabular, and returns a Properties map. Records have either
+// four fields (name, property, value, source), or five fields when received
+// values are requested (name, property, value, received, source).
 //
 // For example, if given the following "records" [][]string value:
 //
@@ -131,23 +140,47 @@ type Properties map[string]Property
 func newProperties(records [][]string) map[string]Properties {
 	r := map[string]Properties{}
 	for _, record := range records {
-		if len(record) == 4 && record[0] != "" {
+		if (len(record) == 4 || len(record) == 5) && record[0] != "" {
 			if _, ok := r[record[0]]; !ok {
 				r[record[0]] = map[string]Property{}
 			}
 
-			r[record[0]][record[1]] = Property{
+			prop := Property{
 				Name:     record[0],
 				Property: record[1],
 				Value:    record[2],
 				Source:   record[3],
 			}
+			if len(record) == 5 {
+				prop.Received = record[3]
+				prop.Source = record[4]
+			}
+
+			r[record[0]][record[1]] = prop
 		}
 	}
 
 	return r
 }
 
+// recordNames returns the distinct names of the given "records", typically as
+// returned by parseTabular, in the order they first appear. As zfs and zpool
+// list pools and datasets in hierarchical order, this preserves that order,
+// which is lost in the map returned by newProperties.
+func recordNames(records [][]string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, record := range records {
+		if (len(record) == 4 || len(record) == 5) && record[0] != "" &&
+			!seen[record[0]] {
+			seen[record[0]] = true
+			names = append(names, record[0])
+		}
+	}
+
+	return names
+}
+
 // String returns the value of the given property.
 //
 // The second return value indicates if the property is present and could
@@ -208,8 +241,8 @@ func (p Properties) Ratio(property string) (float64, bool) {
 	return 0, false
 }
 
-// Bool returns the value of the given property as a bool. Only "on" and
-// "enabled" are considered true, all other value return false.
+// Bool returns the value of the given property as a bool. Only "on",
+// "enabled" and "yes" are considered true, all other value return false.
 //
 // The second return value indicates if the property is present and could
 // successfully be parsed.
@@ -297,7 +330,8 @@ func (p Properties) parseSize(size string) (uint64, error) {
 
 func (p Properties) parseBool(str string) bool {
 	switch str {
-	case "on", "On", "ON", "enabled", "Enabled", "ENABLED":
+	case "on", "On", "ON", "enabled", "Enabled", "ENABLED",
+		"yes", "Yes", "YES":
 		return true
 	}
 
@@ -316,3 +350,122 @@ func (p Properties) parseTime(str string) (time.Time, error) {
 
 	return t.UTC(), err
 }
+
+// validateDatasetProperties validates properties against the metadata in the
+// zfsprops package. Read-only properties are always rejected, create-only
+// properties are rejected unless create is true, and properties which do not
+// apply to typ are rejected. A zero typ skips the dataset type check.
+//
+// Properties unknown to the zfsprops package are passed through without
+// validation, to support properties added by newer versions of ZFS.
+func validateDatasetProperties(
+	properties map[string]string,
+	typ zfsprops.DatasetTypes,
+	create bool,
+) error {
+	for _, name := range sortedKeys(properties) {
+		p, ok := zfsprops.Lookup(name)
+		if !ok {
+			continue
+		}
+
+		switch p.Access {
+		case zfsprops.AccessReadOnly:
+			return fmt.Errorf("%w: %s: property is read-only",
+				ErrInvalidProperty, name,
+			)
+		case zfsprops.AccessCreateOnly:
+			if !create {
+				return fmt.Errorf(
+					"%w: %s: property can only be set at creation time",
+					ErrInvalidProperty, name,
+				)
+			}
+		case zfsprops.AccessSettable:
+		}
+
+		if !p.Types.Has(typ) {
+			return fmt.Errorf("%w: %s: property is not valid for %ss",
+				ErrInvalidProperty, name, datasetTypeName(typ),
+			)
+		}
+
+		if err := p.Validate(properties[name]); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidProperty, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func datasetTypeName(typ zfsprops.DatasetTypes) DatasetType {
+	if typ == zfsprops.TypeVolume {
+		return VolumeType
+	}
+
+	return FilesystemType
+}
+
+// validatePoolProperties validates properties against the metadata in the
+// zpoolprops package. Properties with an access not included in allowed are
+// rejected.
+//
+// Properties unknown to the zpoolprops package are passed through without
+// validation, to support properties added by newer versions of ZFS.
+func validatePoolProperties(
+	properties map[string]string,
+	allowed ...zpoolprops.Access,
+) error {
+	for _, name := range sortedKeys(properties) {
+		p, ok := zpoolprops.Lookup(name)
+		if !ok {
+			continue
+		}
+
+		if !accessAllowed(p.Access, allowed) {
+			return fmt.Errorf("%w: %s: %s",
+				ErrInvalidProperty, name, poolAccessReason(p.Access),
+			)
+		}
+
+		if err := p.Validate(properties[name]); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidProperty, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func accessAllowed(access zpoolprops.Access, allowed []zpoolprops.Access) bool {
+	for _, a := range allowed {
+		if a == access {
+			return true
+		}
+	}
+
+	return false
+}
+
+func poolAccessReason(access zpoolprops.Access) string {
+	switch access {
+	case zpoolprops.AccessReadOnly:
+		return "property is read-only"
+	case zpoolprops.AccessCreateOnly:
+		return "property can only be set at creation or import time"
+	case zpoolprops.AccessImportOnly:
+		return "property can only be set at import time"
+	case zpoolprops.AccessSettable:
+	}
+
+	return "property cannot be set here"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,129 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/krystal/go-zfs"
+)
+
+// Action is the kind of a Change.
+type Action string
+
+const (
+	// SetPoolProperty sets a pool property.
+	SetPoolProperty Action = "set-pool"
+
+	// CreateDataset creates a dataset.
+	CreateDataset Action = "create"
+
+	// SetProperty sets a dataset property.
+	SetProperty Action = "set"
+
+	// InheritProperty inherits a dataset property, clearing its local value.
+	InheritProperty Action = "inherit"
+
+	// DestroyDataset destroys a dataset.
+	DestroyDataset Action = "destroy"
+)
+
+// Change is a single change of a Plan.
+type Change struct {
+	// Action is the kind of change.
+	Action Action
+
+	// Name is the name of the pool or dataset to change.
+	Name string
+
+	// Property is the name of the property to set or inherit.
+	Property string
+
+	// Value is the new value of the property to set.
+	Value string
+
+	// OldValue is the current value of the property to set or inherit. Empty
+	// if the property is not currently present.
+	OldValue string
+
+	// CreateOptions are the options used to create the dataset.
+	CreateOptions *zfs.CreateDatasetOptions
+
+	// DestroyFlags are the flags used to destroy the dataset.
+	DestroyFlags []zfs.DestroyDatasetFlag
+}
+
+// String returns a human readable description of the change, for example:
+//
+//	set tank/www compression=lz4 (was off)
+func (c *Change) String() string {
+	switch c.Action {
+	case SetPoolProperty, SetProperty:
+		s := fmt.Sprintf("%s %s %s=%s", c.Action, c.Name, c.Property, c.Value)
+		if c.OldValue != "" {
+			s += fmt.Sprintf(" (was %s)", c.OldValue)
+		}
+
+		return s
+	case InheritProperty:
+		return fmt.Sprintf(
+			"%s %s %s (was %s)", c.Action, c.Name, c.Property, c.OldValue,
+		)
+	case CreateDataset, DestroyDataset:
+		return fmt.Sprintf("%s %s", c.Action, c.Name)
+	default:
+		return fmt.Sprintf("%s %s", c.Action, c.Name)
+	}
+}
+
+// apply performs the change via m.
+func (c *Change) apply(ctx context.Context, m zfs.Interface) error {
+	switch c.Action {
+	case SetPoolProperty:
+		return m.SetPoolProperty(ctx, c.Name, c.Property, c.Value)
+	case CreateDataset:
+		return m.CreateDataset(ctx, c.CreateOptions)
+	case SetProperty:
+		return m.SetDatasetProperty(ctx, c.Name, c.Property, c.Value)
+	case InheritProperty:
+		return m.InheritDatasetProperty(ctx, c.Name, c.Property, false)
+	case DestroyDataset:
+		return m.DestroyDataset(ctx, c.Name, c.DestroyFlags...)
+	default:
+		return fmt.Errorf("unknown action '%s'", c.Action)
+	}
+}
+
+// Plan is an ordered list of changes, as returned by Diff.
+type Plan struct {
+	Changes []*Change
+}
+
+// Empty reports whether the plan has no changes, meaning the current state
+// already matches the desired state.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns the descriptions of all changes, one per line.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		b.WriteString(c.String())
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Apply performs all changes of plan via m, in order. It stops at the first
+// change which fails, returning its error along with the change.
+func Apply(ctx context.Context, m zfs.Interface, plan *Plan) error {
+	for _, c := range plan.Changes {
+		if err := c.apply(ctx, m); err != nil {
+			return fmt.Errorf("%s: %w", c, err)
+		}
+	}
+
+	return nil
+}
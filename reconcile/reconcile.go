@@ -0,0 +1,622 @@
+// Package reconcile brings pools and datasets in line with a declarative
+// desired state.
+//
+// Diff compares a State against the datasets and pools reported by a
+// zfs.Manager, and returns an ordered Plan of changes: pool properties are set
+// first, then missing datasets are created parents-first, changed properties
+// are set, properties no longer desired are inherited, and finally, only if
+// explicitly enabled, datasets no longer desired are destroyed children-first.
+// Apply then performs the changes of a Plan via the zfs.Manager:
+//
+//	plan, err := reconcile.Diff(ctx, m, &reconcile.State{
+//		Datasets: []*reconcile.Dataset{
+//			{
+//				Name: "tank/www",
+//				Properties: map[string]string{
+//					zfsprops.Quota:       "100G",
+//					zfsprops.Compression: "lz4",
+//					"acme:owner":         "web-team",
+//				},
+//			},
+//		},
+//	}, nil)
+//	if err != nil {
+//		return err
+//	}
+//
+//	fmt.Print(plan)
+//
+//	err = reconcile.Apply(ctx, m, plan)
+//
+// Property sources are used to tell local values from inherited and default
+// ones. A desired property is set if its value differs, or if it is not set
+// locally. A locally set property which is not desired is inherited. Quotas,
+// reservations, and limits cannot be inherited, and are instead set to "none",
+// while other properties which cannot be inherited are left as is.
+//
+// Property aliases, like "compress", are resolved to their full names, like
+// "compression", before they are compared.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/krystal/go-zfs"
+	"github.com/krystal/go-zfs/zfsprops"
+	"github.com/krystal/go-zfs/zpoolprops"
+)
+
+var (
+	// ErrInvalidState is returned when a State is invalid.
+	ErrInvalidState = errors.New("invalid desired state")
+
+	// ErrTypeMismatch is returned when an existing dataset is not of the
+	// desired type.
+	ErrTypeMismatch = errors.New("dataset type mismatch")
+)
+
+// DefaultIgnoredProperties are properties which are never inherited by Diff,
+// even if they are set locally and not desired. They are set locally on
+// creation of a dataset, and cannot be inherited.
+var DefaultIgnoredProperties = []string{
+	zfsprops.CaseSensitivity,
+	zfsprops.Encryption,
+	zfsprops.KeyFormat,
+	zfsprops.KeyLocation,
+	zfsprops.Normalization,
+	zfsprops.PBKDF2Iterations,
+	zfsprops.RefReservation,
+	zfsprops.UTF8Only,
+	zfsprops.VolBlockSize,
+	zfsprops.VolSize,
+}
+
+// State is the desired state of pools and datasets.
+type State struct {
+	// Pools are pools with desired properties. Pools must already exist, as
+	// they are never created or destroyed.
+	Pools []*Pool
+
+	// Datasets are the desired filesystems and volumes. Their pools must
+	// already exist.
+	Datasets []*Dataset
+}
+
+// Pool is the desired state of a pool.
+type Pool struct {
+	// Name of the pool. (required)
+	Name string
+
+	// Properties are the desired pool properties. Properties not listed are
+	// left as is.
+	Properties map[string]string
+}
+
+// Dataset is the desired state of a filesystem or volume.
+type Dataset struct {
+	// Name of the dataset. (required)
+	Name string
+
+	// Type is the type of the dataset, either zfs.FilesystemType or
+	// zfs.VolumeType. Defaults to zfs.FilesystemType when empty.
+	Type zfs.DatasetType
+
+	// VolumeSize is the size of the volume. Required for volumes, and must be
+	// empty for filesystems.
+	VolumeSize string
+
+	// Properties are the desired properties of the dataset, including quotas
+	// and user properties. Locally set properties not listed are inherited.
+	Properties map[string]string
+}
+
+func (d *Dataset) typ() zfs.DatasetType {
+	if d.Type == "" {
+		return zfs.FilesystemType
+	}
+
+	return d.Type
+}
+
+// Options are options for Diff.
+type Options struct {
+	// Destroy enables destroying of existing filesystems and volumes which
+	// are not in the desired state, within the pools of the desired state.
+	// Pool root datasets, and parents of desired datasets are never
+	// destroyed.
+	Destroy bool
+
+	// DestroyFlags are passed to zfs.Manager.DestroyDataset when destroying
+	// datasets.
+	DestroyFlags []zfs.DestroyDatasetFlag
+
+	// IgnoreProperties are properties which are never inherited, in
+	// addition to DefaultIgnoredProperties.
+	IgnoreProperties []string
+}
+
+// Reconcile calls Diff, and then Apply with the resulting plan. The returned
+// plan lists the changes which were made, or attempted in case of an error.
+func Reconcile(
+	ctx context.Context,
+	m zfs.Interface,
+	state *State,
+	options *Options,
+) (*Plan, error) {
+	plan, err := Diff(ctx, m, state, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, Apply(ctx, m, plan)
+}
+
+// Diff compares the desired state against the current state of pools and
+// datasets as reported by m, and returns a plan of changes needed to bring
+// the current state in line with the desired state.
+func Diff(
+	ctx context.Context,
+	m zfs.Interface,
+	state *State,
+	options *Options,
+) (*Plan, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	if err := state.validate(); err != nil {
+		return nil, err
+	}
+
+	d := &differ{
+		options: options,
+		ignored: map[string]bool{},
+		desired: map[string]*Dataset{},
+		current: map[string]*zfs.Dataset{},
+		plan:    &Plan{},
+	}
+	for _, p := range DefaultIgnoredProperties {
+		d.ignored[p] = true
+	}
+	for _, p := range options.IgnoreProperties {
+		d.ignored[datasetPropertyName(p)] = true
+	}
+	for _, ds := range state.Datasets {
+		desired := *ds
+		desired.Properties = datasetProperties(ds.Properties)
+		d.desired[ds.Name] = &desired
+	}
+
+	for _, pool := range state.Pools {
+		if err := d.diffPool(ctx, m, pool); err != nil {
+			return nil, err
+		}
+	}
+
+	types := zfs.JoinTypes(zfs.FilesystemType, zfs.VolumeType)
+	for _, pool := range state.poolNames() {
+		datasets, err := m.ListDatasets(ctx, pool, 0, types)
+		if err != nil {
+			return nil, err
+		}
+		for _, ds := range datasets {
+			d.current[ds.Name] = ds
+		}
+	}
+
+	if err := d.diffDatasets(); err != nil {
+		return nil, err
+	}
+	if options.Destroy {
+		d.diffDestroy()
+	}
+
+	return d.plan, nil
+}
+
+func (s *State) validate() error {
+	if s == nil {
+		return fmt.Errorf("%w: state is nil", ErrInvalidState)
+	}
+
+	pools := map[string]bool{}
+	for _, p := range s.Pools {
+		if p == nil || p.Name == "" || strings.ContainsAny(p.Name, "/@#") {
+			return fmt.Errorf("%w: invalid pool name", ErrInvalidState)
+		}
+		if pools[p.Name] {
+			return fmt.Errorf("%w: duplicate pool %s", ErrInvalidState, p.Name)
+		}
+		pools[p.Name] = true
+		if prop, ok := duplicateProperty(
+			p.Properties, poolPropertyName,
+		); ok {
+			return fmt.Errorf(
+				"%w: pool %s has duplicate property %s",
+				ErrInvalidState, p.Name, prop,
+			)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, ds := range s.Datasets {
+		if err := ds.validate(); err != nil {
+			return err
+		}
+		if names[ds.Name] {
+			return fmt.Errorf(
+				"%w: duplicate dataset %s", ErrInvalidState, ds.Name,
+			)
+		}
+		names[ds.Name] = true
+	}
+
+	return nil
+}
+
+func (d *Dataset) validate() error {
+	switch {
+	case d == nil:
+		return fmt.Errorf("%w: dataset is nil", ErrInvalidState)
+	case d.Name == "" || strings.HasPrefix(d.Name, "/") ||
+		strings.HasSuffix(d.Name, "/") || strings.ContainsAny(d.Name, "@#"):
+		return fmt.Errorf(
+			"%w: invalid dataset name '%s'", ErrInvalidState, d.Name,
+		)
+	case d.typ() == zfs.VolumeType && d.VolumeSize == "":
+		return fmt.Errorf(
+			"%w: volume %s requires a size", ErrInvalidState, d.Name,
+		)
+	case d.typ() == zfs.FilesystemType && d.VolumeSize != "":
+		return fmt.Errorf(
+			"%w: filesystem %s cannot have a volume size",
+			ErrInvalidState, d.Name,
+		)
+	case d.typ() != zfs.FilesystemType && d.typ() != zfs.VolumeType:
+		return fmt.Errorf(
+			"%w: dataset %s has unsupported type '%s'",
+			ErrInvalidState, d.Name, d.Type,
+		)
+	}
+
+	if prop, ok := duplicateProperty(d.Properties, datasetPropertyName); ok {
+		return fmt.Errorf(
+			"%w: dataset %s has duplicate property %s",
+			ErrInvalidState, d.Name, prop,
+		)
+	}
+
+	return nil
+}
+
+// poolNames returns the sorted names of all pools referenced by datasets of
+// the state.
+func (s *State) poolNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, ds := range s.Datasets {
+		pool := strings.SplitN(ds.Name, "/", 2)[0]
+		if !seen[pool] {
+			seen[pool] = true
+			names = append(names, pool)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// differ holds the state of a single Diff call.
+type differ struct {
+	options *Options
+	ignored map[string]bool
+	desired map[string]*Dataset
+	current map[string]*zfs.Dataset
+	plan    *Plan
+}
+
+func (d *differ) add(c *Change) {
+	d.plan.Changes = append(d.plan.Changes, c)
+}
+
+func (d *differ) diffPool(ctx context.Context, m zfs.Interface, p *Pool) error {
+	if len(p.Properties) == 0 {
+		return nil
+	}
+
+	props := poolProperties(p.Properties)
+	names := sortedKeys(props)
+	pool, err := m.GetPool(ctx, p.Name, names...)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		want := props[name]
+		prop, ok := pool.Properties[name]
+		if ok && equalValues(want, prop.Value) {
+			continue
+		}
+
+		d.add(&Change{
+			Action:   SetPoolProperty,
+			Name:     p.Name,
+			Property: name,
+			Value:    want,
+			OldValue: prop.Value,
+		})
+	}
+
+	return nil
+}
+
+func (d *differ) diffDatasets() error {
+	names := make([]string, 0, len(d.desired))
+	for name := range d.desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sets := []*Change{}
+	inherits := []*Change{}
+	for _, name := range names {
+		want := d.desired[name]
+		got, ok := d.current[name]
+		if !ok {
+			d.add(d.create(want))
+
+			continue
+		}
+
+		if typ, _ := got.String(zfsprops.Type); typ != string(want.typ()) {
+			return fmt.Errorf(
+				"%w: %s is a %s, not a %s",
+				ErrTypeMismatch, name, typ, want.typ(),
+			)
+		}
+
+		props := want.Properties
+		if want.VolumeSize != "" {
+			props = withProperty(props, zfsprops.VolSize, want.VolumeSize)
+		}
+
+		for _, prop := range sortedKeys(props) {
+			cur := got.Properties[prop]
+			if got.Properties.IsLocal(prop) &&
+				equalValues(props[prop], cur.Value) {
+				continue
+			}
+
+			sets = append(sets, &Change{
+				Action:   SetProperty,
+				Name:     name,
+				Property: prop,
+				Value:    props[prop],
+				OldValue: cur.Value,
+			})
+		}
+
+		local := []string{}
+		for prop := range got.Properties {
+			_, ok := props[prop]
+			if !ok && got.Properties.IsLocal(prop) && !d.ignored[prop] {
+				local = append(local, prop)
+			}
+		}
+		sort.Strings(local)
+
+		for _, prop := range local {
+			cur := got.Properties[prop]
+			meta, ok := zfsprops.Lookup(prop)
+			switch {
+			case ok && meta.Inheritable:
+				inherits = append(inherits, &Change{
+					Action:   InheritProperty,
+					Name:     name,
+					Property: prop,
+					OldValue: cur.Value,
+				})
+			case ok && resettable(meta) && !equalValues("none", cur.Value):
+				sets = append(sets, &Change{
+					Action:   SetProperty,
+					Name:     name,
+					Property: prop,
+					Value:    "none",
+					OldValue: cur.Value,
+				})
+			}
+		}
+	}
+
+	d.plan.Changes = append(d.plan.Changes, sets...)
+	d.plan.Changes = append(d.plan.Changes, inherits...)
+
+	return nil
+}
+
+// create returns a change which creates ds. Missing parents which are not
+// desired themselves are created too.
+func (d *differ) create(ds *Dataset) *Change {
+	parent := ""
+	if i := strings.LastIndex(ds.Name, "/"); i != -1 {
+		parent = ds.Name[:i]
+	}
+	_, parentExists := d.current[parent]
+	_, parentDesired := d.desired[parent]
+
+	return &Change{
+		Action: CreateDataset,
+		Name:   ds.Name,
+		CreateOptions: &zfs.CreateDatasetOptions{
+			Name:          ds.Name,
+			Properties:    ds.Properties,
+			CreateParents: parent != "" && !parentExists && !parentDesired,
+			VolumeSize:    ds.VolumeSize,
+		},
+	}
+}
+
+func (d *differ) diffDestroy() {
+	keep := map[string]bool{}
+	for name := range d.desired {
+		for {
+			keep[name] = true
+			i := strings.LastIndex(name, "/")
+			if i == -1 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+
+	names := make([]string, 0, len(d.current))
+	for name := range d.current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		if keep[name] || !strings.Contains(name, "/") {
+			continue
+		}
+
+		d.add(&Change{
+			Action:       DestroyDataset,
+			Name:         name,
+			DestroyFlags: d.options.DestroyFlags,
+		})
+	}
+}
+
+// equalValues reports whether the desired property value want is equal to
+// the current value got. As current values are in parsable form, sizes like
+// "100G" are compared by their number of bytes.
+func equalValues(want, got string) bool {
+	if want == got {
+		return true
+	}
+	if want == "none" && got == "0" {
+		return true
+	}
+
+	props := zfs.Properties{
+		"want": {Value: want},
+		"got":  {Value: got},
+	}
+	w, ok := props.Bytes("want")
+	if !ok {
+		return false
+	}
+	g, ok := props.Bytes("got")
+
+	return ok && w == g
+}
+
+// resettable reports whether the property p, which cannot be inherited, can
+// be reset by setting it to "none", like quotas, reservations, and limits.
+func resettable(p *zfsprops.Property) bool {
+	if p.Access != zfsprops.AccessSettable {
+		return false
+	}
+	for _, v := range p.Values {
+		if v == "none" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// datasetPropertyName returns the full name of the dataset property name,
+// resolving aliases like "compress" to "compression".
+func datasetPropertyName(name string) string {
+	if p, ok := zfsprops.Lookup(name); ok {
+		return p.Name
+	}
+
+	return name
+}
+
+// poolPropertyName returns the full name of the pool property name,
+// resolving aliases like "cap" to "capacity".
+func poolPropertyName(name string) string {
+	if p, ok := zpoolprops.Lookup(name); ok {
+		return p.Name
+	}
+
+	return name
+}
+
+// datasetProperties returns a copy of props with aliases resolved to full
+// property names.
+func datasetProperties(props map[string]string) map[string]string {
+	return renameKeys(props, datasetPropertyName)
+}
+
+// poolProperties returns a copy of props with aliases resolved to full
+// property names.
+func poolProperties(props map[string]string) map[string]string {
+	return renameKeys(props, poolPropertyName)
+}
+
+func renameKeys(
+	m map[string]string,
+	rename func(string) string,
+) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	r := make(map[string]string, len(m))
+	for k, v := range m {
+		r[rename(k)] = v
+	}
+
+	return r
+}
+
+// duplicateProperty returns the first property of props, in sorted order,
+// which is given more than once by its full name and an alias.
+func duplicateProperty(
+	props map[string]string,
+	rename func(string) string,
+) (string, bool) {
+	seen := map[string]bool{}
+	for _, k := range sortedKeys(props) {
+		name := rename(k)
+		if seen[name] {
+			return name, true
+		}
+		seen[name] = true
+	}
+
+	return "", false
+}
+
+func withProperty(
+	props map[string]string,
+	name string,
+	value string,
+) map[string]string {
+	r := make(map[string]string, len(props)+1)
+	for k, v := range props {
+		r[k] = v
+	}
+	r[name] = value
+
+	return r
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,339 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/krystal/go-zfs"
+	"github.com/krystal/go-zfs/zfstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newManager returns a zfs.Manager backed by a fake with a pool named "tank",
+// and the given filesystems created in order.
+func newManager(t *testing.T, filesystems ...string) *zfs.Manager {
+	t.Helper()
+
+	ctx := context.Background()
+	m := &zfs.Manager{Runner: zfstest.New()}
+
+	err := m.CreatePool(ctx, &zfs.CreatePoolOptions{
+		Name:  "tank",
+		Vdevs: []string{"/dev/sdb"},
+	})
+	require.NoError(t, err)
+
+	for _, name := range filesystems {
+		err := m.CreateDataset(ctx, &zfs.CreateDatasetOptions{Name: name})
+		require.NoError(t, err)
+	}
+
+	return m
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, "tank/www", "tank/www/cache", "tank/old")
+	err := m.SetDatasetProperties(ctx, "tank/www", map[string]string{
+		"quota":      "10G",
+		"atime":      "off",
+		"acme:owner": "ops",
+	})
+	require.NoError(t, err)
+
+	plan, err := Diff(ctx, m, &State{
+		Pools: []*Pool{
+			{Name: "tank", Properties: map[string]string{"comment": "main"}},
+		},
+		Datasets: []*Dataset{
+			{
+				Name: "tank/www",
+				Properties: map[string]string{
+					"quota":       "10G",
+					"compression": "lz4",
+					"acme:owner":  "web",
+				},
+			},
+			{Name: "tank/www/cache"},
+			{
+				Name:       "tank/db/data",
+				Properties: map[string]string{"recordsize": "16K"},
+			},
+			{Name: "tank/db"},
+			{Name: "tank/vm/disk0", Type: zfs.VolumeType, VolumeSize: "1G"},
+		},
+	}, &Options{Destroy: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, ""+
+		"set-pool tank comment=main (was -)\n"+
+		"create tank/db\n"+
+		"create tank/db/data\n"+
+		"create tank/vm/disk0\n"+
+		"set tank/www acme:owner=web (was ops)\n"+
+		"set tank/www compression=lz4 (was off)\n"+
+		"inherit tank/www atime (was off)\n"+
+		"destroy tank/old\n",
+		plan.String(),
+	)
+
+	create := plan.Changes[3].CreateOptions
+	assert.Equal(t, &zfs.CreateDatasetOptions{
+		Name:          "tank/vm/disk0",
+		CreateParents: true,
+		VolumeSize:    "1G",
+	}, create)
+	assert.False(t, plan.Changes[1].CreateOptions.CreateParents)
+	assert.False(t, plan.Changes[2].CreateOptions.CreateParents)
+
+	require.NoError(t, Apply(ctx, m, plan))
+
+	plan, err = Diff(ctx, m, &State{
+		Pools: []*Pool{
+			{Name: "tank", Properties: map[string]string{"comment": "main"}},
+		},
+		Datasets: []*Dataset{
+			{
+				Name: "tank/www",
+				Properties: map[string]string{
+					"quota":       "10G",
+					"compression": "lz4",
+					"acme:owner":  "web",
+				},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+}
+
+func TestDiff_Destroy(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, "tank/a", "tank/a/b", "tank/a/b/c", "tank/d")
+	state := &State{Datasets: []*Dataset{{Name: "tank/a/b"}}}
+
+	plan, err := Diff(ctx, m, state, nil)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty())
+
+	plan, err = Diff(ctx, m, state, &Options{
+		Destroy:      true,
+		DestroyFlags: []zfs.DestroyDatasetFlag{zfs.DestroyRecursive},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "destroy tank/d\ndestroy tank/a/b/c\n", plan.String())
+	assert.Equal(t,
+		[]zfs.DestroyDatasetFlag{zfs.DestroyRecursive},
+		plan.Changes[0].DestroyFlags,
+	)
+
+	require.NoError(t, Apply(ctx, m, plan))
+
+	names, err := m.ListDatasetNames(ctx, "tank", 0, zfs.FilesystemType)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tank", "tank/a", "tank/a/b"}, names)
+}
+
+func TestDiff_notInheritable(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, "tank/www")
+	err := m.SetDatasetProperties(ctx, "tank/www", map[string]string{
+		"canmount": "noauto",
+		"quota":    "10G",
+		"refquota": "5G",
+		"atime":    "off",
+	})
+	require.NoError(t, err)
+
+	state := &State{Datasets: []*Dataset{{Name: "tank/www"}}}
+
+	plan, err := Diff(ctx, m, state, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"set tank/www quota=none (was 10737418240)\n"+
+		"set tank/www refquota=none (was 5368709120)\n"+
+		"inherit tank/www atime (was off)\n",
+		plan.String(),
+	)
+
+	require.NoError(t, Apply(ctx, m, plan))
+
+	plan, err = Diff(ctx, m, state, nil)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+
+	v, err := m.GetDatasetProperty(ctx, "tank/www", "canmount")
+	require.NoError(t, err)
+	assert.Equal(t, "noauto", v)
+}
+
+func TestDiff_aliases(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+	state := &State{
+		Datasets: []*Dataset{
+			{
+				Name: "tank/www",
+				Properties: map[string]string{
+					"compress": "lz4",
+					"recsize":  "64K",
+				},
+			},
+		},
+	}
+
+	plan, err := Reconcile(ctx, m, state, &Options{
+		IgnoreProperties: []string{"rdonly"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "create tank/www\n", plan.String())
+	assert.Equal(t,
+		map[string]string{"compression": "lz4", "recordsize": "64K"},
+		plan.Changes[0].CreateOptions.Properties,
+	)
+
+	plan, err = Diff(ctx, m, state, nil)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+
+	err = m.SetDatasetProperty(ctx, "tank/www", "compression", "off")
+	require.NoError(t, err)
+
+	plan, err = Diff(ctx, m, state, nil)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"set tank/www compression=lz4 (was off)\n", plan.String(),
+	)
+}
+
+func TestDiff_Errors(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, "tank/fs")
+
+	tests := []struct {
+		name    string
+		state   *State
+		wantErr error
+	}{
+		{
+			name:    "nil state",
+			wantErr: ErrInvalidState,
+		},
+		{
+			name:    "invalid dataset name",
+			state:   &State{Datasets: []*Dataset{{Name: "tank/fs/"}}},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "duplicate dataset",
+			state: &State{
+				Datasets: []*Dataset{{Name: "tank/a"}, {Name: "tank/a"}},
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "duplicate dataset property",
+			state: &State{
+				Datasets: []*Dataset{
+					{
+						Name: "tank/a",
+						Properties: map[string]string{
+							"compress":    "lz4",
+							"compression": "off",
+						},
+					},
+				},
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "duplicate pool property",
+			state: &State{
+				Pools: []*Pool{
+					{
+						Name: "tank",
+						Properties: map[string]string{
+							"autotrim": "on",
+							"trim":     "off",
+						},
+					},
+				},
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "volume without size",
+			state: &State{
+				Datasets: []*Dataset{{Name: "tank/v", Type: zfs.VolumeType}},
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "snapshot type",
+			state: &State{
+				Datasets: []*Dataset{
+					{Name: "tank/fs", Type: zfs.SnapshotType},
+				},
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "type mismatch",
+			state: &State{
+				Datasets: []*Dataset{
+					{Name: "tank/fs", Type: zfs.VolumeType, VolumeSize: "1G"},
+				},
+			},
+			wantErr: ErrTypeMismatch,
+		},
+		{
+			name:    "missing pool",
+			state:   &State{Datasets: []*Dataset{{Name: "other/fs"}}},
+			wantErr: zfs.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Diff(ctx, m, tt.state, nil)
+
+			assert.Nil(t, plan)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+}
+
+func TestApply_Error(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	err := Apply(ctx, m, &Plan{Changes: []*Change{
+		{
+			Action:   SetProperty,
+			Name:     "tank/missing",
+			Property: "atime",
+			Value:    "off",
+		},
+	}})
+
+	assert.True(t, errors.Is(err, zfs.ErrNotFound))
+	assert.Contains(t, err.Error(), "set tank/missing atime=off: ")
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	plan, err := Reconcile(ctx, m, &State{
+		Datasets: []*Dataset{
+			{Name: "tank/a", Properties: map[string]string{"quota": "1G"}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "create tank/a\n", plan.String())
+
+	v, err := m.GetDatasetProperty(ctx, "tank/a", "quota")
+	require.NoError(t, err)
+	assert.Equal(t, "1073741824", v)
+}
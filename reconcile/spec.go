@@ -0,0 +1,471 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/krystal/go-zfs"
+	"github.com/krystal/go-zfs/zfsprops"
+	"github.com/krystal/go-zfs/zpoolprops"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidSpec is returned when a Spec cannot be decoded, or is invalid.
+var ErrInvalidSpec = errors.New("invalid spec")
+
+// Spec describes a storage layout of pools and datasets. It is typically
+// decoded from a YAML or JSON document with ParseSpec, for example:
+//
+//	pools:
+//	  - name: tank
+//	    vdevs: [mirror, /dev/sdb, /dev/sdc]
+//	    properties:
+//	      ashift: 12
+//	    filesystem_properties:
+//	      compression: lz4
+//	datasets:
+//	  - name: tank/www
+//	    properties:
+//	      quota: 100G
+//	    children:
+//	      - name: cache
+//	        properties:
+//	          sync: disabled
+//	  - name: tank/vm/disk0
+//	    type: volume
+//	    volume_size: 10G
+//
+// Use State to get the desired state for Diff and Reconcile, and
+// PoolSpec.CreatePoolOptions to create pools which do not exist yet.
+type Spec struct {
+	// Pools are the pools of the layout.
+	Pools []*PoolSpec `yaml:"pools,omitempty"`
+
+	// Datasets are the top-level datasets of the layout, with full names
+	// including the pool name.
+	Datasets []*DatasetSpec `yaml:"datasets,omitempty"`
+}
+
+// PoolSpec describes a pool.
+type PoolSpec struct {
+	// Name of the pool. (required)
+	Name string `yaml:"name"`
+
+	// Vdevs is the vdev layout used to create the pool, as passed to zpool
+	// create, for example: ["mirror", "/dev/sdb", "/dev/sdc"].
+	Vdevs []string `yaml:"vdevs,omitempty"`
+
+	// Properties are the pool properties.
+	Properties map[string]string `yaml:"properties,omitempty"`
+
+	// FilesystemProperties are properties of the pool's root dataset.
+	FilesystemProperties map[string]string `yaml:"filesystem_properties,omitempty"` //nolint:lll
+
+	// Mountpoint is the mountpoint of the pool's root dataset.
+	Mountpoint string `yaml:"mountpoint,omitempty"`
+
+	node *yaml.Node
+}
+
+// DatasetSpec describes a filesystem or volume, and its children.
+type DatasetSpec struct {
+	// Name of the dataset. Top-level datasets require a full name including
+	// the pool name, while children are named relative to their parent.
+	Name string `yaml:"name"`
+
+	// Type of the dataset, either "filesystem" or "volume". Defaults to
+	// "filesystem" when empty.
+	Type zfs.DatasetType `yaml:"type,omitempty"`
+
+	// VolumeSize is the size of the volume. Required for volumes.
+	VolumeSize string `yaml:"volume_size,omitempty"`
+
+	// Properties are the properties of the dataset.
+	Properties map[string]string `yaml:"properties,omitempty"`
+
+	// Children are the child datasets.
+	Children []*DatasetSpec `yaml:"children,omitempty"`
+
+	node *yaml.Node
+}
+
+// SpecError is a validation error of a Spec, referencing the line and column
+// of the offending value in the decoded document. Line and Column are zero
+// for specs which were not decoded from a document.
+type SpecError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error returns the message, prefixed with the line and column if known.
+func (e *SpecError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Unwrap returns ErrInvalidSpec.
+func (e *SpecError) Unwrap() error {
+	return ErrInvalidSpec
+}
+
+// DecodeSpec reads a YAML or JSON document from r, and parses it with
+// ParseSpec.
+func DecodeSpec(r io.Reader) (*Spec, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSpec(data)
+}
+
+// ParseSpec parses and validates a YAML or JSON document describing a Spec.
+//
+// All validation errors are returned combined, each as a *SpecError
+// referencing the offending line. Use multierr.Errors to get the individual
+// errors. The returned error satisfies errors.Is for ErrInvalidSpec.
+func ParseSpec(data []byte) (*Spec, error) {
+	spec := &Spec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		var specErr *SpecError
+		if errors.As(err, &specErr) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSpec, err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// UnmarshalYAML decodes the spec from value, rejecting unknown fields.
+func (s *Spec) UnmarshalYAML(value *yaml.Node) error {
+	if err := checkFields(value, []string{"pools", "datasets"}); err != nil {
+		return err
+	}
+
+	type plain Spec
+
+	return value.Decode((*plain)(s))
+}
+
+// UnmarshalYAML decodes the pool from value, rejecting unknown fields.
+func (p *PoolSpec) UnmarshalYAML(value *yaml.Node) error {
+	if err := checkFields(value, []string{
+		"name", "vdevs", "properties", "filesystem_properties", "mountpoint",
+	}); err != nil {
+		return err
+	}
+
+	type plain PoolSpec
+	if err := value.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	p.node = value
+
+	return nil
+}
+
+// UnmarshalYAML decodes the dataset from value, rejecting unknown fields.
+func (d *DatasetSpec) UnmarshalYAML(value *yaml.Node) error {
+	if err := checkFields(value, []string{
+		"name", "type", "volume_size", "properties", "children",
+	}); err != nil {
+		return err
+	}
+
+	type plain DatasetSpec
+	if err := value.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	d.node = value
+
+	return nil
+}
+
+// checkFields returns a *SpecError if mapping node has a key not in fields.
+func checkFields(node *yaml.Node, fields []string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		found := false
+		for _, f := range fields {
+			if key.Value == f {
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			return specError(key, "unknown field '%s'", key.Value)
+		}
+	}
+
+	return nil
+}
+
+func specError(node *yaml.Node, format string, args ...interface{}) error {
+	err := &SpecError{Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		err.Line = node.Line
+		err.Column = node.Column
+	}
+
+	return err
+}
+
+// field returns the value node of key in mapping node, falling back to node
+// itself when not found.
+func field(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return node
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return node
+}
+
+// propertyKey returns the key node of property within the mapping node of
+// field, falling back to the field node when not found.
+func propertyKey(node *yaml.Node, fieldName, property string) *yaml.Node {
+	props := field(node, fieldName)
+	if props == nil || props.Kind != yaml.MappingNode {
+		return props
+	}
+
+	for i := 0; i+1 < len(props.Content); i += 2 {
+		if props.Content[i].Value == property {
+			return props.Content[i]
+		}
+	}
+
+	return props
+}
+
+// Validate validates the spec, returning all errors combined. See ParseSpec
+// for details.
+func (s *Spec) Validate() error {
+	var errs error
+
+	pools := map[string]bool{}
+	for _, p := range s.Pools {
+		if p == nil {
+			continue
+		}
+		errs = multierr.Append(errs, p.validate())
+		if p.Name != "" && pools[p.Name] {
+			errs = multierr.Append(errs, specError(
+				field(p.node, "name"), "duplicate pool '%s'", p.Name,
+			))
+		}
+		pools[p.Name] = true
+	}
+
+	names := map[string]bool{}
+	for _, d := range s.Datasets {
+		if d == nil {
+			continue
+		}
+		errs = multierr.Append(errs, d.validate("", names))
+	}
+
+	return errs
+}
+
+func (p *PoolSpec) validate() error {
+	var errs error
+
+	if p.Name == "" || strings.ContainsAny(p.Name, "/@# ") {
+		errs = multierr.Append(errs, specError(
+			field(p.node, "name"), "invalid pool name '%s'", p.Name,
+		))
+	}
+
+	for _, name := range sortedKeys(p.Properties) {
+		if !zpoolprops.Valid(name) {
+			errs = multierr.Append(errs, specError(
+				propertyKey(p.node, "properties", name),
+				"unknown pool property '%s'", name,
+			))
+		}
+	}
+
+	for _, name := range sortedKeys(p.FilesystemProperties) {
+		if !zfsprops.Valid(name) {
+			errs = multierr.Append(errs, specError(
+				propertyKey(p.node, "filesystem_properties", name),
+				"unknown filesystem property '%s'", name,
+			))
+		}
+	}
+
+	return errs
+}
+
+// validate validates the dataset and its children, where parent is the full
+// name of the parent dataset, or empty for top-level datasets. Full names are
+// recorded in names to detect duplicates.
+func (d *DatasetSpec) validate(parent string, names map[string]bool) error {
+	var errs error
+
+	nameNode := field(d.node, "name")
+	full := d.fullName(parent)
+	switch {
+	case d.Name == "" || strings.HasPrefix(d.Name, "/") ||
+		strings.HasSuffix(d.Name, "/") || strings.Contains(d.Name, "//") ||
+		strings.ContainsAny(d.Name, "@# "):
+		errs = multierr.Append(errs, specError(
+			nameNode, "invalid dataset name '%s'", d.Name,
+		))
+	case names[full]:
+		errs = multierr.Append(errs, specError(
+			nameNode, "duplicate dataset '%s'", full,
+		))
+	}
+	names[full] = true
+
+	typeNode := field(d.node, "type")
+	switch d.Type {
+	case "", zfs.FilesystemType:
+		if d.VolumeSize != "" {
+			errs = multierr.Append(errs, specError(
+				field(d.node, "volume_size"),
+				"volume_size is only valid for volumes",
+			))
+		}
+	case zfs.VolumeType:
+		if d.VolumeSize == "" {
+			errs = multierr.Append(errs, specError(
+				typeNode, "volume '%s' requires volume_size", full,
+			))
+		}
+		if len(d.Children) > 0 {
+			errs = multierr.Append(errs, specError(
+				field(d.node, "children"), "volumes cannot have children",
+			))
+		}
+	case zfs.AllTypes, zfs.BookmarkType, zfs.SnapshotType:
+		fallthrough
+	default:
+		errs = multierr.Append(errs, specError(
+			typeNode, "invalid dataset type '%s'", d.Type,
+		))
+	}
+
+	for _, name := range sortedKeys(d.Properties) {
+		if !zfsprops.Valid(name) {
+			errs = multierr.Append(errs, specError(
+				propertyKey(d.node, "properties", name),
+				"unknown property '%s'", name,
+			))
+		}
+	}
+
+	for _, c := range d.Children {
+		if c != nil {
+			errs = multierr.Append(errs, c.validate(full, names))
+		}
+	}
+
+	return errs
+}
+
+func (d *DatasetSpec) fullName(parent string) string {
+	if parent == "" {
+		return d.Name
+	}
+
+	return parent + "/" + d.Name
+}
+
+// CreatePoolOptions returns options to create the pool with
+// zfs.Manager.CreatePool.
+func (p *PoolSpec) CreatePoolOptions() *zfs.CreatePoolOptions {
+	return &zfs.CreatePoolOptions{
+		Name:                 p.Name,
+		Properties:           p.Properties,
+		FilesystemProperties: p.FilesystemProperties,
+		Mountpoint:           p.Mountpoint,
+		Vdevs:                p.Vdevs,
+	}
+}
+
+// State returns the desired state described by the spec, for use with Diff
+// and Reconcile. Dataset hierarchies are flattened, and the properties of
+// pool root datasets are taken from FilesystemProperties and Mountpoint,
+// unless the root dataset is also listed as a dataset.
+func (s *Spec) State() *State {
+	state := &State{}
+	for _, p := range s.Pools {
+		if p == nil {
+			continue
+		}
+		state.Pools = append(state.Pools, &Pool{
+			Name:       p.Name,
+			Properties: p.Properties,
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, d := range s.Datasets {
+		if d != nil {
+			state.Datasets = d.flatten("", state.Datasets)
+		}
+	}
+	for _, ds := range state.Datasets {
+		seen[ds.Name] = true
+	}
+
+	roots := []*Dataset{}
+	for _, p := range s.Pools {
+		if p == nil {
+			continue
+		}
+		props := p.FilesystemProperties
+		if p.Mountpoint != "" {
+			props = withProperty(props, zfsprops.Mountpoint, p.Mountpoint)
+		}
+		if len(props) > 0 && !seen[p.Name] {
+			roots = append(roots, &Dataset{Name: p.Name, Properties: props})
+		}
+	}
+	state.Datasets = append(roots, state.Datasets...)
+
+	return state
+}
+
+func (d *DatasetSpec) flatten(parent string, datasets []*Dataset) []*Dataset {
+	name := d.fullName(parent)
+	datasets = append(datasets, &Dataset{
+		Name:       name,
+		Type:       d.Type,
+		VolumeSize: d.VolumeSize,
+		Properties: d.Properties,
+	})
+	for _, c := range d.Children {
+		if c != nil {
+			datasets = c.flatten(name, datasets)
+		}
+	}
+
+	return datasets
+}
@@ -0,0 +1,235 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/krystal/go-zfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+)
+
+const testSpecYAML = `pools:
+  - name: tank
+    vdevs: [mirror, /dev/sdb, /dev/sdc]
+    mountpoint: /srv
+    properties:
+      ashift: 12
+      comment: main
+    filesystem_properties:
+      compression: lz4
+datasets:
+  - name: tank/www
+    properties:
+      quota: 100G
+      acme:owner: web
+    children:
+      - name: cache
+        properties:
+          sync: disabled
+      - name: logs
+  - name: tank/vm/disk0
+    type: volume
+    volume_size: 10G
+`
+
+const testSpecJSON = `{
+  "pools": [
+    {
+      "name": "tank",
+      "vdevs": ["mirror", "/dev/sdb", "/dev/sdc"],
+      "mountpoint": "/srv",
+      "properties": {"ashift": "12", "comment": "main"},
+      "filesystem_properties": {"compression": "lz4"}
+    }
+  ],
+  "datasets": [
+    {
+      "name": "tank/www",
+      "properties": {"quota": "100G", "acme:owner": "web"},
+      "children": [
+        {"name": "cache", "properties": {"sync": "disabled"}},
+        {"name": "logs"}
+      ]
+    },
+    {"name": "tank/vm/disk0", "type": "volume", "volume_size": "10G"}
+  ]
+}`
+
+func TestParseSpec(t *testing.T) {
+	for name, doc := range map[string]string{
+		"yaml": testSpecYAML,
+		"json": testSpecJSON,
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec, err := DecodeSpec(strings.NewReader(doc))
+			require.NoError(t, err)
+
+			require.Len(t, spec.Pools, 1)
+			assert.Equal(t, &zfs.CreatePoolOptions{
+				Name: "tank",
+				Properties: map[string]string{
+					"ashift":  "12",
+					"comment": "main",
+				},
+				FilesystemProperties: map[string]string{"compression": "lz4"},
+				Mountpoint:           "/srv",
+				Vdevs: []string{
+					"mirror", "/dev/sdb", "/dev/sdc",
+				},
+			}, spec.Pools[0].CreatePoolOptions())
+
+			assert.Equal(t, &State{
+				Pools: []*Pool{
+					{
+						Name: "tank",
+						Properties: map[string]string{
+							"ashift":  "12",
+							"comment": "main",
+						},
+					},
+				},
+				Datasets: []*Dataset{
+					{
+						Name: "tank",
+						Properties: map[string]string{
+							"compression": "lz4",
+							"mountpoint":  "/srv",
+						},
+					},
+					{
+						Name: "tank/www",
+						Properties: map[string]string{
+							"quota":      "100G",
+							"acme:owner": "web",
+						},
+					},
+					{
+						Name:       "tank/www/cache",
+						Properties: map[string]string{"sync": "disabled"},
+					},
+					{Name: "tank/www/logs"},
+					{
+						Name:       "tank/vm/disk0",
+						Type:       zfs.VolumeType,
+						VolumeSize: "10G",
+					},
+				},
+			}, spec.State())
+		})
+	}
+}
+
+func TestParseSpec_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		wantErrs []string
+	}{
+		{
+			name:     "syntax error",
+			doc:      "pools: [",
+			wantErrs: []string{"invalid spec: yaml: line 1: "},
+		},
+		{
+			name: "type error",
+			doc:  "pools:\n  - name: tank\n    properties: [a]\n",
+			wantErrs: []string{
+				"invalid spec: yaml: unmarshal errors:\n  line 3: ",
+			},
+		},
+		{
+			name:     "unknown top-level field",
+			doc:      "pool:\n  - name: tank\n",
+			wantErrs: []string{"line 1, column 1: unknown field 'pool'"},
+		},
+		{
+			name: "unknown dataset field",
+			doc:  "datasets:\n  - name: tank/a\n    propreties: {}\n",
+			wantErrs: []string{
+				"line 3, column 5: unknown field 'propreties'",
+			},
+		},
+		{
+			name: "invalid values",
+			doc: `pools:
+  - name: tank
+    properties:
+      ashfit: 12
+    filesystem_properties:
+      compresion: lz4
+  - name: tank
+datasets:
+  - name: tank/a
+    properties:
+      quota: 1G
+      recsz: 1M
+    children:
+      - name: b/
+      - name: vol
+        type: volume
+  - name: tank/a
+  - name: tank/b
+    type: snapshot
+  - name: tank/c
+    volume_size: 1G
+`,
+			wantErrs: []string{
+				"line 4, column 7: unknown pool property 'ashfit'",
+				"line 6, column 7: unknown filesystem property 'compresion'",
+				"line 7, column 11: duplicate pool 'tank'",
+				"line 12, column 7: unknown property 'recsz'",
+				"line 14, column 15: invalid dataset name 'b/'",
+				"line 16, column 15: volume 'tank/a/vol' requires volume_size",
+				"line 17, column 11: duplicate dataset 'tank/a'",
+				"line 19, column 11: invalid dataset type 'snapshot'",
+				"line 21, column 18: volume_size is only valid for volumes",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec([]byte(tt.doc))
+
+			assert.Nil(t, spec)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidSpec))
+
+			errs := multierr.Errors(err)
+			require.Len(t, errs, len(tt.wantErrs), err.Error())
+			for i, want := range tt.wantErrs {
+				assert.True(t,
+					strings.HasPrefix(errs[i].Error(), want),
+					"%q does not start with %q", errs[i].Error(), want,
+				)
+			}
+		})
+	}
+}
+
+func TestSpec_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	spec, err := ParseSpec([]byte(`datasets:
+  - name: tank/www
+    properties:
+      quota: 10G
+    children:
+      - name: cache
+        properties:
+          atime: "off"
+`))
+	require.NoError(t, err)
+
+	plan, err := Reconcile(ctx, m, spec.State(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "create tank/www\ncreate tank/www/cache\n", plan.String())
+
+	plan, err = Diff(ctx, m, spec.State(), nil)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+}
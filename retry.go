@@ -0,0 +1,157 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// RetryPolicy controls if and how failed zfs and zpool commands are retried.
+//
+// Commands are only retried if they fail with an error matching one of the
+// RetryOn errors, as determined by errors.Is. This is useful for transient
+// failures like "dataset is busy", which typically resolve themselves within
+// a second or two.
+//
+// Commands which are given stdin input are only retried if the input
+// implements io.Seeker, so it can be rewound before each retry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a command is run, including
+	// the initial attempt. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps how long to wait between retries. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff is multiplied by after each retry.
+	// Values less than 1 are treated as 1, resulting in a constant backoff.
+	Multiplier float64
+
+	// RetryOn is a list of errors which are considered retryable. If empty,
+	// only ErrBusy is considered retryable.
+	RetryOn []error
+}
+
+// DefaultRetryPolicy returns a RetryPolicy which retries busy errors up to 5
+// times, with exponential backoff starting at 250ms, capped at 2 seconds.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		RetryOn:        []error{ErrBusy},
+	}
+}
+
+// Retryable reports whether err is considered retryable by the policy.
+func (p *RetryPolicy) Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	retryOn := p.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = []error{ErrBusy}
+	}
+
+	for _, target := range retryOn {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Backoff returns how long to wait before the given retry, where 1 is the
+// first retry.
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+
+	d := time.Duration(backoff)
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return d
+}
+
+type retryPolicyCtxKey struct{}
+
+// WithRetryPolicy returns a copy of ctx which overrides the Manager's
+// RetryPolicy for all commands run with the returned context. A nil policy
+// disables retries.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyCtxKey{}, policy)
+}
+
+// retryPolicy returns the RetryPolicy from ctx if present, otherwise the
+// Manager's RetryPolicy.
+func (m *Manager) retryPolicy(ctx context.Context) *RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyCtxKey{}).(*RetryPolicy); ok {
+		return p
+	}
+
+	return m.RetryPolicy
+}
+
+// retry calls fn until it succeeds, returns a non-retryable error, or the
+// retry policy's attempts are exhausted. It returns early if ctx is done while
+// waiting between attempts.
+func (m *Manager) retry(
+	ctx context.Context,
+	stdin io.Reader,
+	fn func() ([][]string, error),
+) ([][]string, error) {
+	policy := m.retryPolicy(ctx)
+	seeker, seekable := stdin.(io.Seeker)
+	if policy == nil || policy.MaxAttempts < 2 || (stdin != nil && !seekable) {
+		return fn()
+	}
+
+	var records [][]string
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(policy.Backoff(attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+
+				return nil, multierr.Append(err, ctx.Err())
+			case <-timer.C:
+			}
+
+			if seekable {
+				if _, serr := seeker.Seek(0, io.SeekStart); serr != nil {
+					return nil, multierr.Append(err, serr)
+				}
+			}
+		}
+
+		records, err = fn()
+		if !policy.Retryable(err) {
+			return records, err
+		}
+	}
+
+	return records, err
+}
@@ -0,0 +1,425 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	assert.Equal(t, &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		RetryOn:        []error{ErrBusy},
+	}, p)
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	busyErr := &CommandError{
+		Err:  errors.New("exit status 1"),
+		errs: []error{ErrZFS, ErrBusy},
+	}
+	notFoundErr := &CommandError{
+		Err:  errors.New("exit status 1"),
+		errs: []error{ErrZFS, ErrNotFound},
+	}
+
+	tests := []struct {
+		name    string
+		retryOn []error
+		err     error
+		want    bool
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+			want: false,
+		},
+		{
+			name: "default busy",
+			err:  busyErr,
+			want: true,
+		},
+		{
+			name: "default not found",
+			err:  notFoundErr,
+			want: false,
+		},
+		{
+			name:    "custom matching",
+			retryOn: []error{ErrNotFound},
+			err:     notFoundErr,
+			want:    true,
+		},
+		{
+			name:    "custom not matching",
+			retryOn: []error{ErrNotFound},
+			err:     busyErr,
+			want:    false,
+		},
+		{
+			name:    "multiple",
+			retryOn: []error{ErrNoSpace, ErrBusy},
+			err:     busyErr,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &RetryPolicy{RetryOn: tt.retryOn}
+
+			got := p.Retryable(tt.err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "zero",
+			policy: &RetryPolicy{},
+			want:   []time.Duration{0, 0, 0},
+		},
+		{
+			name: "constant",
+			policy: &RetryPolicy{
+				InitialBackoff: 100 * time.Millisecond,
+			},
+			want: []time.Duration{
+				100 * time.Millisecond,
+				100 * time.Millisecond,
+				100 * time.Millisecond,
+			},
+		},
+		{
+			name: "exponential",
+			policy: &RetryPolicy{
+				InitialBackoff: 100 * time.Millisecond,
+				Multiplier:     2,
+			},
+			want: []time.Duration{
+				100 * time.Millisecond,
+				200 * time.Millisecond,
+				400 * time.Millisecond,
+				800 * time.Millisecond,
+			},
+		},
+		{
+			name: "exponential with max",
+			policy: &RetryPolicy{
+				InitialBackoff: 100 * time.Millisecond,
+				MaxBackoff:     300 * time.Millisecond,
+				Multiplier:     2,
+			},
+			want: []time.Duration{
+				100 * time.Millisecond,
+				200 * time.Millisecond,
+				300 * time.Millisecond,
+				300 * time.Millisecond,
+			},
+		},
+		{
+			name: "initial above max",
+			policy: &RetryPolicy{
+				InitialBackoff: 5 * time.Second,
+				MaxBackoff:     time.Second,
+			},
+			want: []time.Duration{time.Second, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]time.Duration, len(tt.want))
+			for i := range got {
+				got[i] = tt.policy.Backoff(i + 1)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_retry(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+	busy := "cannot destroy 'tank/data': dataset is busy\n"
+	notFound := "cannot open 'tank/data': dataset does not exist\n"
+	policy := &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+
+	tests := []struct {
+		name           string
+		policy         *RetryPolicy
+		ctxPolicy      *RetryPolicy
+		setCtxPolicy   bool
+		stderrs        []string
+		wantCalls      int
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:      "no policy",
+			stderrs:   []string{busy},
+			wantCalls: 1,
+			wantErr: "zfs; busy; exit status 1: " +
+				"cannot destroy 'tank/data': dataset is busy",
+			wantErrTargets: []error{ErrZFS, ErrBusy},
+		},
+		{
+			name:      "success after retries",
+			policy:    policy,
+			stderrs:   []string{busy, busy, ""},
+			wantCalls: 3,
+		},
+		{
+			name:      "attempts exhausted",
+			policy:    policy,
+			stderrs:   []string{busy, busy, busy},
+			wantCalls: 3,
+			wantErr: "zfs; busy; exit status 1: " +
+				"cannot destroy 'tank/data': dataset is busy",
+			wantErrTargets: []error{ErrZFS, ErrBusy},
+		},
+		{
+			name:      "non-retryable error",
+			policy:    policy,
+			stderrs:   []string{busy, notFound},
+			wantCalls: 2,
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/data': dataset does not exist",
+			wantErrTargets: []error{ErrZFS, ErrNotFound},
+		},
+		{
+			name:         "context policy overrides manager policy",
+			policy:       policy,
+			setCtxPolicy: true,
+			ctxPolicy: &RetryPolicy{
+				MaxAttempts: 2,
+				RetryOn:     []error{ErrBusy},
+			},
+			stderrs:   []string{busy, busy},
+			wantCalls: 2,
+			wantErr: "zfs; busy; exit status 1: " +
+				"cannot destroy 'tank/data': dataset is busy",
+			wantErrTargets: []error{ErrZFS, ErrBusy},
+		},
+		{
+			name:         "context policy enables retries",
+			setCtxPolicy: true,
+			ctxPolicy:    policy,
+			stderrs:      []string{busy, ""},
+			wantCalls:    2,
+		},
+		{
+			name:         "nil context policy disables retries",
+			policy:       policy,
+			setCtxPolicy: true,
+			ctxPolicy:    nil,
+			stderrs:      []string{busy},
+			wantCalls:    1,
+			wantErr: "zfs; busy; exit status 1: " +
+				"cannot destroy 'tank/data': dataset is busy",
+			wantErrTargets: []error{ErrZFS, ErrBusy},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.setCtxPolicy {
+				ctx = WithRetryPolicy(ctx, tt.ctxPolicy)
+			}
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+
+			calls := 0
+			r.EXPECT().RunContext(
+				gomock.Any(),
+				gomock.Nil(),
+				gomock.AssignableToTypeOf(ioWriter),
+				gomock.AssignableToTypeOf(ioWriter),
+				"zfs",
+				[]string{"destroy", "tank/data"},
+			).DoAndReturn(func(
+				_ context.Context,
+				_ io.Reader,
+				_ io.Writer,
+				stderr io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				s := tt.stderrs[calls]
+				calls++
+				if s == "" {
+					return nil
+				}
+				_, _ = stderr.Write([]byte(s))
+
+				return errors.New("exit status 1")
+			}).Times(tt.wantCalls)
+
+			m := &Manager{Runner: r, RetryPolicy: tt.policy}
+
+			err := m.DestroyDataset(ctx, "tank/data")
+
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_retry_contextCancel(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomock.Any(),
+		gomock.Nil(),
+		gomock.AssignableToTypeOf(ioWriter),
+		gomock.AssignableToTypeOf(ioWriter),
+		"zpool",
+		[]string{"export", "tank"},
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		_ io.Writer,
+		stderr io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = stderr.Write([]byte("cannot export 'tank': pool is busy\n"))
+		cancel()
+
+		return errors.New("exit status 1")
+	}).Times(1)
+
+	m := &Manager{
+		Runner: r,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Hour,
+		},
+	}
+
+	err := m.ExportPool(ctx, "tank", false)
+
+	assert.EqualError(t, err,
+		"zpool; busy; exit status 1: cannot export 'tank': pool is busy; "+
+			"context canceled",
+	)
+	assert.ErrorIs(t, err, ErrZpool)
+	assert.ErrorIs(t, err, ErrBusy)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestManager_retry_stdin(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+	policy := &RetryPolicy{MaxAttempts: 3}
+
+	tests := []struct {
+		name      string
+		key       func() io.Reader
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name: "seekable stdin is rewound and retried",
+			key: func() io.Reader {
+				return strings.NewReader("hunter22")
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "non-seekable stdin is not retried",
+			key: func() io.Reader {
+				return io.MultiReader(bytes.NewBufferString("hunter22"))
+			},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+
+			calls := 0
+			r.EXPECT().RunContext(
+				gomock.Any(),
+				gomock.Not(gomock.Nil()),
+				gomock.AssignableToTypeOf(ioWriter),
+				gomock.AssignableToTypeOf(ioWriter),
+				"zfs",
+				[]string{"load-key", "-L", "prompt", "tank/secret"},
+			).DoAndReturn(func(
+				_ context.Context,
+				stdin io.Reader,
+				_ io.Writer,
+				stderr io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				calls++
+				b, err := io.ReadAll(stdin)
+				require.NoError(t, err)
+				assert.Equal(t, "hunter22", string(b))
+				if calls > 1 {
+					return nil
+				}
+				_, _ = stderr.Write([]byte(
+					"cannot load key for 'tank/secret': dataset is busy\n",
+				))
+
+				return errors.New("exit status 255")
+			}).Times(tt.wantCalls)
+
+			m := &Manager{Runner: r, RetryPolicy: policy}
+
+			err := m.LoadKey(context.Background(), &LoadKeyOptions{
+				Name: "tank/secret",
+				Key:  tt.key(),
+			})
+
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrBusy)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package zfs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortKey is a property to sort datasets by, equivalent to the -s and -S flags
+// of zfs list.
+type SortKey struct {
+	// Property to sort by. The special value "name" sorts by dataset name.
+	Property string
+
+	// Descending sorts in descending order when true, equivalent to -S.
+	Descending bool
+}
+
+// validateSortKeys returns an error if any of keys has an invalid property.
+func validateSortKeys(keys []SortKey) error {
+	for _, k := range keys {
+		if k.Property == "" || k.Property == allProperty ||
+			strings.Contains(k.Property, ",") {
+			return fmt.Errorf(
+				"%w: invalid sort property '%s'",
+				ErrInvalidProperty, k.Property,
+			)
+		}
+	}
+
+	return nil
+}
+
+// withSortProperties returns properties with the properties of keys appended,
+// unless they are already present.
+func withSortProperties(properties []string, keys []SortKey) []string {
+	r := append([]string{}, properties...)
+	for _, k := range keys {
+		if k.Property != "name" && !containsString(r, k.Property) {
+			r = append(r, k.Property)
+		}
+	}
+
+	return r
+}
+
+// sortFlags returns the -s and -S flags of zfs list for keys.
+func sortFlags(keys []SortKey) []string {
+	flags := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		if k.Descending {
+			flags = append(flags, "-S", k.Property)
+		} else {
+			flags = append(flags, "-s", k.Property)
+		}
+	}
+
+	return flags
+}
+
+func containsString(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package zfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func datasetNames(datasets []*Dataset) []string {
+	names := make([]string, 0, len(datasets))
+	for _, ds := range datasets {
+		names = append(names, ds.Name)
+	}
+
+	return names
+}
+
+func Test_validateSortKeys(t *testing.T) {
+	assert.NoError(t, validateSortKeys(nil))
+	assert.NoError(t, validateSortKeys([]SortKey{{Property: "used"}}))
+
+	for _, p := range []string{"", "all", "used,creation"} {
+		err := validateSortKeys([]SortKey{{Property: p}})
+		assert.ErrorIs(t, err, ErrInvalidProperty)
+	}
+}
+
+func Test_withSortProperties(t *testing.T) {
+	keys := []SortKey{{Property: "name"}, {Property: "used"}}
+
+	assert.Equal(t, []string{"used"}, withSortProperties(nil, keys))
+	assert.Equal(t,
+		[]string{"quota", "used"},
+		withSortProperties([]string{"quota"}, keys),
+	)
+	assert.Equal(t,
+		[]string{"used", "quota"},
+		withSortProperties([]string{"used", "quota"}, keys),
+	)
+}
+
+func Test_sortFlags(t *testing.T) {
+	assert.Empty(t, sortFlags(nil))
+	assert.Equal(t,
+		[]string{"-S", "used", "-s", "name"},
+		sortFlags([]SortKey{
+			{Property: "used", Descending: true},
+			{Property: "name"},
+		}),
+	)
+}
@@ -0,0 +1,165 @@
+package zfs
+
+import "strings"
+
+// SourceKind is the kind of source of a property value, as accepted by the -s
+// flag of zfs get.
+type SourceKind string
+
+const (
+	// SourceNone is the source of read-only statistics, which zfs reports as
+	// "-".
+	SourceNone SourceKind = "none"
+
+	// SourceLocal is the source of values set directly on the dataset or
+	// pool.
+	SourceLocal SourceKind = "local"
+
+	// SourceDefault is the source of values which have not been set.
+	SourceDefault SourceKind = "default"
+
+	// SourceInherited is the source of values inherited from an ancestor
+	// dataset.
+	SourceInherited SourceKind = "inherited"
+
+	// SourceReceived is the source of values set by zfs receive.
+	SourceReceived SourceKind = "received"
+
+	// SourceTemporary is the source of values set as temporary mount options
+	// with zfs mount -o.
+	SourceTemporary SourceKind = "temporary"
+)
+
+const inheritedFromPrefix = "inherited from "
+
+// PropertySource is the parsed source of a property value.
+type PropertySource struct {
+	// Kind of the source. Unrecognized sources are returned as is.
+	Kind SourceKind
+
+	// From is the name of the dataset the value is inherited from, when Kind
+	// is SourceInherited.
+	From string
+}
+
+// ParsePropertySource parses a raw property source as reported by zfs and
+// zpool, for example "local", "-", or "inherited from tank/data".
+func ParsePropertySource(source string) PropertySource {
+	switch {
+	case source == "" || source == "-":
+		return PropertySource{Kind: SourceNone}
+	case strings.HasPrefix(source, inheritedFromPrefix):
+		return PropertySource{
+			Kind: SourceInherited,
+			From: strings.TrimPrefix(source, inheritedFromPrefix),
+		}
+	}
+
+	return PropertySource{Kind: SourceKind(source)}
+}
+
+// String returns the source as reported by zfs, for example "local", "-", or
+// "inherited from tank/data".
+func (s PropertySource) String() string {
+	switch s.Kind {
+	case SourceNone:
+		return "-"
+	case SourceInherited:
+		if s.From != "" {
+			return inheritedFromPrefix + s.From
+		}
+	case SourceLocal, SourceDefault, SourceReceived, SourceTemporary:
+	}
+
+	return string(s.Kind)
+}
+
+// PropertySource returns the parsed Source of the property.
+func (p Property) PropertySource() PropertySource {
+	return ParsePropertySource(p.Source)
+}
+
+// Source returns the parsed source of the given property.
+//
+// The second return value indicates if the property is present.
+func (p Properties) Source(property string) (PropertySource, bool) {
+	if prop, ok := p[property]; ok {
+		return prop.PropertySource(), true
+	}
+
+	return PropertySource{}, false
+}
+
+// IsLocal returns true if the given property is present and set locally.
+func (p Properties) IsLocal(property string) bool {
+	s, ok := p.Source(property)
+
+	return ok && s.Kind == SourceLocal
+}
+
+// InheritedFrom returns the name of the dataset the given property is
+// inherited from.
+//
+// The second return value indicates if the property is present and inherited.
+func (p Properties) InheritedFrom(property string) (string, bool) {
+	if s, ok := p.Source(property); ok && s.Kind == SourceInherited {
+		return s.From, true
+	}
+
+	return "", false
+}
+
+// WithSource returns a new Properties map containing only the properties with
+// one of the given source kinds.
+func (p Properties) WithSource(kinds ...SourceKind) Properties {
+	r := Properties{}
+	for name, prop := range p {
+		kind := prop.PropertySource().Kind
+		for _, k := range kinds {
+			if kind == k {
+				r[name] = prop
+
+				break
+			}
+		}
+	}
+
+	return r
+}
+
+// sourceFlag returns the -s flag and its value for zfs get, filtering on the
+// given source kinds. It returns nil if kinds is empty.
+func sourceFlag(kinds []SourceKind) []string {
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	s := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		s = append(s, string(k))
+	}
+
+	return []string{"-s", strings.Join(s, ",")}
+}
+
+// Received returns the received value of the given property.
+//
+// The second return value indicates if the property is present and has a
+// received value. Received values are only available on properties returned by
+// methods which request them, like GetDatasetReceived.
+func (p Properties) Received(property string) (string, bool) {
+	if prop, ok := p[property]; ok &&
+		prop.Received != "" && prop.Received != "-" {
+		return prop.Received, true
+	}
+
+	return "", false
+}
+
+// OverridesReceived returns true if the given property has a received value,
+// and has since been set locally, overriding the received value.
+func (p Properties) OverridesReceived(property string) bool {
+	_, ok := p.Received(property)
+
+	return ok && p.IsLocal(property)
+}
@@ -0,0 +1,103 @@
+package zfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePropertySource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   PropertySource
+	}{
+		{source: "-", want: PropertySource{Kind: SourceNone}},
+		{source: "", want: PropertySource{Kind: SourceNone}},
+		{source: "local", want: PropertySource{Kind: SourceLocal}},
+		{source: "default", want: PropertySource{Kind: SourceDefault}},
+		{source: "received", want: PropertySource{Kind: SourceReceived}},
+		{source: "temporary", want: PropertySource{Kind: SourceTemporary}},
+		{
+			source: "inherited from tank/data",
+			want:   PropertySource{Kind: SourceInherited, From: "tank/data"},
+		},
+		{source: "something", want: PropertySource{Kind: "something"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got := ParsePropertySource(tt.source)
+
+			assert.Equal(t, tt.want, got)
+			if tt.source != "" {
+				assert.Equal(t, tt.source, got.String())
+			}
+		})
+	}
+}
+
+func TestProperties_sources(t *testing.T) {
+	p := Properties{
+		"used":       {Property: "used", Value: "1024", Source: "-"},
+		"quota":      {Property: "quota", Value: "1G", Source: "local"},
+		"atime":      {Property: "atime", Value: "on", Source: "default"},
+		"recordsize": {Property: "recordsize", Value: "1M", Source: "received"},
+		"compression": {
+			Property: "compression",
+			Value:    "lz4",
+			Source:   "inherited from tank",
+		},
+	}
+
+	s, ok := p.Source("compression")
+	assert.True(t, ok)
+	assert.Equal(t, PropertySource{Kind: SourceInherited, From: "tank"}, s)
+	_, ok = p.Source("missing")
+	assert.False(t, ok)
+
+	assert.True(t, p.IsLocal("quota"))
+	assert.False(t, p.IsLocal("atime"))
+	assert.False(t, p.IsLocal("missing"))
+
+	from, ok := p.InheritedFrom("compression")
+	assert.True(t, ok)
+	assert.Equal(t, "tank", from)
+	_, ok = p.InheritedFrom("quota")
+	assert.False(t, ok)
+
+	assert.Equal(t, Properties{
+		"quota":      p["quota"],
+		"recordsize": p["recordsize"],
+	}, p.WithSource(SourceLocal, SourceReceived))
+	assert.Equal(t, Properties{}, p.WithSource())
+}
+
+func TestProperties_Received(t *testing.T) {
+	p := Properties{
+		"compression": {
+			Property: "compression",
+			Value:    "zstd",
+			Received: "lz4",
+			Source:   "local",
+		},
+		"atime": {
+			Property: "atime",
+			Value:    "off",
+			Received: "off",
+			Source:   "received",
+		},
+		"quota": {Property: "quota", Value: "0", Received: "-"},
+		"sync":  {Property: "sync", Value: "standard", Source: "default"},
+	}
+
+	v, ok := p.Received("compression")
+	assert.True(t, ok)
+	assert.Equal(t, "lz4", v)
+	_, ok = p.Received("quota")
+	assert.False(t, ok)
+	_, ok = p.Received("sync")
+	assert.False(t, ok)
+
+	assert.True(t, p.OverridesReceived("compression"))
+	assert.False(t, p.OverridesReceived("atime"))
+	assert.False(t, p.OverridesReceived("quota"))
+}
@@ -0,0 +1,246 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+var errInvalidSpaceOptions = multierr.Append(ErrZFS, ErrInvalidSpaceOptions)
+
+// spaceFields are the fields requested from zfs userspace, groupspace, and
+// projectspace, in the order they are parsed by newSpaceEntry.
+const spaceFields = "type,name,used,quota,objused,objquota"
+
+// SpaceType is the type of identity that space usage is accounted to.
+type SpaceType string
+
+const (
+	// SpacePOSIXUser is a POSIX user identified by UID, the "posixuser" type
+	// of zfs userspace.
+	SpacePOSIXUser SpaceType = "posixuser"
+
+	// SpacePOSIXGroup is a POSIX group identified by GID, the "posixgroup"
+	// type of zfs groupspace.
+	SpacePOSIXGroup SpaceType = "posixgroup"
+
+	// SpaceSMBUser is an SMB user identified by SID, the "smbuser" type of zfs
+	// userspace.
+	SpaceSMBUser SpaceType = "smbuser"
+
+	// SpaceSMBGroup is an SMB group identified by SID, the "smbgroup" type of
+	// zfs groupspace.
+	SpaceSMBGroup SpaceType = "smbgroup"
+
+	// SpaceProject is a project identified by project ID, as reported by zfs
+	// projectspace, which does not support the -t flag. Its "project" value
+	// is only used to identify entries.
+	SpaceProject SpaceType = "project"
+)
+
+// parseSpaceType parses the type column of zfs userspace, groupspace, and
+// projectspace output, like "POSIX User", into a SpaceType.
+func parseSpaceType(s string) SpaceType {
+	return SpaceType(strings.ToLower(strings.ReplaceAll(s, " ", "")))
+}
+
+// SpaceEntry is the space usage of a single user, group, or project within a
+// dataset, as returned by UserSpace, GroupSpace, and ProjectSpace.
+type SpaceEntry struct {
+	// Type of identity the entry is for.
+	Type SpaceType
+
+	// Name of the identity, as resolved by SpaceOptions.ResolveName. It is
+	// empty if names are not resolved, or the ID could not be resolved.
+	Name string
+
+	// ID is the numeric user, group, or project ID. It is zero for SMB
+	// identities which do not have a POSIX ID, in which case SID is set.
+	ID uint64
+
+	// SID of SMB identities which do not have a POSIX ID.
+	SID string
+
+	// Used is the space used in bytes.
+	Used uint64
+
+	// Quota is the space quota in bytes, or zero if there is no quota.
+	Quota uint64
+
+	// ObjUsed is the number of objects, like files and directories, used.
+	ObjUsed uint64
+
+	// ObjQuota is the object quota, or zero if there is no object quota.
+	ObjQuota uint64
+}
+
+// SpaceOptions are options for UserSpace, GroupSpace, and ProjectSpace.
+type SpaceOptions struct {
+	// Types of identities to report, passed with the -t flag. If empty, zfs
+	// reports POSIX and SMB users for UserSpace, and POSIX and SMB groups for
+	// GroupSpace. Not supported by ProjectSpace.
+	Types []SpaceType
+
+	// TranslateSIDs translates SMB SIDs to POSIX IDs where possible, by
+	// passing the -i flag. Not supported by ProjectSpace.
+	TranslateSIDs bool
+
+	// ResolveName optionally resolves the numeric ID of each identity to a
+	// name, returning false if it cannot be resolved. zfs always reports
+	// numeric IDs, so no name lookups are done unless ResolveName is set.
+	// LookupLocalName can be used when zfs is run on the local host.
+	ResolveName func(typ SpaceType, id uint64) (string, bool)
+}
+
+// LookupLocalName resolves the ID of POSIX users and groups to a name using
+// the user and group databases of the local host. It is suitable for use as
+// SpaceOptions.ResolveName when zfs commands are run on the local host.
+func LookupLocalName(typ SpaceType, id uint64) (string, bool) {
+	s := strconv.FormatUint(id, 10)
+
+	switch typ {
+	case SpacePOSIXUser:
+		if u, err := user.LookupId(s); err == nil {
+			return u.Username, true
+		}
+	case SpacePOSIXGroup:
+		if g, err := user.LookupGroupId(s); err == nil {
+			return g.Name, true
+		}
+	case SpaceSMBUser, SpaceSMBGroup, SpaceProject:
+	}
+
+	return "", false
+}
+
+// UserSpace returns the space used by each user within the named filesystem
+// or snapshot, via zfs userspace.
+func (m *Manager) UserSpace(
+	ctx context.Context,
+	name string,
+	options *SpaceOptions,
+) ([]*SpaceEntry, error) {
+	return m.space(ctx, "userspace", name, options)
+}
+
+// GroupSpace returns the space used by each group within the named filesystem
+// or snapshot, via zfs groupspace.
+func (m *Manager) GroupSpace(
+	ctx context.Context,
+	name string,
+	options *SpaceOptions,
+) ([]*SpaceEntry, error) {
+	return m.space(ctx, "groupspace", name, options)
+}
+
+// ProjectSpace returns the space used by each project within the named
+// filesystem or snapshot, via zfs projectspace.
+func (m *Manager) ProjectSpace(
+	ctx context.Context,
+	name string,
+	options *SpaceOptions,
+) ([]*SpaceEntry, error) {
+	return m.space(ctx, "projectspace", name, options)
+}
+
+func (m *Manager) space(
+	ctx context.Context,
+	subcommand string,
+	name string,
+	options *SpaceOptions,
+) ([]*SpaceEntry, error) {
+	if err := validateDatasetName(name); err != nil {
+		return nil, multierr.Append(ErrZFS, err)
+	}
+	if options == nil {
+		options = &SpaceOptions{}
+	}
+
+	args := []string{subcommand, "-Hp", "-o", spaceFields}
+	if subcommand == "projectspace" {
+		if len(options.Types) > 0 || options.TranslateSIDs {
+			return nil, fmt.Errorf(
+				"%w: types and SID translation are not supported for "+
+					"projects",
+				errInvalidSpaceOptions,
+			)
+		}
+	} else {
+		args = append(args, "-n")
+		if options.TranslateSIDs {
+			args = append(args, "-i")
+		}
+		if len(options.Types) > 0 {
+			types := make([]string, len(options.Types))
+			for i, t := range options.Types {
+				types[i] = string(t)
+			}
+			args = append(args, "-t", strings.Join(types, ","))
+		}
+	}
+	args = append(args, name)
+
+	records, err := m.zfs(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*SpaceEntry{}
+	for _, record := range records {
+		if len(record) == 6 && record[0] != "" {
+			entries = append(entries,
+				newSpaceEntry(record, options.ResolveName),
+			)
+		}
+	}
+
+	return entries, nil
+}
+
+// newSpaceEntry returns a *SpaceEntry from a record of zfs userspace,
+// groupspace, or projectspace output with the fields in spaceFields. The name
+// field is expected to be numeric, as returned with the -n flag, or an SMB
+// SID.
+func newSpaceEntry(
+	record []string,
+	resolve func(SpaceType, uint64) (string, bool),
+) *SpaceEntry {
+	e := &SpaceEntry{
+		Type:     parseSpaceType(record[0]),
+		Used:     parseSpaceValue(record[2]),
+		Quota:    parseSpaceValue(record[3]),
+		ObjUsed:  parseSpaceValue(record[4]),
+		ObjQuota: parseSpaceValue(record[5]),
+	}
+
+	id, err := strconv.ParseUint(record[1], 10, 64)
+	if err != nil {
+		e.SID = record[1]
+
+		return e
+	}
+
+	e.ID = id
+	if resolve != nil {
+		if name, ok := resolve(e.Type, id); ok {
+			e.Name = name
+		}
+	}
+
+	return e
+}
+
+// parseSpaceValue parses a numeric value of zfs userspace output, returning
+// zero for "none", "-", and other non-numeric values.
+func parseSpaceValue(s string) uint64 {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
@@ -0,0 +1,253 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/user"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_space(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	resolve := func(typ SpaceType, id uint64) (string, bool) {
+		if typ == SpacePOSIXUser && id == 1000 {
+			return "alice", true
+		}
+
+		return "", false
+	}
+
+	type spaceFunc func(
+		m *Manager,
+		ctx context.Context,
+		name string,
+		options *SpaceOptions,
+	) ([]*SpaceEntry, error)
+
+	tests := []struct {
+		name           string
+		method         spaceFunc
+		dataset        string
+		options        *SpaceOptions
+		wantArgs       []string
+		stdout         string
+		stderr         string
+		commandErr     error
+		want           []*SpaceEntry
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "user space",
+			method:  (*Manager).UserSpace,
+			dataset: "tank/home",
+			wantArgs: []string{
+				"userspace", "-Hp", "-o",
+				"type,name,used,quota,objused,objquota", "-n", "tank/home",
+			},
+			stdout: "POSIX User\t0\t1536\tnone\t3\tnone\n" +
+				"POSIX User\t1000\t10737418240\t21474836480\t1200\t5000\n" +
+				"SMB User\tS-1-5-21-123-456-789-1001\t4096\tnone\t2\tnone\n",
+			want: []*SpaceEntry{
+				{Type: SpacePOSIXUser, ID: 0, Used: 1536, ObjUsed: 3},
+				{
+					Type:     SpacePOSIXUser,
+					ID:       1000,
+					Used:     10737418240,
+					Quota:    21474836480,
+					ObjUsed:  1200,
+					ObjQuota: 5000,
+				},
+				{
+					Type:    SpaceSMBUser,
+					SID:     "S-1-5-21-123-456-789-1001",
+					Used:    4096,
+					ObjUsed: 2,
+				},
+			},
+		},
+		{
+			name:    "user space with options",
+			method:  (*Manager).UserSpace,
+			dataset: "tank/home@daily",
+			options: &SpaceOptions{
+				Types:         []SpaceType{SpacePOSIXUser, SpaceSMBUser},
+				TranslateSIDs: true,
+				ResolveName:   resolve,
+			},
+			wantArgs: []string{
+				"userspace", "-Hp", "-o",
+				"type,name,used,quota,objused,objquota", "-n", "-i",
+				"-t", "posixuser,smbuser", "tank/home@daily",
+			},
+			stdout: "POSIX User\t0\t1536\tnone\t3\tnone\n" +
+				"POSIX User\t1000\t2048\tnone\t4\tnone\n",
+			want: []*SpaceEntry{
+				{Type: SpacePOSIXUser, ID: 0, Used: 1536, ObjUsed: 3},
+				{
+					Type:    SpacePOSIXUser,
+					Name:    "alice",
+					ID:      1000,
+					Used:    2048,
+					ObjUsed: 4,
+				},
+			},
+		},
+		{
+			name:    "group space",
+			method:  (*Manager).GroupSpace,
+			dataset: "tank/home",
+			options: &SpaceOptions{ResolveName: resolve},
+			wantArgs: []string{
+				"groupspace", "-Hp", "-o",
+				"type,name,used,quota,objused,objquota", "-n", "tank/home",
+			},
+			stdout: "POSIX Group\t1000\t2048\t4096\t4\t-\n",
+			want: []*SpaceEntry{
+				{
+					Type:    SpacePOSIXGroup,
+					ID:      1000,
+					Used:    2048,
+					Quota:   4096,
+					ObjUsed: 4,
+				},
+			},
+		},
+		{
+			name:    "project space",
+			method:  (*Manager).ProjectSpace,
+			dataset: "tank/home",
+			wantArgs: []string{
+				"projectspace", "-Hp", "-o",
+				"type,name,used,quota,objused,objquota", "tank/home",
+			},
+			stdout: "PROJECT\t42\t8192\t1048576\t10\tnone\n",
+			want: []*SpaceEntry{
+				{
+					Type:    SpaceProject,
+					ID:      42,
+					Used:    8192,
+					Quota:   1048576,
+					ObjUsed: 10,
+				},
+			},
+		},
+		{
+			name:    "empty output",
+			method:  (*Manager).UserSpace,
+			dataset: "tank/empty",
+			wantArgs: []string{
+				"userspace", "-Hp", "-o",
+				"type,name,used,quota,objused,objquota", "-n", "tank/empty",
+			},
+			stdout: "",
+			want:   []*SpaceEntry{},
+		},
+		{
+			name:    "project space with types",
+			method:  (*Manager).ProjectSpace,
+			dataset: "tank/home",
+			options: &SpaceOptions{Types: []SpaceType{SpacePOSIXUser}},
+			wantErr: "zfs; invalid space options: " +
+				"types and SID translation are not supported for projects",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidSpaceOptions},
+		},
+		{
+			name:    "invalid name",
+			method:  (*Manager).GroupSpace,
+			dataset: "tank/home/",
+			wantErr: "zfs; invalid name 'tank/home/': " +
+				"trailing slash in name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
+		{
+			name:    "not found",
+			method:  (*Manager).UserSpace,
+			dataset: "tank/missing",
+			wantArgs: []string{
+				"userspace", "-Hp", "-o",
+				"type,name,used,quota,objused,objquota", "-n",
+				"tank/missing",
+			},
+			stderr: "cannot open 'tank/missing': " +
+				"dataset does not exist\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/missing': dataset does not exist",
+			wantErrTargets: []error{Err, ErrZFS, ErrNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					stdout io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stdout.Write([]byte(tt.stdout))
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			got, err := tt.method(m, ctx, tt.dataset, tt.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLookupLocalName(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+	uid, err := strconv.ParseUint(current.Uid, 10, 64)
+	require.NoError(t, err)
+
+	name, ok := LookupLocalName(SpacePOSIXUser, uid)
+	assert.True(t, ok)
+	assert.Equal(t, current.Username, name)
+
+	_, ok = LookupLocalName(SpaceProject, uid)
+	assert.False(t, ok)
+
+	_, ok = LookupLocalName(SpacePOSIXUser, 4294967294)
+	assert.False(t, ok)
+}
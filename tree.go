@@ -0,0 +1,196 @@
+package zfs
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// DatasetTreeOptions are options for GetDatasetTree.
+type DatasetTreeOptions struct {
+	// Depth limits the tree to the given number of levels below the root,
+	// where 1 includes direct children only. Zero means no limit. As with
+	// zfs, snapshots and bookmarks count as a level below their dataset.
+	Depth uint64
+
+	// Snapshots includes snapshots in the tree when true.
+	Snapshots bool
+
+	// Bookmarks includes bookmarks in the tree when true.
+	Bookmarks bool
+
+	// Properties to return for each dataset. If empty, all properties are
+	// returned. SnapshotUsed requires the "usedbysnapshots" property.
+	Properties []string
+
+	// NoSources lists datasets with zfs list instead of zfs get, as described
+	// by ListDatasetsOptions. Properties must be given when true.
+	NoSources bool
+}
+
+// DatasetNode is a filesystem or volume in a dataset tree, as returned by
+// GetDatasetTree.
+type DatasetNode struct {
+	*Dataset
+
+	// Parent is the node of the parent dataset, or nil for the root of the
+	// tree.
+	Parent *DatasetNode
+
+	// Children are the nodes of child filesystems and volumes, in the order
+	// listed by zfs.
+	Children []*DatasetNode
+
+	// Snapshots of the dataset, in the order listed by zfs.
+	Snapshots []*Dataset
+
+	// Bookmarks of the dataset, in the order listed by zfs.
+	Bookmarks []*Dataset
+}
+
+// Find returns the node of the named dataset within the subtree of n,
+// including n itself, or nil if it is not in the subtree.
+func (n *DatasetNode) Find(name string) *DatasetNode {
+	if name == n.Name {
+		return n
+	}
+	if !strings.HasPrefix(name, n.Name+"/") {
+		return nil
+	}
+
+	for _, child := range n.Children {
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// Descendants returns all nodes below n, with each node followed by its own
+// descendants.
+func (n *DatasetNode) Descendants() []*DatasetNode {
+	r := []*DatasetNode{}
+	for _, child := range n.Children {
+		r = append(r, child)
+		r = append(r, child.Descendants()...)
+	}
+
+	return r
+}
+
+// SnapshotCount returns the number of snapshots of n and its descendants.
+func (n *DatasetNode) SnapshotCount() int {
+	count := len(n.Snapshots)
+	for _, child := range n.Children {
+		count += child.SnapshotCount()
+	}
+
+	return count
+}
+
+// SnapshotUsed returns the space used by snapshots of n and its descendants,
+// as the sum of the "usedbysnapshots" property of each node.
+//
+// The second return value indicates if the property is present and could
+// successfully be parsed for every node.
+func (n *DatasetNode) SnapshotUsed() (uint64, bool) {
+	total, ok := n.UsedBySnapshots()
+	for _, child := range n.Children {
+		v, childOK := child.SnapshotUsed()
+		total += v
+		ok = ok && childOK
+	}
+
+	return total, ok
+}
+
+// GetDatasetTree returns the tree of the named filesystem or volume and its
+// descendants, built from a single listing of datasets. Nil options build a
+// tree of all descendant filesystems and volumes with all properties.
+func (m *Manager) GetDatasetTree(
+	ctx context.Context,
+	root string,
+	options *DatasetTreeOptions,
+) (*DatasetNode, error) {
+	n, err := ParseDatasetName(root)
+	if err != nil {
+		return nil, multierr.Append(ErrZFS, err)
+	}
+	if n.IsSnapshot() || n.IsBookmark() {
+		return nil, multierr.Append(ErrZFS, invalidNameError(root,
+			"snapshots and bookmarks cannot have a tree",
+		))
+	}
+	if options == nil {
+		options = &DatasetTreeOptions{}
+	}
+
+	types := []DatasetType{FilesystemType, VolumeType}
+	if options.Snapshots {
+		types = append(types, SnapshotType)
+	}
+	if options.Bookmarks {
+		types = append(types, BookmarkType)
+	}
+
+	datasets, err := m.ListDatasetsWithOptions(ctx, &ListDatasetsOptions{
+		Names:      []string{root},
+		Recursive:  true,
+		Depth:      options.Depth,
+		Types:      types,
+		Properties: options.Properties,
+		NoSources:  options.NoSources,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newDatasetTree(root, datasets), nil
+}
+
+// newDatasetTree builds the tree of root from datasets, in hierarchical order
+// as listed by zfs. Datasets whose parent is not listed before them are
+// ignored. If root is not listed, a node without properties is returned.
+func newDatasetTree(root string, datasets []*Dataset) *DatasetNode {
+	nodes := map[string]*DatasetNode{}
+	tree := &DatasetNode{Dataset: &Dataset{Name: root}}
+	nodes[root] = tree
+
+	for _, ds := range datasets {
+		if ds.Name == root {
+			tree.Dataset = ds
+
+			continue
+		}
+
+		if i := strings.IndexAny(ds.Name, "@#"); i >= 0 {
+			parent, ok := nodes[ds.Name[:i]]
+			if !ok {
+				continue
+			}
+			if ds.Name[i] == '@' {
+				parent.Snapshots = append(parent.Snapshots, ds)
+			} else {
+				parent.Bookmarks = append(parent.Bookmarks, ds)
+			}
+
+			continue
+		}
+
+		i := strings.LastIndexByte(ds.Name, '/')
+		if i < 0 {
+			continue
+		}
+		parent, ok := nodes[ds.Name[:i]]
+		if !ok {
+			continue
+		}
+		node := &DatasetNode{Dataset: ds, Parent: parent}
+		parent.Children = append(parent.Children, node)
+		nodes[ds.Name] = node
+	}
+
+	return tree
+}
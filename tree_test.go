@@ -0,0 +1,248 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDataset(name string, props map[string]string) *Dataset {
+	ds := &Dataset{Name: name, Properties: Properties{}}
+	for k, v := range props {
+		ds.Properties[k] = Property{Name: name, Property: k, Value: v}
+	}
+
+	return ds
+}
+
+func testDatasetTree() *DatasetNode {
+	return newDatasetTree("tank", []*Dataset{
+		testDataset("tank", map[string]string{"usedbysnapshots": "10"}),
+		testDataset("tank@daily", nil),
+		testDataset("tank/a", map[string]string{"usedbysnapshots": "20"}),
+		testDataset("tank/a@daily", nil),
+		testDataset("tank/a@weekly", nil),
+		testDataset("tank/a#mark", nil),
+		testDataset("tank/a/b", map[string]string{"usedbysnapshots": "5"}),
+		testDataset("tank/c", map[string]string{"usedbysnapshots": "0"}),
+		testDataset("tank/orphan/x", nil),
+	})
+}
+
+func TestNewDatasetTree(t *testing.T) {
+	tree := testDatasetTree()
+
+	assert.Equal(t, "tank", tree.Name)
+	assert.Nil(t, tree.Parent)
+	assert.Equal(t, []string{"tank@daily"}, datasetNames(tree.Snapshots))
+	require.Len(t, tree.Children, 2)
+
+	a := tree.Children[0]
+	assert.Equal(t, "tank/a", a.Name)
+	assert.Same(t, tree, a.Parent)
+	assert.Equal(t,
+		[]string{"tank/a@daily", "tank/a@weekly"},
+		datasetNames(a.Snapshots),
+	)
+	assert.Equal(t, []string{"tank/a#mark"}, datasetNames(a.Bookmarks))
+	require.Len(t, a.Children, 1)
+	assert.Equal(t, "tank/a/b", a.Children[0].Name)
+	assert.Same(t, a, a.Children[0].Parent)
+
+	assert.Equal(t, "tank/c", tree.Children[1].Name)
+	assert.Empty(t, tree.Children[1].Children)
+}
+
+func TestDatasetNode_Find(t *testing.T) {
+	tree := testDatasetTree()
+
+	assert.Same(t, tree, tree.Find("tank"))
+	assert.Same(t, tree.Children[0].Children[0], tree.Find("tank/a/b"))
+	assert.Same(t, tree.Children[0].Children[0],
+		tree.Children[0].Find("tank/a/b"),
+	)
+	assert.Nil(t, tree.Find("tank/a/missing"))
+	assert.Nil(t, tree.Find("tank/orphan/x"))
+	assert.Nil(t, tree.Find("tan"))
+	assert.Nil(t, tree.Children[1].Find("tank/a"))
+}
+
+func TestDatasetNode_Descendants(t *testing.T) {
+	tree := testDatasetTree()
+
+	names := []string{}
+	for _, node := range tree.Descendants() {
+		names = append(names, node.Name)
+	}
+
+	assert.Equal(t, []string{"tank/a", "tank/a/b", "tank/c"}, names)
+	assert.Empty(t, tree.Children[1].Descendants())
+}
+
+func TestDatasetNode_SnapshotCount(t *testing.T) {
+	tree := testDatasetTree()
+
+	assert.Equal(t, 3, tree.SnapshotCount())
+	assert.Equal(t, 2, tree.Children[0].SnapshotCount())
+	assert.Equal(t, 0, tree.Children[1].SnapshotCount())
+}
+
+func TestDatasetNode_SnapshotUsed(t *testing.T) {
+	tree := testDatasetTree()
+
+	used, ok := tree.SnapshotUsed()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(35), used)
+
+	used, ok = tree.Children[0].SnapshotUsed()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(25), used)
+
+	delete(tree.Children[0].Children[0].Properties, "usedbysnapshots")
+	used, ok = tree.SnapshotUsed()
+	assert.False(t, ok)
+	assert.Equal(t, uint64(30), used)
+}
+
+func TestManager_GetDatasetTree(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name           string
+		root           string
+		options        *DatasetTreeOptions
+		wantArgs       []string
+		stdout         string
+		stderr         string
+		commandErr     error
+		want           []string
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			root:    "tank",
+			options: nil,
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"-r", "-t", "filesystem,volume", "all", "tank",
+			},
+			stdout: "tank\tused\t300\t-\n" +
+				"tank/a\tused\t100\t-\n" +
+				"tank/a/b\tused\t50\t-\n",
+			want: []string{"tank/a", "tank/a/b"},
+		},
+		{
+			name: "snapshots and bookmarks",
+			root: "tank/a",
+			options: &DatasetTreeOptions{
+				Depth:      2,
+				Snapshots:  true,
+				Bookmarks:  true,
+				Properties: []string{"usedbysnapshots"},
+				NoSources:  true,
+			},
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,usedbysnapshots",
+				"-d", "2", "-t", "filesystem,volume,snapshot,bookmark",
+				"tank/a",
+			},
+			stdout: "tank/a\t100\n" +
+				"tank/a@snap\t0\n" +
+				"tank/a#mark\t-\n" +
+				"tank/a/b\t0\n",
+			want: []string{"tank/a/b"},
+		},
+		{
+			name: "snapshot root",
+			root: "tank/a@snap",
+			wantErr: "zfs; invalid name 'tank/a@snap': " +
+				"snapshots and bookmarks cannot have a tree",
+			wantErrTargets: []error{
+				Err, ErrZFS, ErrInvalidName,
+			},
+		},
+		{
+			name:    "invalid root",
+			root:    "/tank",
+			wantErr: "zfs; invalid name '/tank': leading slash in name",
+			wantErrTargets: []error{
+				Err, ErrZFS, ErrInvalidName,
+			},
+		},
+		{
+			name: "not found",
+			root: "tank/missing",
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"-r", "-t", "filesystem,volume", "all", "tank/missing",
+			},
+			stderr: "cannot open 'tank/missing': " +
+				"dataset does not exist\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/missing': dataset does not exist",
+			wantErrTargets: []error{Err, ErrZFS, ErrNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					stdout io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stdout.Write([]byte(tt.stdout))
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			got, err := m.GetDatasetTree(ctx, tt.root, tt.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.root, got.Name)
+			assert.NotEmpty(t, got.Properties)
+			names := []string{}
+			for _, node := range got.Descendants() {
+				names = append(names, node.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package zfs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrStopWalk can be returned by the function passed to WalkDatasets to stop
+// walking datasets early without WalkDatasets returning an error.
+var ErrStopWalk = errors.New("stop walk")
+
+// datasetDecoder decodes datasets from lines of zfs output.
+type datasetDecoder interface {
+	// decode decodes line, returning a dataset when one is complete, or nil.
+	decode(line string) *Dataset
+
+	// flush returns the last dataset, if not yet returned by decode, or nil.
+	flush() *Dataset
+}
+
+// listDecoder decodes lines of "zfs list -H -o name,<properties>" output,
+// where each line is a complete dataset.
+type listDecoder struct {
+	properties []string
+	fields     []string
+}
+
+func newListDecoder(properties []string) *listDecoder {
+	return &listDecoder{
+		properties: properties,
+		fields:     make([]string, len(properties)+1),
+	}
+}
+
+func (d *listDecoder) decode(line string) *Dataset {
+	if !splitFields(line, d.fields) || d.fields[0] == "" {
+		return nil
+	}
+
+	return newListDataset(d.fields, d.properties)
+}
+
+func (d *listDecoder) flush() *Dataset {
+	return nil
+}
+
+// getDecoder decodes lines of "zfs get -H -o name,property,value,source"
+// output, where each line is a property, and properties of a dataset are on
+// consecutive lines.
+type getDecoder struct {
+	current *Dataset
+}
+
+func (d *getDecoder) decode(line string) *Dataset {
+	record := strings.Split(line, "\t")
+	if len(record) != 4 || record[0] == "" {
+		return nil
+	}
+
+	var done *Dataset
+	if d.current != nil && d.current.Name != record[0] {
+		done, d.current = d.current, nil
+	}
+	if d.current == nil {
+		d.current = &Dataset{Name: record[0], Properties: Properties{}}
+	}
+	d.current.Properties[record[1]] = Property{
+		Name:     record[0],
+		Property: record[1],
+		Value:    record[2],
+		Source:   record[3],
+	}
+
+	return done
+}
+
+func (d *getDecoder) flush() *Dataset {
+	done := d.current
+	d.current = nil
+
+	return done
+}
+
+// walkArgs returns the zfs command arguments and decoder used by WalkDatasets
+// for options.
+func (o *ListDatasetsOptions) walkArgs() ([]string, datasetDecoder) {
+	if !o.NoSources {
+		args := []string{"get", "-Hp", "-o", "name,property,value,source"}
+
+		return append(args, o.flags()...), &getDecoder{}
+	}
+
+	properties := withSortProperties(o.Properties, o.Sort)
+
+	return o.listArgs(properties, sortFlags(o.Sort)...),
+		newListDecoder(properties)
+}
+
+// WalkDatasets calls fn for each dataset matching options, in the same order
+// as ListDatasetsWithOptions. Output of zfs is read and decoded incrementally
+// as it is produced, so only a single dataset is held in memory at a time,
+// making it suitable for very large numbers of datasets. Nil options walk all
+// datasets with all properties.
+//
+// If fn returns an error, the zfs command is stopped, and WalkDatasets returns
+// the error, unless it is ErrStopWalk, in which case nil is returned.
+//
+// As with ListDatasetsWithOptions, sort keys require NoSources, and datasets
+// are sorted by zfs list itself. Failed commands are not retried, as datasets
+// may already have been passed to fn.
+func (m *Manager) WalkDatasets(
+	ctx context.Context,
+	options *ListDatasetsOptions,
+	fn func(*Dataset) error,
+) error {
+	if options == nil {
+		options = &ListDatasetsOptions{}
+	}
+	if err := options.validate(); err != nil {
+		return err
+	}
+	args, decoder := options.walkArgs()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := m.execTo(ctx, nil, pw, ErrZFS, "zfs", args...)
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+
+	stopped, err := decodeDatasets(pr, decoder, fn)
+	_ = pr.Close()
+	cancel()
+	cmdErr := <-done
+
+	switch {
+	case stopped && errors.Is(err, ErrStopWalk):
+		return nil
+	case stopped:
+		return err
+	case cmdErr != nil:
+		return cmdErr
+	}
+
+	return err
+}
+
+// decodeDatasets reads lines from r until EOF, calling fn with each dataset
+// returned by decoder. The returned bool indicates if fn returned an error,
+// which is returned as is.
+func decodeDatasets(
+	r io.Reader,
+	decoder datasetDecoder,
+	fn func(*Dataset) error,
+) (bool, error) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			ds := decoder.decode(strings.TrimSuffix(line, "\n"))
+			if ds != nil {
+				if ferr := fn(ds); ferr != nil {
+					return true, ferr
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if ds := decoder.flush(); ds != nil {
+		if err := fn(ds); err != nil {
+			return true, err
+		}
+	}
+
+	return false, nil
+}
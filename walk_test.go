@@ -0,0 +1,280 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_WalkDatasets(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name           string
+		options        *ListDatasetsOptions
+		fnErrAfter     int
+		fnErr          error
+		wantArgs       []string
+		stdout         string
+		stderr         string
+		commandErr     error
+		want           []*Dataset
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			options: nil,
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source", "all",
+			},
+			stdout: "tank\tused\t300\t-\n" +
+				"tank\tquota\t0\tdefault\n" +
+				"tank/a\tused\t100\t-\n" +
+				"tank/a\tquota\t1024\tlocal\n",
+			want: []*Dataset{
+				{
+					Name: "tank",
+					Properties: Properties{
+						"used": {
+							Name: "tank", Property: "used", Value: "300",
+							Source: "-",
+						},
+						"quota": {
+							Name: "tank", Property: "quota", Value: "0",
+							Source: "default",
+						},
+					},
+				},
+				{
+					Name: "tank/a",
+					Properties: Properties{
+						"used": {
+							Name: "tank/a", Property: "used", Value: "100",
+							Source: "-",
+						},
+						"quota": {
+							Name: "tank/a", Property: "quota", Value: "1024",
+							Source: "local",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "no sources with sort",
+			options: &ListDatasetsOptions{
+				Names:      []string{"tank"},
+				Recursive:  true,
+				Properties: []string{"used"},
+				Sort: []SortKey{
+					{Property: "used", Descending: true},
+					{Property: "name"},
+				},
+				NoSources: true,
+			},
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,used", "-r", "-t", "all",
+				"-S", "used", "-s", "name", "tank",
+			},
+			stdout: "tank\t300\ntank/a\t100\n",
+			want: []*Dataset{
+				{
+					Name: "tank",
+					Properties: Properties{
+						"used": {
+							Name: "tank", Property: "used", Value: "300",
+						},
+					},
+				},
+				{
+					Name: "tank/a",
+					Properties: Properties{
+						"used": {
+							Name: "tank/a", Property: "used", Value: "100",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "stop walk",
+			options: &ListDatasetsOptions{
+				Properties: []string{"used"},
+				NoSources:  true,
+			},
+			fnErrAfter: 1,
+			fnErr:      ErrStopWalk,
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,used", "-t", "all",
+			},
+			stdout: "tank\t300\ntank/a\t100\n",
+			want: []*Dataset{
+				{
+					Name: "tank",
+					Properties: Properties{
+						"used": {
+							Name: "tank", Property: "used", Value: "300",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "fn error",
+			options: &ListDatasetsOptions{
+				Properties: []string{"used"},
+			},
+			fnErrAfter: 2,
+			fnErr:      errBoom,
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source", "used",
+			},
+			stdout:  "tank\tused\t300\t-\ntank/a\tused\t100\t-\n",
+			wantErr: "boom",
+		},
+		{
+			name: "command error",
+			options: &ListDatasetsOptions{
+				Names: []string{"tank/missing"},
+			},
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"all", "tank/missing",
+			},
+			stderr: "cannot open 'tank/missing': " +
+				"dataset does not exist\n",
+			commandErr: errors.New("exit status 1"),
+			wantErr: "zfs; not found; exit status 1: " +
+				"cannot open 'tank/missing': dataset does not exist",
+			wantErrTargets: []error{Err, ErrZFS, ErrNotFound},
+		},
+		{
+			name: "sort without no sources",
+			options: &ListDatasetsOptions{
+				Sort: []SortKey{{Property: "used"}},
+			},
+			wantErr: "zfs; invalid list options: " +
+				"Sort requires NoSources",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidListOptions},
+		},
+		{
+			name: "invalid options",
+			options: &ListDatasetsOptions{
+				NoSources: true,
+			},
+			wantErr: "zfs; invalid list options: " +
+				"NoSources requires Properties",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidListOptions},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					stdout io.Writer,
+					stderr io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stdout.Write([]byte(tt.stdout))
+					_, _ = stderr.Write([]byte(tt.stderr))
+
+					return tt.commandErr
+				})
+			}
+
+			m := &Manager{Runner: r}
+
+			got := []*Dataset{}
+			err := m.WalkDatasets(ctx, tt.options, func(ds *Dataset) error {
+				got = append(got, ds)
+				if tt.fnErr != nil && len(got) == tt.fnErrAfter {
+					return tt.fnErr
+				}
+
+				return nil
+			})
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_WalkDatasets_stopsCommand(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+
+	r.EXPECT().RunContext(
+		gomock.Any(), gomock.Nil(), gomock.Any(), gomock.Any(),
+		"zfs", gomock.Any(),
+	).DoAndReturn(func(
+		ctx context.Context,
+		_ io.Reader,
+		stdout io.Writer,
+		_ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		// Behave like a command with endless output, which only stops when
+		// writes fail or it is killed.
+		for {
+			if _, err := stdout.Write([]byte("tank\t1\n")); err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+	})
+
+	m := &Manager{Runner: r}
+
+	calls := 0
+	err := m.WalkDatasets(ctx,
+		&ListDatasetsOptions{Properties: []string{"used"}, NoSources: true},
+		func(ds *Dataset) error {
+			calls++
+			if calls == 3 {
+				return ErrStopWalk
+			}
+
+			return nil
+		},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
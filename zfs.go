@@ -5,54 +5,33 @@
 package zfs
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
+	"github.com/krystal/go-zfs/zfsprops"
 	"go.uber.org/multierr"
 )
 
 var (
-	errInvalidDatasetName     = multierr.Append(ErrZFS, ErrInvalidName)
 	errInvalidDatasetProperty = multierr.Append(ErrZFS, ErrInvalidProperty)
+	errInvalidListOptions     = multierr.Append(ErrZFS, ErrInvalidListOptions)
 )
 
-func validDatasetName(name string) bool {
-	return len(name) > 0 && name[0] != '/' && name[len(name)-1] != '/'
-}
-
-var (
-	datasetDoesNotExistText = []byte("dataset does not exist")
-	parentDoesNotExistText  = []byte("parent does not exist")
-	noSuchPoolText          = []byte("no such pool")
-)
-
-func notFoundErr(stderr []byte) bool {
-	return bytes.Contains(stderr, datasetDoesNotExistText) ||
-		bytes.Contains(stderr, parentDoesNotExistText) ||
-		bytes.Contains(stderr, noSuchPoolText)
-}
-
 func (m *Manager) zfs(ctx context.Context, args ...string) ([][]string, error) {
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	err := m.Runner.RunContext(ctx, nil, &stdout, &stderr, "zfs", args...)
-	if err != nil {
-		cleanStderr := cleanUpStderr(stderr.Bytes())
-
-		errs := ErrZFS
-		if notFoundErr(cleanStderr) {
-			errs = multierr.Append(errs, ErrNotFound)
-		}
-
-		return nil, multierr.Append(
-			errs, fmt.Errorf("%w: %s", err, cleanStderr),
-		)
-	}
+	return m.zfsWithStdin(ctx, nil, args...)
+}
 
-	return parseTabular(stdout.Bytes()), nil
+// zfsWithStdin behaves like zfs, but passes stdin to the executed command.
+// Used to supply key material without it appearing on the command line.
+func (m *Manager) zfsWithStdin(
+	ctx context.Context,
+	stdin io.Reader,
+	args ...string,
+) ([][]string, error) {
+	return m.run(ctx, stdin, ErrZFS, "zfs", args...)
 }
 
 // GetDatasetProperty returns the value of the given property for the given
@@ -62,8 +41,8 @@ func (m *Manager) GetDatasetProperty(
 	name string,
 	property string,
 ) (string, error) {
-	if !validDatasetName(name) {
-		return "", errInvalidDatasetName
+	if err := validateDatasetName(name); err != nil {
+		return "", multierr.Append(ErrZFS, err)
 	}
 
 	if property == "" || property == allProperty {
@@ -94,8 +73,8 @@ func (m *Manager) SetDatasetProperties(
 	name string,
 	properties map[string]string,
 ) error {
-	if !validDatasetName(name) {
-		return errInvalidDatasetName
+	if err := validateDatasetName(name); err != nil {
+		return multierr.Append(ErrZFS, err)
 	}
 
 	args := []string{"set"}
@@ -103,6 +82,10 @@ func (m *Manager) SetDatasetProperties(
 	if err != nil {
 		return multierr.Append(ErrZFS, err)
 	}
+	err = validateDatasetProperties(properties, 0, false)
+	if err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
 	args = append(args, propArgs...)
 	args = append(args, name)
 
@@ -118,8 +101,8 @@ func (m *Manager) InheritDatasetProperty(
 	property string,
 	recursive bool,
 ) error {
-	if !validDatasetName(name) {
-		return errInvalidDatasetName
+	if err := validateDatasetName(name); err != nil {
+		return multierr.Append(ErrZFS, err)
 	}
 
 	if property == "" {
@@ -137,6 +120,34 @@ func (m *Manager) InheritDatasetProperty(
 	return err
 }
 
+// RevertToReceived reverts property to its received value, by passing the -S
+// flag to zfs inherit. If no value was received, the property is inherited
+// from the parent dataset instead.
+func (m *Manager) RevertToReceived(
+	ctx context.Context,
+	name string,
+	property string,
+	recursive bool,
+) error {
+	if err := validateDatasetName(name); err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
+
+	if property == "" {
+		return errInvalidDatasetProperty
+	}
+
+	args := []string{"inherit"}
+	if recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, "-S", property, name)
+
+	_, err := m.zfs(ctx, args...)
+
+	return err
+}
+
 // CreateDatasetOptions are options for creating a new dataset.
 type CreateDatasetOptions struct {
 	// Name of the dataset. (required)
@@ -171,6 +182,13 @@ type CreateDatasetOptions struct {
 	//
 	// Ignored when VolumeSize is empty.
 	Sparse bool
+
+	// Key is the encryption key for the new dataset, which is piped to stdin
+	// of the zfs command. Unless Properties specifies a keylocation,
+	// -o keylocation=prompt is passed so the key is read from stdin.
+	//
+	// Properties must also enable encryption, and specify a keyformat.
+	Key io.Reader
 }
 
 // CreateDataset creates a new dataset with the given options.
@@ -181,12 +199,8 @@ func (m *Manager) CreateDataset(
 	if options == nil {
 		return multierr.Append(ErrZFS, ErrInvalidCreateOptions)
 	}
-	if !validDatasetName(options.Name) {
-		return multierr.Combine(
-			ErrZFS,
-			ErrInvalidCreateOptions,
-			ErrInvalidName,
-		)
+	if err := validateDatasetName(options.Name); err != nil {
+		return multierr.Combine(ErrZFS, ErrInvalidCreateOptions, err)
 	}
 
 	args := []string{"create"}
@@ -206,7 +220,21 @@ func (m *Manager) CreateDataset(
 		}
 	}
 
-	propArgs, err := propertyMapFlags("-o", options.Properties)
+	props := options.Properties
+	if options.Key != nil {
+		props = withPromptKeyLocation(props)
+	}
+
+	propArgs, err := propertyMapFlags("-o", props)
+	if err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
+
+	typ := zfsprops.TypeFilesystem
+	if options.VolumeSize != "" {
+		typ = zfsprops.TypeVolume
+	}
+	err = validateDatasetProperties(options.Properties, typ, true)
 	if err != nil {
 		return multierr.Append(ErrZFS, err)
 	}
@@ -218,7 +246,7 @@ func (m *Manager) CreateDataset(
 
 	args = append(args, options.Name)
 
-	_, err = m.zfs(ctx, args...)
+	_, err = m.zfsWithStdin(ctx, options.Key, args...)
 
 	return err
 }
@@ -232,17 +260,33 @@ func (m *Manager) GetDataset(
 	name string,
 	properties ...string,
 ) (*Dataset, error) {
-	if !validDatasetName(name) {
-		return nil, errInvalidDatasetName
+	return m.GetDatasetBySource(ctx, name, nil, properties...)
+}
+
+// GetDatasetBySource returns a *Dataset instance for named dataset, with only
+// properties which have one of the given source kinds, by passing the -s flag
+// to zfs get. If sources is empty, properties of all sources are returned.
+//
+// If properties are specified, only those properties are returned for the
+// dataset, otherwise all properties are returned.
+func (m *Manager) GetDatasetBySource(
+	ctx context.Context,
+	name string,
+	sources []SourceKind,
+	properties ...string,
+) (*Dataset, error) {
+	if err := validateDatasetName(name); err != nil {
+		return nil, multierr.Append(ErrZFS, err)
 	}
 	if len(properties) == 0 {
 		properties = []string{allProperty}
 	}
 
-	records, err := m.zfs(ctx,
-		"get", "-Hp", "-o", "name,property,value,source",
-		strings.Join(properties, ","), name,
-	)
+	args := []string{"get", "-Hp", "-o", "name,property,value,source"}
+	args = append(args, sourceFlag(sources)...)
+	args = append(args, strings.Join(properties, ","), name)
+
+	records, err := m.zfs(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -252,37 +296,179 @@ func (m *Manager) GetDataset(
 	return NewDataset(name, props[name]), nil
 }
 
-// ListDatasets returns a slice of *Dataset instances based on the given
-// arguments.
+// GetDatasetReceived returns a *Dataset instance for named dataset, with both
+// the effective and received value of each property. Received values are
+// available via the Received field of each Property.
 //
-// If properties are specified, only those properties are returned for each
+// If properties are specified, only those properties are returned for the
 // dataset, otherwise all properties are returned.
-func (m *Manager) ListDatasets(
+func (m *Manager) GetDatasetReceived(
 	ctx context.Context,
-	filter string,
-	depth uint64,
-	typ DatasetType,
+	name string,
 	properties ...string,
-) ([]*Dataset, error) {
-	args := []string{"get", "-Hp", "-o", "name,property,value,source"}
+) (*Dataset, error) {
+	if err := validateDatasetName(name); err != nil {
+		return nil, multierr.Append(ErrZFS, err)
+	}
+	if len(properties) == 0 {
+		properties = []string{allProperty}
+	}
 
-	if depth > 0 {
-		args = append(args, "-d", strconv.FormatUint(depth, 10))
-	} else {
+	records, err := m.zfs(ctx,
+		"get", "-Hp", "-o", "name,property,value,received,source",
+		strings.Join(properties, ","), name,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	props := newProperties(records)
+
+	return NewDataset(name, props[name]), nil
+}
+
+// ListDatasetsOptions are options for listing datasets.
+type ListDatasetsOptions struct {
+	// Names of the datasets to list. If empty, all datasets are listed.
+	Names []string
+
+	// Recursive indicates whether to also list descendants of Names by
+	// passing the -r flag. When false and Depth is zero, only the named
+	// datasets themselves are listed.
+	Recursive bool
+
+	// Depth limits recursion to the given number of levels below Names by
+	// passing the -d flag, where 1 lists direct children only. Non-zero values
+	// imply Recursive.
+	Depth uint64
+
+	// Types to list, passed with the -t flag. If empty, zfs lists all types.
+	Types []DatasetType
+
+	// Properties to return for each dataset. If empty, all properties are
+	// returned.
+	Properties []string
+
+	// Sources limits returned properties to those with one of the given
+	// source kinds, by passing the -s flag to zfs get. Datasets which have no
+	// properties matching Sources are not returned.
+	Sources []SourceKind
+
+	// Sort keys to sort datasets by, passed with the -s and -S flags of zfs
+	// list, which also determines the order of datasets with equal values
+	// for all sort keys. As zfs get does not sort, Sort requires NoSources.
+	// Sort key properties are added to Properties as needed.
+	Sort []SortKey
+
+	// NoSources lists datasets with zfs list instead of zfs get. As zfs list
+	// outputs a line per dataset rather than a line per property, this is
+	// considerably faster when listing large numbers of datasets, but
+	// property sources are not available, leaving the Source of each
+	// Property empty. Properties must be given, and Sources must be empty.
+	NoSources bool
+}
+
+// SnapshotsOf returns *ListDatasetsOptions which list the snapshots of the
+// named dataset, but not of its descendants, oldest first. Snapshots are
+// listed with NoSources, along with the createtxg property they are sorted
+// by.
+func SnapshotsOf(name string, properties ...string) *ListDatasetsOptions {
+	return &ListDatasetsOptions{
+		Names:      []string{name},
+		Depth:      1,
+		Types:      []DatasetType{SnapshotType},
+		Properties: withSortProperties(properties, snapshotsOfSort),
+		Sort:       snapshotsOfSort,
+		NoSources:  true,
+	}
+}
+
+// snapshotsOfSort are the sort keys of SnapshotsOf, ordering snapshots by the
+// transaction group they were created in.
+var snapshotsOfSort = []SortKey{{Property: "createtxg"}}
+
+func (o *ListDatasetsOptions) validate() error {
+	for _, name := range o.Names {
+		if err := validateDatasetName(name); err != nil {
+			return multierr.Combine(ErrZFS, ErrInvalidListOptions, err)
+		}
+	}
+	for _, p := range o.Properties {
+		if p == "" || strings.Contains(p, ",") {
+			return fmt.Errorf(
+				"%w: invalid property '%s'", errInvalidListOptions, p,
+			)
+		}
+	}
+	if err := validateSortKeys(o.Sort); err != nil {
+		return multierr.Append(ErrZFS, err)
+	}
+	if len(o.Sort) > 0 && !o.NoSources {
+		return fmt.Errorf(
+			"%w: Sort requires NoSources", errInvalidListOptions,
+		)
+	}
+	if o.NoSources {
+		switch {
+		case len(o.Properties) == 0:
+			return fmt.Errorf(
+				"%w: NoSources requires Properties",
+				errInvalidListOptions,
+			)
+		case len(o.Sources) > 0:
+			return fmt.Errorf(
+				"%w: NoSources cannot be combined with Sources",
+				errInvalidListOptions,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (o *ListDatasetsOptions) flags() []string {
+	args := sourceFlag(o.Sources)
+
+	switch {
+	case o.Depth > 0:
+		args = append(args, "-d", strconv.FormatUint(o.Depth, 10))
+	case o.Recursive:
 		args = append(args, "-r")
 	}
 
-	args = append(args, "-t", string(typ))
+	if len(o.Types) > 0 {
+		args = append(args, "-t", string(JoinTypes(o.Types...)))
+	}
 
-	if len(properties) == 0 {
+	if len(o.Properties) == 0 {
 		args = append(args, allProperty)
 	} else {
-		args = append(args, strings.Join(properties, ","))
+		args = append(args, strings.Join(o.Properties, ","))
 	}
 
-	if filter != "" {
-		args = append(args, filter)
+	return append(args, o.Names...)
+}
+
+// ListDatasetsWithOptions returns a slice of *Dataset instances based on the
+// given options, in hierarchical order as reported by zfs unless sort keys
+// are given, in which case they are in the order reported by zfs list with
+// the -s and -S flags. Nil options list all datasets with all properties.
+func (m *Manager) ListDatasetsWithOptions(
+	ctx context.Context,
+	options *ListDatasetsOptions,
+) ([]*Dataset, error) {
+	if options == nil {
+		options = &ListDatasetsOptions{}
 	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.NoSources {
+		return m.listDatasets(ctx, options)
+	}
+
+	args := []string{"get", "-Hp", "-o", "name,property,value,source"}
+	args = append(args, options.flags()...)
 
 	records, err := m.zfs(ctx, args...)
 	if err != nil {
@@ -290,14 +476,55 @@ func (m *Manager) ListDatasets(
 	}
 
 	props := newProperties(records)
-	datasets := make([]*Dataset, 0, len(props))
-	for name, datasetProps := range props {
-		datasets = append(datasets, NewDataset(name, datasetProps))
+	names := recordNames(records)
+	datasets := make([]*Dataset, 0, len(names))
+	for _, name := range names {
+		datasets = append(datasets, NewDataset(name, props[name]))
 	}
 
 	return datasets, nil
 }
 
+// legacyListOptions returns *ListDatasetsOptions equivalent to the positional
+// arguments of ListDatasets, where a depth of zero lists recursively.
+func legacyListOptions(
+	filter string,
+	depth uint64,
+	typ DatasetType,
+	properties []string,
+) *ListDatasetsOptions {
+	o := &ListDatasetsOptions{
+		Recursive:  depth == 0,
+		Depth:      depth,
+		Types:      []DatasetType{typ},
+		Properties: properties,
+	}
+	if filter != "" {
+		o.Names = []string{filter}
+	}
+
+	return o
+}
+
+// ListDatasets returns a slice of *Dataset instances based on the given
+// arguments, in hierarchical order as reported by zfs. A depth of zero lists
+// all descendants of filter; use ListDatasetsWithOptions to list only the
+// named dataset.
+//
+// If properties are specified, only those properties are returned for each
+// dataset, otherwise all properties are returned.
+func (m *Manager) ListDatasets(
+	ctx context.Context,
+	filter string,
+	depth uint64,
+	typ DatasetType,
+	properties ...string,
+) ([]*Dataset, error) {
+	return m.ListDatasetsWithOptions(ctx,
+		legacyListOptions(filter, depth, typ, properties),
+	)
+}
+
 // ListDatasetNames returns a string slice of dataset names matching the given
 // arguments.
 func (m *Manager) ListDatasetNames(
@@ -385,14 +612,16 @@ const (
 	DestroyForceUnmount
 )
 
-// DestroyDataset destroys the named dataset.
+// DestroyDataset destroys the named dataset. Multiple snapshots of a dataset
+// can be destroyed at once by giving a comma separated list of snapshot names
+// and ranges as supported by zfs destroy, like "tank/data@a,c%e".
 func (m *Manager) DestroyDataset(
 	ctx context.Context,
 	name string,
 	flags ...DestroyDatasetFlag,
 ) error {
-	if !validDatasetName(name) {
-		return errInvalidDatasetName
+	if err := validateDestroyName(name); err != nil {
+		return multierr.Append(ErrZFS, err)
 	}
 
 	args := []string{"destroy"}
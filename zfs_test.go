@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -39,7 +40,7 @@ func TestManager_GetDatasetProperty(t *testing.T) {
 				name:     "",
 				property: "size",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -52,7 +53,8 @@ func TestManager_GetDatasetProperty(t *testing.T) {
 				name:     "/tank/my-dataset",
 				property: "size",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name '/tank/my-dataset': " +
+				"leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -65,7 +67,8 @@ func TestManager_GetDatasetProperty(t *testing.T) {
 				name:     "tank/my-dataset/",
 				property: "size",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name 'tank/my-dataset/': " +
+				"trailing slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -243,7 +246,7 @@ func TestManager_SetDatasetProperty(t *testing.T) {
 				property: "quota",
 				value:    "1G",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -256,7 +259,8 @@ func TestManager_SetDatasetProperty(t *testing.T) {
 				name:     "/tank/my-dataset",
 				property: "size",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name '/tank/my-dataset': " +
+				"leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -269,7 +273,8 @@ func TestManager_SetDatasetProperty(t *testing.T) {
 				name:     "tank/my-dataset/",
 				property: "size",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name 'tank/my-dataset/': " +
+				"trailing slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -345,22 +350,66 @@ func TestManager_SetDatasetProperty(t *testing.T) {
 			name: "command error",
 			args: args{
 				name:     "tank/my-dataset",
-				property: "sync",
-				value:    "dontdoit",
+				property: "recordsize",
+				value:    "3K",
 			},
 			wantArgs: []string{
-				"set", "sync=dontdoit", "tank/my-dataset",
+				"set", "recordsize=3K", "tank/my-dataset",
 			},
 			//nolint:lll
-			stderr: `cannot set property for 'tank/my-dataset': 'sync' must be one of 'standard | always | disabled'
+			stderr: `cannot set property for 'tank/my-dataset': 'recordsize' must be power of 2 from 512B to 1M
 usage:
 	set <property=value> ... <filesystem|volume|snapshot> ...
 `,
 			commandErr: errors.New("exit status 1"),
-			wantErr: "zfs; exit status 1: " +
+			wantErr: "zfs; invalid property; exit status 1: " +
 				"cannot set property for 'tank/my-dataset': " +
-				"'sync' must be one of 'standard | always | disabled'",
-			wantErrTargets: []error{Err, ErrZFS},
+				"'recordsize' must be power of 2 from 512B to 1M",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "invalid value",
+			args: args{
+				name:     "tank/my-dataset",
+				property: "sync",
+				value:    "dontdoit",
+			},
+			wantErr: "zfs; invalid property: sync: invalid value " +
+				"'dontdoit', must be one of: standard, always, disabled",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "read-only property",
+			args: args{
+				name:     "tank/my-dataset",
+				property: "used",
+				value:    "1G",
+			},
+			wantErr: "zfs; invalid property: used: " +
+				"property is read-only",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "create-only property",
+			args: args{
+				name:     "tank/my-dataset",
+				property: "casesensitivity",
+				value:    "insensitive",
+			},
+			wantErr: "zfs; invalid property: casesensitivity: " +
+				"property can only be set at creation time",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "unknown property",
+			args: args{
+				name:     "tank/my-dataset",
+				property: "futureprop",
+				value:    "anything",
+			},
+			wantArgs: []string{
+				"set", "futureprop=anything", "tank/my-dataset",
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -437,7 +486,7 @@ func TestManager_SetDatasetProperties(t *testing.T) {
 					"quota": "10G",
 				},
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -452,7 +501,8 @@ func TestManager_SetDatasetProperties(t *testing.T) {
 					"quota": "10G",
 				},
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name '/tank/my-dataset': " +
+				"leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -467,7 +517,8 @@ func TestManager_SetDatasetProperties(t *testing.T) {
 					"quota": "10G",
 				},
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name 'tank/my-dataset/': " +
+				"trailing slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -553,22 +604,35 @@ func TestManager_SetDatasetProperties(t *testing.T) {
 			args: args{
 				name: "tank/my-dataset",
 				properties: map[string]string{
-					"sync": "dontdoit",
+					"recordsize": "3K",
 				},
 			},
 			wantArgs: []string{
-				"set", "sync=dontdoit", "tank/my-dataset",
+				"set", "recordsize=3K", "tank/my-dataset",
 			},
 			//nolint:lll
-			stderr: `cannot set property for 'tank/my-dataset': 'sync' must be one of 'standard | always | disabled'
+			stderr: `cannot set property for 'tank/my-dataset': 'recordsize' must be power of 2 from 512B to 1M
 usage:
 	set <property=value> ... <filesystem|volume|snapshot> ...
 `,
 			commandErr: errors.New("exit status 1"),
-			wantErr: "zfs; exit status 1: " +
+			wantErr: "zfs; invalid property; exit status 1: " +
 				"cannot set property for 'tank/my-dataset': " +
-				"'sync' must be one of 'standard | always | disabled'",
-			wantErrTargets: []error{Err, ErrZFS},
+				"'recordsize' must be power of 2 from 512B to 1M",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "invalid values",
+			args: args{
+				name: "tank/my-dataset",
+				properties: map[string]string{
+					"sync":  "dontdoit",
+					"quota": "lots",
+				},
+			},
+			wantErr: "zfs; invalid property: quota: invalid value " +
+				"'lots', must be a size like 10G, or one of: none",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
 		},
 	}
 	for _, tt := range tests {
@@ -640,7 +704,7 @@ func TestManager_InheritDatasetProperty(t *testing.T) {
 				property:  "quota",
 				recursive: false,
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -654,7 +718,8 @@ func TestManager_InheritDatasetProperty(t *testing.T) {
 				property:  "quota",
 				recursive: false,
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name '/tank/my-dataset': " +
+				"leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -668,7 +733,8 @@ func TestManager_InheritDatasetProperty(t *testing.T) {
 				property:  "quota",
 				recursive: false,
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name 'tank/my-dataset/': " +
+				"trailing slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -814,6 +880,7 @@ func TestManager_CreateDataset(t *testing.T) {
 		name           string
 		args           args
 		wantArgs       []string
+		wantStdin      string
 		stderr         string
 		commandErr     error
 		wantErr        string
@@ -834,7 +901,7 @@ func TestManager_CreateDataset(t *testing.T) {
 			args: args{
 				options: &CreateDatasetOptions{},
 			},
-			wantErr: "zfs; invalid create options; invalid name",
+			wantErr: "zfs; invalid create options; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -849,7 +916,7 @@ func TestManager_CreateDataset(t *testing.T) {
 					Name: "",
 				},
 			},
-			wantErr: "zfs; invalid create options; invalid name",
+			wantErr: "zfs; invalid create options; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -864,7 +931,8 @@ func TestManager_CreateDataset(t *testing.T) {
 					Name: "/tank/my-dataset",
 				},
 			},
-			wantErr: "zfs; invalid create options; invalid name",
+			wantErr: "zfs; invalid create options; " +
+				"invalid name '/tank/my-dataset': leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -879,7 +947,8 @@ func TestManager_CreateDataset(t *testing.T) {
 					Name: "tank/my-dataset/",
 				},
 			},
-			wantErr: "zfs; invalid create options; invalid name",
+			wantErr: "zfs; invalid create options; " +
+				"invalid name 'tank/my-dataset/': trailing slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -1101,6 +1170,55 @@ func TestManager_CreateDataset(t *testing.T) {
 				"tank/my-dataset",
 			},
 		},
+		{
+			name: "encrypted with key",
+			args: args{
+				options: &CreateDatasetOptions{
+					Name: "tank/secret",
+					Properties: map[string]string{
+						(zfsprops.Encryption): "on",
+						(zfsprops.KeyFormat):  "passphrase",
+					},
+					Key: strings.NewReader("correct horse battery"),
+				},
+			},
+			wantArgs: []string{
+				"create",
+				"-o", "encryption=on",
+				"-o", "keyformat=passphrase",
+				"-o", "keylocation=prompt",
+				"tank/secret",
+			},
+			wantStdin: "correct horse battery",
+		},
+		{
+			name: "encrypted volume with key and keylocation",
+			args: args{
+				options: &CreateDatasetOptions{
+					Name: "tank/secret-vol",
+					Properties: map[string]string{
+						(zfsprops.Encryption):  "aes-256-gcm",
+						(zfsprops.KeyFormat):   "hex",
+						(zfsprops.KeyLocation): "prompt",
+					},
+					VolumeSize: "1G",
+					Key: strings.NewReader(
+						"0123456789abcdef0123456789abcdef" +
+							"0123456789abcdef0123456789abcdef",
+					),
+				},
+			},
+			wantArgs: []string{
+				"create",
+				"-o", "encryption=aes-256-gcm",
+				"-o", "keyformat=hex",
+				"-o", "keylocation=prompt",
+				"-V", "1G",
+				"tank/secret-vol",
+			},
+			wantStdin: "0123456789abcdef0123456789abcdef" +
+				"0123456789abcdef0123456789abcdef",
+		},
 		{
 			name: "deeply nested without create parents",
 			args: args{
@@ -1137,22 +1255,82 @@ func TestManager_CreateDataset(t *testing.T) {
 				options: &CreateDatasetOptions{
 					Name: "tank/my-dataset",
 					Properties: map[string]string{
-						zfsprops.Quota: "what",
+						zfsprops.RecordSize: "3K",
 					},
 				},
 			},
 			wantArgs: []string{
-				"create", "-o", "quota=what", "tank/my-dataset",
+				"create", "-o", "recordsize=3K", "tank/my-dataset",
 			},
-			stderr: `cannot create 'tank/my-dataset': bad numeric value 'what'
+			//nolint:lll
+			stderr: `cannot create 'tank/my-dataset': 'recordsize' must be power of 2 from 512B to 1M
 usage:
 	create [-Pnpuv] [-o property=value] ... <filesystem>
 	create [-Pnpsv] [-b blocksize] [-o property=value] ... -V <size> <volume>
 `,
 			commandErr: errors.New("exit status 1"),
-			wantErr: "zfs; exit status 1: cannot create 'tank/my-dataset': " +
-				"bad numeric value 'what'",
-			wantErrTargets: []error{Err, ErrZFS},
+			wantErr: "zfs; invalid property; exit status 1: " +
+				"cannot create 'tank/my-dataset': " +
+				"'recordsize' must be power of 2 from 512B to 1M",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "invalid property value",
+			args: args{
+				options: &CreateDatasetOptions{
+					Name: "tank/my-dataset",
+					Properties: map[string]string{
+						zfsprops.Quota: "what",
+					},
+				},
+			},
+			wantErr: "zfs; invalid property: quota: invalid value " +
+				"'what', must be a size like 10G, or one of: none",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "read-only property",
+			args: args{
+				options: &CreateDatasetOptions{
+					Name: "tank/my-dataset",
+					Properties: map[string]string{
+						zfsprops.Creation: "1234",
+					},
+				},
+			},
+			wantErr: "zfs; invalid property: creation: " +
+				"property is read-only",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "filesystem property on volume",
+			args: args{
+				options: &CreateDatasetOptions{
+					Name:       "tank/my-volume",
+					VolumeSize: "10G",
+					Properties: map[string]string{
+						zfsprops.RecordSize: "128K",
+					},
+				},
+			},
+			wantErr: "zfs; invalid property: recordsize: " +
+				"property is not valid for volumes",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "create-only property",
+			args: args{
+				options: &CreateDatasetOptions{
+					Name: "tank/my-dataset",
+					Properties: map[string]string{
+						zfsprops.CaseSensitivity: "insensitive",
+					},
+				},
+			},
+			wantArgs: []string{
+				"create", "-o", "casesensitivity=insensitive",
+				"tank/my-dataset",
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -1161,21 +1339,30 @@ usage:
 			ctrl := gomock.NewController(t)
 			r := mock_runner.NewMockRunner(ctrl)
 			if len(tt.wantArgs) > 0 {
+				var stdinMatcher gomock.Matcher = gomock.Nil()
+				if tt.wantStdin != "" {
+					stdinMatcher = gomock.Not(gomock.Nil())
+				}
 				r.EXPECT().RunContext(
 					gomockctx.Eq(ctx),
-					gomock.Nil(),
+					stdinMatcher,
 					gomock.AssignableToTypeOf(ioWriter),
 					gomock.AssignableToTypeOf(ioWriter),
 					"zfs",
 					tt.wantArgs,
 				).DoAndReturn(func(
 					_ context.Context,
-					_ io.Reader,
+					stdin io.Reader,
 					_ io.Writer,
 					stderr io.Writer,
 					_ string,
 					_ ...string,
 				) error {
+					if stdin != nil {
+						b, err := io.ReadAll(stdin)
+						require.NoError(t, err)
+						assert.Equal(t, tt.wantStdin, string(b))
+					}
 					_, _ = stderr.Write([]byte(tt.stderr))
 
 					return tt.commandErr
@@ -1223,7 +1410,7 @@ func TestManager_GetDataset(t *testing.T) {
 			args: args{
 				name: "",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -1235,7 +1422,8 @@ func TestManager_GetDataset(t *testing.T) {
 			args: args{
 				name: "/tank/my-dataset",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name '/tank/my-dataset': " +
+				"leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -1247,7 +1435,34 @@ func TestManager_GetDataset(t *testing.T) {
 			args: args{
 				name: "tank/my-dataset/",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name 'tank/my-dataset/': " +
+				"trailing slash in name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "parent reference name",
+			args: args{
+				name: "tank/../my-dataset",
+			},
+			wantErr: "zfs; invalid name 'tank/../my-dataset': " +
+				"parent reference, '..' is found in name",
+			wantErrTargets: []error{
+				Err,
+				ErrZFS,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "invalid character name",
+			args: args{
+				name: "tank/my*dataset",
+			},
+			wantErr: "zfs; invalid name 'tank/my*dataset': " +
+				"invalid character '*' in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -2100,7 +2315,7 @@ usage:
 			}
 
 			require.NoError(t, err)
-			assert.ElementsMatch(t, tt.want, got)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
@@ -2399,7 +2614,7 @@ func TestManager_DestroyDataset(t *testing.T) {
 			args: args{
 				name: "",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -2411,7 +2626,8 @@ func TestManager_DestroyDataset(t *testing.T) {
 			args: args{
 				name: "/tank/my-dataset",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name '/tank/my-dataset': " +
+				"leading slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
@@ -2423,13 +2639,45 @@ func TestManager_DestroyDataset(t *testing.T) {
 			args: args{
 				name: "tank/my-dataset/",
 			},
-			wantErr: "zfs; invalid name",
+			wantErr: "zfs; invalid name 'tank/my-dataset/': " +
+				"trailing slash in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZFS,
 				ErrInvalidName,
 			},
 		},
+		{
+			name: "snapshot range",
+			args: args{
+				name:  "tank/my-dataset@a%b",
+				flags: []DestroyDatasetFlag{DestroyDeferDeletion},
+			},
+			wantArgs: []string{"destroy", "-d", "tank/my-dataset@a%b"},
+		},
+		{
+			name: "snapshot list",
+			args: args{
+				name: "tank/my-dataset@a,b",
+			},
+			wantArgs: []string{"destroy", "tank/my-dataset@a,b"},
+		},
+		{
+			name: "snapshot list with ranges",
+			args: args{
+				name: "tank/my-dataset@a,c%e,%g",
+			},
+			wantArgs: []string{"destroy", "tank/my-dataset@a,c%e,%g"},
+		},
+		{
+			name: "invalid snapshot list",
+			args: args{
+				name: "tank/my-dataset@a,,b",
+			},
+			wantErr: "zfs; invalid name 'tank/my-dataset@a,,b': " +
+				"empty component or misplaced '@' or '#' delimiter in name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
 		{
 			name: "zerod flag",
 			args: args{
@@ -2583,3 +2831,446 @@ usage:
 		})
 	}
 }
+
+func TestManager_GetDatasetBySource(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name       string
+		sources    []SourceKind
+		properties []string
+		wantArgs   []string
+		stdout     string
+		want       *Dataset
+	}{
+		{
+			name: "no sources",
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"all", "tank/my-dataset",
+			},
+			stdout: "tank/my-dataset\tquota\t0\tdefault\n",
+			want: &Dataset{
+				Name: "tank/my-dataset",
+				Properties: Properties{
+					"quota": {
+						Name:     "tank/my-dataset",
+						Property: "quota",
+						Value:    "0",
+						Source:   "default",
+					},
+				},
+			},
+		},
+		{
+			name:       "local and received",
+			sources:    []SourceKind{SourceLocal, SourceReceived},
+			properties: []string{"quota", "compression"},
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"-s", "local,received", "quota,compression",
+				"tank/my-dataset",
+			},
+			stdout: "tank/my-dataset\tcompression\tlz4\treceived\n",
+			want: &Dataset{
+				Name: "tank/my-dataset",
+				Properties: Properties{
+					"compression": {
+						Name:     "tank/my-dataset",
+						Property: "compression",
+						Value:    "lz4",
+						Source:   "received",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				gomock.Nil(),
+				gomock.AssignableToTypeOf(ioWriter),
+				gomock.AssignableToTypeOf(ioWriter),
+				"zfs",
+				tt.wantArgs,
+			).DoAndReturn(func(
+				_ context.Context,
+				_ io.Reader,
+				stdout io.Writer,
+				_ io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				_, _ = stdout.Write([]byte(tt.stdout))
+
+				return nil
+			})
+
+			m := &Manager{Runner: r}
+
+			got, err := m.GetDatasetBySource(
+				ctx, "tank/my-dataset", tt.sources, tt.properties...,
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_GetDatasetReceived(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx),
+		gomock.Nil(),
+		gomock.AssignableToTypeOf(ioWriter),
+		gomock.AssignableToTypeOf(ioWriter),
+		"zfs",
+		[]string{
+			"get", "-Hp", "-o", "name,property,value,received,source",
+			"compression,quota", "backup/www",
+		},
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout io.Writer,
+		_ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = stdout.Write([]byte(
+			"backup/www\tcompression\tzstd\tlz4\tlocal\n" +
+				"backup/www\tquota\t0\t-\tdefault\n",
+		))
+
+		return nil
+	})
+
+	m := &Manager{Runner: r}
+
+	got, err := m.GetDatasetReceived(
+		ctx, "backup/www", "compression", "quota",
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Dataset{
+		Name: "backup/www",
+		Properties: Properties{
+			"compression": {
+				Name:     "backup/www",
+				Property: "compression",
+				Value:    "zstd",
+				Received: "lz4",
+				Source:   "local",
+			},
+			"quota": {
+				Name:     "backup/www",
+				Property: "quota",
+				Value:    "0",
+				Received: "-",
+				Source:   "default",
+			},
+		},
+	}, got)
+	assert.True(t, got.OverridesReceived("compression"))
+	assert.False(t, got.OverridesReceived("quota"))
+
+	_, err = m.GetDatasetReceived(ctx, "/backup")
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+func TestManager_RevertToReceived(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	type args struct {
+		name      string
+		property  string
+		recursive bool
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantArgs       []string
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name: "invalid name",
+			args: args{name: "backup/www/", property: "quota"},
+			wantErr: "zfs; invalid name 'backup/www/': " +
+				"trailing slash in name",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidName},
+		},
+		{
+			name:           "empty property",
+			args:           args{name: "backup/www"},
+			wantErr:        "zfs; invalid property",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+		{
+			name: "non-recursive",
+			args: args{name: "backup/www", property: "compression"},
+			wantArgs: []string{
+				"inherit", "-S", "compression", "backup/www",
+			},
+		},
+		{
+			name: "recursive",
+			args: args{
+				name:      "backup/www",
+				property:  "compression",
+				recursive: true,
+			},
+			wantArgs: []string{
+				"inherit", "-r", "-S", "compression", "backup/www",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).Return(nil)
+			}
+
+			m := &Manager{Runner: r}
+
+			err := m.RevertToReceived(
+				ctx, tt.args.name, tt.args.property, tt.args.recursive,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_ListDatasetsWithOptions(t *testing.T) {
+	ioWriter := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	tests := []struct {
+		name           string
+		options        *ListDatasetsOptions
+		wantArgs       []string
+		stdout         string
+		want           []string
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name:    "nil options",
+			options: nil,
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source", "all",
+			},
+			stdout: "tank\tused\t1024\t-\n",
+			want:   []string{"tank"},
+		},
+		{
+			name: "only named datasets",
+			options: &ListDatasetsOptions{
+				Names:      []string{"tank/a", "tank/b"},
+				Properties: []string{"used"},
+			},
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"used", "tank/a", "tank/b",
+			},
+			stdout: "tank/a\tused\t1024\t-\ntank/b\tused\t2048\t-\n",
+			want:   []string{"tank/a", "tank/b"},
+		},
+		{
+			name: "recursive with types and sources",
+			options: &ListDatasetsOptions{
+				Names:     []string{"tank"},
+				Recursive: true,
+				Types:     []DatasetType{FilesystemType, VolumeType},
+				Sources:   []SourceKind{SourceLocal},
+			},
+			wantArgs: []string{
+				"get", "-Hp", "-o", "name,property,value,source",
+				"-s", "local", "-r", "-t", "filesystem,volume",
+				"all", "tank",
+			},
+			stdout: "tank/a\tquota\t1024\tlocal\n",
+			want:   []string{"tank/a"},
+		},
+		{
+			name:    "snapshots of",
+			options: SnapshotsOf("tank/www", "used"),
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,used,createtxg",
+				"-d", "1", "-t", "snapshot", "-s", "createtxg", "tank/www",
+			},
+			stdout: "tank/www@a\t0\t10\ntank/www@b\t0\t20\n",
+			want:   []string{"tank/www@a", "tank/www@b"},
+		},
+		{
+			name:    "snapshots of without properties",
+			options: SnapshotsOf("tank/www"),
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,createtxg",
+				"-d", "1", "-t", "snapshot", "-s", "createtxg", "tank/www",
+			},
+			stdout: "tank/www@a\t10\ntank/www@b\t20\n",
+			want:   []string{"tank/www@a", "tank/www@b"},
+		},
+		{
+			name: "no sources",
+			options: &ListDatasetsOptions{
+				Names:      []string{"tank"},
+				Recursive:  true,
+				Properties: []string{"used"},
+				NoSources:  true,
+			},
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,used", "-r", "-t", "all", "tank",
+			},
+			stdout: "tank\t300\ntank/a\t100\ntank/a@snap\t0\n",
+			want:   []string{"tank", "tank/a", "tank/a@snap"},
+		},
+		{
+			name: "no sources with sort",
+			options: &ListDatasetsOptions{
+				Names:      []string{"tank"},
+				Depth:      1,
+				Properties: []string{"quota"},
+				Sort:       []SortKey{{Property: "used", Descending: true}},
+				NoSources:  true,
+			},
+			wantArgs: []string{
+				"list", "-Hp", "-o", "name,quota,used",
+				"-d", "1", "-t", "all", "-S", "used", "tank",
+			},
+			stdout: "tank\t0\t300\ntank/b\t0\t200\ntank/a\t0\t100\n",
+			want:   []string{"tank", "tank/b", "tank/a"},
+		},
+		{
+			name: "sort without no sources",
+			options: &ListDatasetsOptions{
+				Properties: []string{"quota"},
+				Sort:       []SortKey{{Property: "used"}},
+			},
+			wantErr: "zfs; invalid list options: " +
+				"Sort requires NoSources",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidListOptions},
+		},
+		{
+			name: "no sources without properties",
+			options: &ListDatasetsOptions{
+				NoSources: true,
+			},
+			wantErr: "zfs; invalid list options: " +
+				"NoSources requires Properties",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidListOptions},
+		},
+		{
+			name: "no sources with sources",
+			options: &ListDatasetsOptions{
+				Properties: []string{"used"},
+				Sources:    []SourceKind{SourceLocal},
+				NoSources:  true,
+			},
+			wantErr: "zfs; invalid list options: " +
+				"NoSources cannot be combined with Sources",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidListOptions},
+		},
+		{
+			name: "invalid name",
+			options: &ListDatasetsOptions{
+				Names: []string{"tank", "/tank/a"},
+			},
+			wantErr: "zfs; invalid list options; invalid name '/tank/a': " +
+				"leading slash in name",
+			wantErrTargets: []error{
+				Err, ErrZFS, ErrInvalidListOptions, ErrInvalidName,
+			},
+		},
+		{
+			name: "invalid property",
+			options: &ListDatasetsOptions{
+				Properties: []string{"used,quota"},
+			},
+			wantErr: "zfs; invalid list options: " +
+				"invalid property 'used,quota'",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidListOptions},
+		},
+		{
+			name: "invalid sort key",
+			options: &ListDatasetsOptions{
+				Sort: []SortKey{{Property: "all"}},
+			},
+			wantErr: "zfs; invalid property: invalid sort property 'all'",
+			wantErrTargets: []error{Err, ErrZFS, ErrInvalidProperty},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gomockctx.New(context.Background())
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if len(tt.wantArgs) > 0 {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					gomock.Nil(),
+					gomock.AssignableToTypeOf(ioWriter),
+					gomock.AssignableToTypeOf(ioWriter),
+					"zfs",
+					tt.wantArgs,
+				).DoAndReturn(func(
+					_ context.Context,
+					_ io.Reader,
+					stdout io.Writer,
+					_ io.Writer,
+					_ string,
+					_ ...string,
+				) error {
+					_, _ = stdout.Write([]byte(tt.stdout))
+
+					return nil
+				})
+			}
+			m := &Manager{Runner: r}
+
+			got, err := m.ListDatasetsWithOptions(ctx, tt.options)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Empty(t, got)
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, datasetNames(got))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package zfsmock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/krystal/go-zfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ zfs.DatasetManager = &MockDatasetManager{}
+	_ zfs.MountManager   = &MockMountManager{}
+	_ zfs.KeyManager     = &MockKeyManager{}
+	_ zfs.PoolManager    = &MockPoolManager{}
+	_ zfs.Interface      = &MockInterface{}
+)
+
+// snapshotNames is an example of code accepting a zfs.DatasetManager.
+func snapshotNames(
+	ctx context.Context,
+	m zfs.DatasetManager,
+	name string,
+) ([]string, error) {
+	return m.ListDatasetNames(ctx, name, 1, zfs.SnapshotType)
+}
+
+func TestMockInterface(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	m := NewMockInterface(ctrl)
+
+	m.EXPECT().
+		ListDatasetNames(ctx, "tank/data", uint64(1), zfs.SnapshotType).
+		Return([]string{"tank/data@daily"}, nil)
+
+	got, err := snapshotNames(ctx, m, "tank/data")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tank/data@daily"}, got)
+}
+
+func TestMockDatasetManager_variadic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	m := NewMockDatasetManager(ctrl)
+
+	m.EXPECT().
+		GetDataset(ctx, "tank", "used", "available").
+		Return(&zfs.Dataset{Name: "tank"}, nil)
+	m.EXPECT().
+		DestroyDataset(ctx, "tank/data", zfs.DestroyRecursive).
+		Return(zfs.ErrBusy)
+
+	ds, err := m.GetDataset(ctx, "tank", "used", "available")
+	require.NoError(t, err)
+	assert.Equal(t, "tank", ds.Name)
+
+	err = m.DestroyDataset(ctx, "tank/data", zfs.DestroyRecursive)
+	assert.ErrorIs(t, err, zfs.ErrBusy)
+}
@@ -0,0 +1,637 @@
+package zfsprops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/krystal/go-zfs/internal/propval"
+)
+
+// Kind is the kind of value a property holds.
+type Kind int
+
+const (
+	// KindString properties hold arbitrary strings, optionally restricted by
+	// a pattern.
+	KindString Kind = iota + 1
+
+	// KindBool properties hold "on" or "off".
+	KindBool
+
+	// KindEnum properties hold one of a fixed set of values.
+	KindEnum
+
+	// KindSize properties hold a size, like "10G".
+	KindSize
+
+	// KindNumber properties hold an unsigned integer.
+	KindNumber
+)
+
+// String returns the name of the kind, for example "bool".
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	case KindEnum:
+		return "enum"
+	case KindSize:
+		return "size"
+	case KindNumber:
+		return "number"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Access describes if and when a property can be set.
+type Access int
+
+const (
+	// AccessReadOnly properties cannot be set.
+	AccessReadOnly Access = iota + 1
+
+	// AccessSettable properties can be set at any time.
+	AccessSettable
+
+	// AccessCreateOnly properties can only be set when creating a dataset.
+	AccessCreateOnly
+)
+
+// DatasetTypes is a bit mask of the dataset types a property applies to.
+type DatasetTypes int
+
+const (
+	// TypeFilesystem is filesystem datasets.
+	TypeFilesystem DatasetTypes = 1 << iota
+
+	// TypeVolume is volume datasets.
+	TypeVolume
+
+	// TypeSnapshot is snapshots.
+	TypeSnapshot
+
+	// TypeBookmark is bookmarks.
+	TypeBookmark
+
+	// TypeDataset is filesystems and volumes.
+	TypeDataset = TypeFilesystem | TypeVolume
+
+	// TypeAll is all dataset types.
+	TypeAll = TypeDataset | TypeSnapshot | TypeBookmark
+)
+
+// Has reports whether t includes all types of other.
+func (t DatasetTypes) Has(other DatasetTypes) bool {
+	return t&other == other
+}
+
+// Property is machine-readable metadata of a property.
+type Property struct {
+	// Name of the property.
+	Name string
+
+	// Aliases are alternative short names accepted by zfs, like "compress"
+	// for "compression".
+	Aliases []string
+
+	// Kind of value the property holds.
+	Kind Kind
+
+	// Values are the accepted values of KindBool and KindEnum properties, and
+	// special values accepted in addition to sizes, numbers, or patterns for
+	// other kinds, like "none".
+	Values []string
+
+	// Pattern optionally matches additional accepted values. For KindString
+	// properties with a Pattern, only matching values and Values are
+	// accepted.
+	Pattern *regexp.Regexp
+
+	// Access describes if and when the property can be set.
+	Access Access
+
+	// Inheritable indicates whether the property is inherited from parent
+	// datasets, and can be reset to the inherited value with zfs inherit.
+	Inheritable bool
+
+	// Types are the dataset types the property applies to.
+	Types DatasetTypes
+}
+
+// Validate returns an error describing the accepted values if value is not
+// valid for the property.
+func (p *Property) Validate(value string) error {
+	if propval.OneOf(value, p.Values) ||
+		(p.Pattern != nil && p.Pattern.MatchString(value)) {
+		return nil
+	}
+
+	switch p.Kind {
+	case KindSize:
+		if propval.Size(value) {
+			return nil
+		}
+	case KindNumber:
+		if propval.Number(value) {
+			return nil
+		}
+	case KindString:
+		if p.Pattern == nil && len(p.Values) == 0 {
+			return nil
+		}
+	case KindBool, KindEnum:
+	}
+
+	return fmt.Errorf(
+		"%s: invalid value '%s', must be %s",
+		p.Name, value, propval.Describe(p.Kind.String(), p.Values, p.Pattern),
+	)
+}
+
+var (
+	onOff = []string{"on", "off"}
+	none  = []string{"none"}
+
+	compressionPattern = regexp.MustCompile(
+		`^(gzip-[1-9]|zstd-([1-9]|1[0-9])|` +
+			`zstd-fast-([1-9]|[1-9]0|100|500|1000))$`,
+	)
+)
+
+// properties is the metadata of all native properties, sorted by name.
+var properties = []*Property{
+	{
+		Name: ACLInherit, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{
+			"discard", "noallow", "restricted", "passthrough", "passthrough-x",
+		},
+	},
+	{
+		Name: ACLMode, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"discard", "groupmask", "passthrough", "restricted"},
+	},
+	{
+		Name: ACLType, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"off", "noacl", "nfsv4", "posix", "posixacl"},
+	},
+	{
+		Name: Atime, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: Available, Aliases: []string{"avail"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeDataset,
+	},
+	{
+		Name: CanMount, Kind: KindEnum, Access: AccessSettable,
+		Types:  TypeFilesystem,
+		Values: []string{"on", "off", "noauto"},
+	},
+	{
+		Name: CaseSensitivity, Kind: KindEnum, Access: AccessCreateOnly,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"sensitive", "insensitive", "mixed"},
+	},
+	{
+		Name: Checksum, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{
+			"on", "off", "fletcher2", "fletcher4", "sha256", "noparity",
+			"sha512", "skein", "edonr", "blake3",
+		},
+	},
+	{
+		Name: Clones, Kind: KindString, Access: AccessReadOnly,
+		Types: TypeSnapshot,
+	},
+	{
+		Name: Compression, Aliases: []string{"compress"}, Kind: KindEnum,
+		Access: AccessSettable, Inheritable: true, Types: TypeDataset,
+		Values: []string{
+			"on", "off", "gzip", "lz4", "lzjb", "zle", "zstd", "zstd-fast",
+		},
+		Pattern: compressionPattern,
+	},
+	{
+		Name: CompressRatio, Aliases: []string{"ratio"}, Kind: KindString,
+		Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: Context, Kind: KindString, Access: AccessSettable,
+		Types: TypeDataset,
+	},
+	{
+		Name: Copies, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"1", "2", "3"},
+	},
+	{
+		Name: CreateTxGroup, Kind: KindNumber, Access: AccessReadOnly,
+		Types: TypeAll,
+	},
+	{
+		Name: Creation, Kind: KindNumber, Access: AccessReadOnly,
+		Types: TypeAll,
+	},
+	{
+		Name: Dedup, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{
+			"off", "on", "verify", "sha256", "sha256,verify", "sha512",
+			"sha512,verify", "skein", "skein,verify", "edonr,verify",
+			"blake3", "blake3,verify",
+		},
+	},
+	{
+		Name: DefContext, Kind: KindString, Access: AccessSettable,
+		Types: TypeDataset,
+	},
+	{
+		Name: DeferDestroy, Kind: KindBool, Values: onOff,
+		Access: AccessReadOnly, Types: TypeSnapshot,
+	},
+	{
+		Name: Devices, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: DNodeSize, Aliases: []string{"dnsize"}, Kind: KindEnum,
+		Access: AccessSettable, Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"legacy", "auto", "1k", "2k", "4k", "8k", "16k"},
+	},
+	{
+		Name: Encryption, Kind: KindEnum, Access: AccessCreateOnly,
+		Types: TypeDataset,
+		Values: []string{
+			"off", "on", "aes-128-ccm", "aes-192-ccm", "aes-256-ccm",
+			"aes-128-gcm", "aes-192-gcm", "aes-256-gcm",
+		},
+	},
+	{
+		Name: EncryptionRoot, Kind: KindString, Access: AccessReadOnly,
+		Types: TypeDataset,
+	},
+	{
+		Name: Exec, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: FilesystemCount, Kind: KindNumber, Access: AccessReadOnly,
+		Types: TypeFilesystem,
+	},
+	{
+		Name: FilesystemLimit, Kind: KindNumber, Values: none,
+		Access: AccessSettable, Types: TypeFilesystem,
+	},
+	{
+		Name: FSContext, Kind: KindString, Access: AccessSettable,
+		Types: TypeDataset,
+	},
+	{
+		Name: GUID, Kind: KindNumber, Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: Jailed, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: KeyFormat, Kind: KindEnum, Access: AccessCreateOnly,
+		Types: TypeDataset, Values: []string{"raw", "hex", "passphrase"},
+	},
+	{
+		Name: KeyLocation, Kind: KindString, Access: AccessSettable,
+		Types: TypeDataset, Values: []string{"none", "prompt"},
+		Pattern: regexp.MustCompile(`^(file:///|https?://).+`),
+	},
+	{
+		Name: KeyStatus, Kind: KindEnum, Access: AccessReadOnly,
+		Types:  TypeDataset,
+		Values: []string{"none", "available", "unavailable"},
+	},
+	{
+		Name: LogBias, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"latency", "throughput"},
+	},
+	{
+		Name: LogicalReferenced, Aliases: []string{"lrefer"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: LogicalUsed, Aliases: []string{"lused"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeDataset,
+	},
+	{
+		Name: Mounted, Kind: KindBool, Values: []string{"yes", "no"},
+		Access: AccessReadOnly, Types: TypeFilesystem,
+	},
+	{
+		Name: Mountpoint, Kind: KindString, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"none", "legacy"}, Pattern: regexp.MustCompile(`^/`),
+	},
+	{
+		Name: Nbmand, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: Normalization, Kind: KindEnum, Access: AccessCreateOnly,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"none", "formC", "formD", "formKC", "formKD"},
+	},
+	{
+		Name: ObjsetID, Kind: KindNumber, Access: AccessReadOnly,
+		Types: TypeAll,
+	},
+	{
+		Name: Origin, Kind: KindString, Access: AccessReadOnly,
+		Types: TypeDataset,
+	},
+	{
+		Name: Overlay, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: PBKDF2Iterations, Kind: KindNumber, Access: AccessCreateOnly,
+		Types: TypeDataset,
+	},
+	{
+		Name: PrimaryCache, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"all", "none", "metadata"},
+	},
+	{
+		Name: Quota, Kind: KindSize, Values: none, Access: AccessSettable,
+		Types: TypeFilesystem,
+	},
+	{
+		Name: ReadOnly, Aliases: []string{"rdonly"}, Kind: KindBool,
+		Values: onOff, Access: AccessSettable, Inheritable: true,
+		Types: TypeDataset,
+	},
+	{
+		Name: ReceiveResumeToken, Kind: KindString, Access: AccessReadOnly,
+		Types: TypeDataset,
+	},
+	{
+		Name: RecordSize, Aliases: []string{"recsize"}, Kind: KindSize,
+		Access: AccessSettable, Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: RedactSnaps, Kind: KindString, Access: AccessReadOnly,
+		Types: TypeDataset,
+	},
+	{
+		Name: RedundantMetadata, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"all", "most", "some", "none"},
+	},
+	{
+		Name: RefCompressRatio, Aliases: []string{"refratio"}, Kind: KindString,
+		Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: Referenced, Aliases: []string{"refer"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: RefQuota, Kind: KindSize, Values: none, Access: AccessSettable,
+		Types: TypeFilesystem,
+	},
+	{
+		Name: RefReservation, Aliases: []string{"refreserv"}, Kind: KindSize,
+		Values: []string{"none", "auto"}, Access: AccessSettable,
+		Types: TypeDataset,
+	},
+	{
+		Name: RelAtime, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: Reservation, Aliases: []string{"reserv"}, Kind: KindSize,
+		Values: none, Access: AccessSettable, Types: TypeDataset,
+	},
+	{
+		Name: RootContext, Kind: KindString, Access: AccessSettable,
+		Types: TypeDataset,
+	},
+	{
+		Name: SecondaryCache, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"all", "none", "metadata"},
+	},
+	{
+		Name: SetUID, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: ShareNFS, Kind: KindString, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: ShareSMB, Kind: KindString, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: SnapDev, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"hidden", "visible"},
+	},
+	{
+		Name: SnapDir, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"hidden", "visible"},
+	},
+	{
+		Name: SnapshotCount, Kind: KindNumber, Access: AccessReadOnly,
+		Types: TypeDataset,
+	},
+	{
+		Name: SnapshotLimit, Kind: KindNumber, Values: none,
+		Access: AccessSettable, Types: TypeDataset,
+	},
+	{
+		Name: SpecialSmallBlocks, Kind: KindSize, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: Sync, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeDataset,
+		Values: []string{"standard", "always", "disabled"},
+	},
+	{
+		Name: Type, Kind: KindEnum, Access: AccessReadOnly, Types: TypeAll,
+		Values: []string{"filesystem", "volume", "snapshot", "bookmark"},
+	},
+	{
+		Name: Used, Kind: KindSize, Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: UsedByChildren, Aliases: []string{"usedchild"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeDataset,
+	},
+	{
+		Name: UsedByDataset, Aliases: []string{"usedds"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeDataset,
+	},
+	{
+		Name: UsedByRefReservation, Aliases: []string{"usedrefreserv"},
+		Kind: KindSize, Access: AccessReadOnly, Types: TypeDataset,
+	},
+	{
+		Name: UsedBySnapshots, Aliases: []string{"usedsnap"}, Kind: KindSize,
+		Access: AccessReadOnly, Types: TypeDataset,
+	},
+	{
+		Name: UTF8Only, Kind: KindBool, Values: onOff,
+		Access: AccessCreateOnly, Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: Version, Kind: KindNumber, Values: []string{"current"},
+		Access: AccessSettable, Types: TypeFilesystem,
+	},
+	{
+		Name: VolBlockSize, Aliases: []string{"volblock"}, Kind: KindSize,
+		Access: AccessCreateOnly, Types: TypeVolume,
+	},
+	{
+		Name: VolMode, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeVolume,
+		Values: []string{"default", "full", "geom", "dev", "none"},
+	},
+	{
+		Name: VolSize, Kind: KindSize, Access: AccessSettable,
+		Types: TypeVolume,
+	},
+	{
+		Name: VScan, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+	{
+		Name: Written, Kind: KindSize, Access: AccessReadOnly, Types: TypeAll,
+	},
+	{
+		Name: XAttr, Kind: KindEnum, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+		Values: []string{"on", "off", "sa", "dir"},
+	},
+	{
+		Name: Zoned, Kind: KindBool, Values: onOff, Access: AccessSettable,
+		Inheritable: true, Types: TypeFilesystem,
+	},
+}
+
+// byName indexes properties by name and aliases.
+var byName = func() map[string]*Property {
+	m := map[string]*Property{}
+	for _, p := range properties {
+		m[p.Name] = p
+		for _, alias := range p.Aliases {
+			m[alias] = p
+		}
+	}
+
+	return m
+}()
+
+// quotaPrefixes are the prefixes of user, group, and project quota and usage
+// properties, mapped to whether they are read-only.
+var quotaPrefixes = map[string]bool{
+	"userquota@":       false,
+	"userobjquota@":    false,
+	"groupquota@":      false,
+	"groupobjquota@":   false,
+	"projectquota@":    false,
+	"projectobjquota@": false,
+	"userused@":        true,
+	"userobjused@":     true,
+	"groupused@":       true,
+	"groupobjused@":    true,
+	"projectused@":     true,
+	"projectobjused@":  true,
+}
+
+// Properties returns a copy of the metadata of all native properties, sorted
+// by name. User properties, and user, group, and project quota properties are
+// not included, but are supported by Lookup.
+func Properties() []*Property {
+	r := make([]*Property, len(properties))
+	for i, p := range properties {
+		r[i] = p.clone()
+	}
+
+	return r
+}
+
+// Lookup returns the metadata of the named property. Aliases, like
+// "compress", resolve to their native property.
+//
+// Quota properties, like "userquota@john", and user properties containing a
+// colon (":"), like "com.example:owner", are also supported, with metadata
+// describing them generically.
+//
+// The returned metadata is a copy, which can safely be modified. The second
+// return value indicates if the property is known.
+func Lookup(name string) (*Property, bool) {
+	if p, ok := byName[name]; ok {
+		return p.clone(), true
+	}
+
+	if i := strings.Index(name, "@"); i != -1 && i+1 < len(name) {
+		if readonly, ok := quotaPrefixes[name[:i+1]]; ok {
+			p := &Property{
+				Name:   name,
+				Kind:   KindSize,
+				Access: AccessSettable,
+				Types:  TypeFilesystem,
+			}
+			if readonly {
+				p.Access = AccessReadOnly
+			} else {
+				p.Values = none
+			}
+			if strings.HasPrefix(name, "project") {
+				p.Types = TypeDataset
+			}
+
+			return p, true
+		}
+	}
+
+	if strings.Contains(name, ":") && !strings.HasPrefix(name, "-") {
+		return &Property{
+			Name:        name,
+			Kind:        KindString,
+			Access:      AccessSettable,
+			Inheritable: true,
+			Types:       TypeAll,
+		}, true
+	}
+
+	return nil, false
+}
+
+// clone returns a deep copy of p.
+func (p *Property) clone() *Property {
+	c := *p
+	c.Aliases = append([]string(nil), p.Aliases...)
+	c.Values = append([]string(nil), p.Values...)
+
+	return &c
+}
+
+// Valid reports whether property is a native property defined by this
+// package or one of its aliases, a user, group, or project quota property, or
+// a user property containing a colon (":").
+func Valid(property string) bool {
+	_, ok := Lookup(property)
+
+	return ok
+}
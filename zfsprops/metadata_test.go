@@ -0,0 +1,223 @@
+package zfsprops
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProperties_Metadata(t *testing.T) {
+	props := Properties()
+	require.NotEmpty(t, props)
+
+	names := make([]string, 0, len(props))
+	seen := map[string]bool{}
+	for _, p := range props {
+		names = append(names, p.Name)
+
+		assert.False(t, seen[p.Name], "duplicate property %s", p.Name)
+		seen[p.Name] = true
+		for _, alias := range p.Aliases {
+			assert.False(t, seen[alias], "duplicate alias %s", alias)
+			seen[alias] = true
+		}
+
+		assert.NotZero(t, p.Access, "%s has no access", p.Name)
+		assert.NotZero(t, p.Types, "%s has no types", p.Name)
+		if p.Kind == KindBool || p.Kind == KindEnum {
+			assert.NotEmpty(t, p.Values, "%s has no values", p.Name)
+		}
+	}
+	assert.True(t, sort.StringsAreSorted(names), "properties are not sorted")
+
+	props[0] = nil
+	assert.NotNil(t, Properties()[0])
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantName   string
+		wantKind   Kind
+		wantAccess Access
+		wantTypes  DatasetTypes
+		wantOK     bool
+	}{
+		{
+			name:       "compression",
+			wantName:   "compression",
+			wantKind:   KindEnum,
+			wantAccess: AccessSettable,
+			wantTypes:  TypeDataset,
+			wantOK:     true,
+		},
+		{
+			name:       "compress",
+			wantName:   "compression",
+			wantKind:   KindEnum,
+			wantAccess: AccessSettable,
+			wantTypes:  TypeDataset,
+			wantOK:     true,
+		},
+		{
+			name:       "used",
+			wantName:   "used",
+			wantKind:   KindSize,
+			wantAccess: AccessReadOnly,
+			wantTypes:  TypeAll,
+			wantOK:     true,
+		},
+		{
+			name:       "casesensitivity",
+			wantName:   "casesensitivity",
+			wantKind:   KindEnum,
+			wantAccess: AccessCreateOnly,
+			wantTypes:  TypeFilesystem,
+			wantOK:     true,
+		},
+		{
+			name:       "userquota@john",
+			wantName:   "userquota@john",
+			wantKind:   KindSize,
+			wantAccess: AccessSettable,
+			wantTypes:  TypeFilesystem,
+			wantOK:     true,
+		},
+		{
+			name:       "userused@john",
+			wantName:   "userused@john",
+			wantKind:   KindSize,
+			wantAccess: AccessReadOnly,
+			wantTypes:  TypeFilesystem,
+			wantOK:     true,
+		},
+		{
+			name:       "projectquota@42",
+			wantName:   "projectquota@42",
+			wantKind:   KindSize,
+			wantAccess: AccessSettable,
+			wantTypes:  TypeDataset,
+			wantOK:     true,
+		},
+		{
+			name:       "com.example:owner",
+			wantName:   "com.example:owner",
+			wantKind:   KindString,
+			wantAccess: AccessSettable,
+			wantTypes:  TypeAll,
+			wantOK:     true,
+		},
+		{name: "userquota@"},
+		{name: "-bad:prop"},
+		{name: "nope"},
+		{name: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Lookup(tt.name)
+
+			assert.Equal(t, tt.wantOK, ok)
+			if !tt.wantOK {
+				assert.Nil(t, got)
+
+				return
+			}
+
+			require.NotNil(t, got)
+			assert.Equal(t, tt.wantName, got.Name)
+			assert.Equal(t, tt.wantKind, got.Kind)
+			assert.Equal(t, tt.wantAccess, got.Access)
+			assert.Equal(t, tt.wantTypes, got.Types)
+		})
+	}
+}
+
+func TestLookup_copy(t *testing.T) {
+	p, ok := Lookup(Compression)
+	require.True(t, ok)
+	p.Access = AccessReadOnly
+	p.Values[0] = "changed"
+
+	for _, props := range Properties() {
+		if props.Name == Compression {
+			props.Aliases = nil
+		}
+	}
+
+	got, ok := Lookup(Compression)
+	require.True(t, ok)
+	assert.Equal(t, AccessSettable, got.Access)
+	assert.NotEqual(t, "changed", got.Values[0])
+	assert.NotEmpty(t, got.Aliases)
+}
+
+func TestProperty_Validate(t *testing.T) {
+	tests := []struct {
+		property string
+		value    string
+		wantErr  string
+	}{
+		{property: Atime, value: "on"},
+		{property: Atime, value: "off"},
+		{
+			property: Atime,
+			value:    "yes",
+			wantErr:  "atime: invalid value 'yes', must be one of: on, off",
+		},
+		{property: Compression, value: "lz4"},
+		{property: Compression, value: "gzip-9"},
+		{property: Compression, value: "zstd-19"},
+		{
+			property: Compression,
+			value:    "gzip-10",
+			wantErr: "compression: invalid value 'gzip-10', must be " +
+				"one of: on, off, gzip, lz4, lzjb, zle, zstd, zstd-fast, " +
+				"or matching " + compressionPattern.String(),
+		},
+		{property: Quota, value: "10G"},
+		{property: Quota, value: "1.5T"},
+		{property: Quota, value: "1073741824"},
+		{property: Quota, value: "none"},
+		{
+			property: Quota,
+			value:    "lots",
+			wantErr: "quota: invalid value 'lots', must be " +
+				"a size like 10G, or one of: none",
+		},
+		{property: Mountpoint, value: "/srv/data"},
+		{property: Mountpoint, value: "legacy"},
+		{
+			property: Mountpoint,
+			value:    "srv/data",
+			wantErr: "mountpoint: invalid value 'srv/data', must be " +
+				"one of: none, legacy, or matching ^/",
+		},
+		{property: "com.example:owner", value: "anything goes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property+"="+tt.value, func(t *testing.T) {
+			p, ok := Lookup(tt.property)
+			require.True(t, ok)
+
+			err := p.Validate(tt.value)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDatasetTypes_Has(t *testing.T) {
+	assert.True(t, TypeDataset.Has(TypeFilesystem))
+	assert.True(t, TypeDataset.Has(TypeVolume))
+	assert.False(t, TypeDataset.Has(TypeSnapshot))
+	assert.True(t, TypeAll.Has(TypeDataset))
+	assert.False(t, TypeFilesystem.Has(TypeDataset))
+}
@@ -313,3 +313,4 @@ func User(module string) func(property string) string {
 		return fmt.Sprintf("%s:%s", module, name)
 	}
 }
+
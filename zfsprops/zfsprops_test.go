@@ -359,3 +359,29 @@ func TestUser(t *testing.T) {
 		})
 	}
 }
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		property string
+		want     bool
+	}{
+		{property: Available, want: true},
+		{property: Compression, want: true},
+		{property: UTF8Only, want: true},
+		{property: UserQuota("john"), want: true},
+		{property: ProjectObjQuota("42"), want: true},
+		{property: "userused@john", want: true},
+		{property: User("com.example")("owner"), want: true},
+		{property: "", want: false},
+		{property: "compresion", want: false},
+		{property: "userquota@", want: false},
+		{property: "-bad:prop", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property, func(t *testing.T) {
+			got := Valid(tt.property)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
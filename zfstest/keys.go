@@ -0,0 +1,407 @@
+package zfstest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// setupEncryption configures encryption of the new dataset ds based on its
+// properties, reading key material from stdin if needed.
+func (r *Runner) setupEncryption(c *cmd, p *pool, ds *dataset) error {
+	enc, hasEnc := ds.props["encryption"]
+	_, hasFormat := ds.props["keyformat"]
+	_, hasLocation := ds.props["keylocation"]
+
+	var parentRoot *dataset
+	if parent := p.parent(ds); parent != nil {
+		parentRoot = p.encryptionRoot(parent)
+	}
+
+	encrypted := (hasEnc && enc != "off") || (!hasEnc && parentRoot != nil)
+	switch {
+	case !encrypted && (hasFormat || hasLocation):
+		return failf("Keyformat and keylocation can only be set when " +
+			"encryption is enabled.",
+		)
+	case !encrypted:
+		return nil
+	case parentRoot != nil && !hasFormat:
+		if !parentRoot.keyLoaded {
+			return failf("encryption root's key is not loaded or provided.")
+		}
+
+		return nil
+	case !hasFormat:
+		return failf("Keyformat required for new encryption root.")
+	}
+
+	if !hasLocation {
+		ds.props["keylocation"] = "prompt"
+	}
+	if ds.props["keylocation"] == "prompt" {
+		key, err := checkKey(ds.props["keyformat"], c.readKey())
+		if err != nil {
+			return err
+		}
+		ds.key = key
+	}
+
+	ds.encRoot = true
+	ds.keyLoaded = true
+
+	return nil
+}
+
+// checkKey validates key material as per keyformat, returning the key with
+// any trailing newline removed from passphrase and hex keys.
+func checkKey(format string, key []byte) ([]byte, error) {
+	switch format {
+	case "passphrase":
+		key = bytes.TrimSuffix(key, []byte("\n"))
+		switch {
+		case len(key) < 8:
+			return nil, failf("Passphrase too short (min 8).")
+		case len(key) > 512:
+			return nil, failf("Passphrase too long (max 512).")
+		}
+	case "hex":
+		key = bytes.TrimSuffix(key, []byte("\n"))
+		switch {
+		case len(key) < 64:
+			return nil, failf("Hex key too short (expected 64).")
+		case len(key) > 64:
+			return nil, failf("Hex key too long (expected 64).")
+		}
+		if _, err := hex.DecodeString(string(key)); err != nil {
+			return nil, failf("Invalid hex key provided.")
+		}
+	case "raw":
+		switch {
+		case len(key) < 32:
+			return nil, failf("Raw key too short (expected 32).")
+		case len(key) > 32:
+			return nil, failf("Raw key too long (expected 32).")
+		}
+	}
+
+	return key, nil
+}
+
+// loadKey loads the key of encryption root, from location if given, or the
+// root's keylocation property. Keys read from stdin are verified against the
+// root's key. Keys from other locations are assumed to be correct.
+func (r *Runner) loadKey(
+	c *cmd,
+	p *pool,
+	root *dataset,
+	location string,
+	dryRun bool,
+) error {
+	if location == "" {
+		location = p.zfsValue(root, "keylocation")
+	}
+
+	switch location {
+	case "prompt":
+		key, err := checkKey(p.zfsValue(root, "keyformat"), c.readKey())
+		if err != nil {
+			return keyErrorf("Key load error: %s", err)
+		}
+		if root.key != nil && !bytes.Equal(key, root.key) {
+			return keyErrorf("Key load error: Incorrect key provided for "+
+				"'%s'.", root.name,
+			)
+		}
+		if root.key == nil && !dryRun {
+			root.key = key
+		}
+	case "none":
+		return keyErrorf("Key load error: Invalid keylocation 'none' for "+
+			"'%s'.", root.name,
+		)
+	}
+
+	if !dryRun {
+		root.keyLoaded = true
+	}
+
+	return nil
+}
+
+// keyInUse returns true if any filesystem using the key of encryption root is
+// mounted.
+func (p *pool) keyInUse(root *dataset) bool {
+	for _, ds := range p.datasets {
+		if ds.mounted && p.encryptionRoot(ds) == root {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyTargets returns the encryption roots targeted by load-key or unload-key,
+// and a bool indicating if multiple roots were requested with -a or -r.
+func (r *Runner) keyTargets(f flags, args []string, op string) (
+	[]zfsEntry, bool, error,
+) {
+	switch {
+	case len(args) > 1, f.has("a") && len(args) > 0:
+		return nil, false, usagef("too many arguments")
+	case !f.has("a") && len(args) == 0:
+		return nil, false, usagef("missing dataset argument")
+	}
+
+	roots := []zfsEntry{}
+	if f.has("a") {
+		for _, p := range r.sortedPools() {
+			for _, ds := range p.sortedDatasets() {
+				if ds.encRoot {
+					roots = append(roots, zfsEntry{pool: p, ds: ds})
+				}
+			}
+		}
+
+		return roots, true, nil
+	}
+
+	name := args[0]
+	p, ds := r.lookup(name)
+	if ds == nil {
+		return nil, false, failf("cannot open '%s': dataset does not exist",
+			name,
+		)
+	}
+
+	if f.has("r") {
+		for _, d := range append([]*dataset{ds}, p.descendants(ds)...) {
+			if d.encRoot {
+				roots = append(roots, zfsEntry{pool: p, ds: d})
+			}
+		}
+
+		return roots, true, nil
+	}
+
+	switch root := p.encryptionRoot(ds); {
+	case root == nil:
+		return nil, false, keyErrorf("Key %s error: Encryption not enabled "+
+			"for dataset '%s'.", op, name,
+		)
+	case root != ds:
+		return nil, false, keyErrorf("Key %s error: Keys must be %sed for "+
+			"encryption root of '%s' (%s).", op, op, name, root.name,
+		)
+	}
+
+	return []zfsEntry{{pool: p, ds: ds}}, false, nil
+}
+
+func (r *Runner) zfsLoadKey(c *cmd) error {
+	f, args, err := getopt(c.args, "anrL:")
+	if err != nil {
+		return err
+	}
+
+	roots, multiple, err := r.keyTargets(f, args, "load")
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	loaded := 0
+	for _, root := range roots {
+		if root.ds.keyLoaded {
+			if !multiple {
+				return keyErrorf("Key load error: Key already loaded for "+
+					"'%s'.", root.ds.name,
+				)
+			}
+
+			continue
+		}
+
+		err := r.loadKey(c, root.pool, root.ds, f.value("L"), f.has("n"))
+		if err != nil && firstErr == nil {
+			firstErr = err
+		} else if err == nil {
+			loaded++
+		}
+	}
+
+	if multiple {
+		fmt.Fprintf(c.stdout, "%d / %d key(s) successfully loaded\n",
+			loaded, len(roots),
+		)
+	}
+
+	return firstErr
+}
+
+func (r *Runner) zfsUnloadKey(c *cmd) error {
+	f, args, err := getopt(c.args, "ar")
+	if err != nil {
+		return err
+	}
+
+	roots, multiple, err := r.keyTargets(f, args, "unload")
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	unloaded := 0
+	for _, root := range roots {
+		switch {
+		case !root.ds.keyLoaded && !multiple:
+			return keyErrorf("Key unload error: Key already unloaded for "+
+				"'%s'.", root.ds.name,
+			)
+		case !root.ds.keyLoaded:
+			continue
+		case root.pool.keyInUse(root.ds):
+			if firstErr == nil {
+				firstErr = keyErrorf("Key unload error: '%s' is busy.",
+					root.ds.name,
+				)
+			}
+
+			continue
+		}
+
+		root.ds.keyLoaded = false
+		unloaded++
+	}
+
+	if multiple {
+		fmt.Fprintf(c.stdout, "%d / %d key(s) successfully unloaded\n",
+			unloaded, len(roots),
+		)
+	}
+
+	return firstErr
+}
+
+var changeKeyProps = []string{"keyformat", "keylocation", "pbkdf2iters"}
+
+func (r *Runner) zfsChangeKey(c *cmd) error {
+	f, args, err := getopt(c.args, "lio:")
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usagef("missing dataset argument")
+	}
+
+	props := map[string]string{}
+	for _, o := range f["o"] {
+		k, v, ok := splitProp(o)
+		if !ok {
+			return usagef("missing '=' for -o option")
+		}
+		if !contains(changeKeyProps, k) {
+			return keyErrorf("Key change error: Only keyformat, keylocation " +
+				"and pbkdf2iters may be set with this command.",
+			)
+		}
+		props[k] = v
+	}
+	if f.has("i") && len(props) > 0 {
+		return usagef("-i cannot be combined with -o")
+	}
+
+	name := args[0]
+	p, ds := r.lookup(name)
+	if ds == nil {
+		return failf("cannot open '%s': dataset does not exist", name)
+	}
+
+	root := p.encryptionRoot(ds)
+	switch {
+	case root == nil:
+		return keyErrorf("Key change error: Encryption not enabled for "+
+			"dataset '%s'.", name,
+		)
+	case !root.keyLoaded && f.has("l"):
+		if err = r.loadKey(c, p, root, "", false); err != nil {
+			return err
+		}
+	case !root.keyLoaded:
+		return keyErrorf("Key change error: Key must be loaded for '%s'.",
+			name,
+		)
+	}
+
+	if f.has("i") {
+		return inheritKey(p, ds)
+	}
+
+	return changeKey(c, p, ds, props)
+}
+
+// inheritKey makes ds inherit the key of its parent's encryption root.
+func inheritKey(p *pool, ds *dataset) error {
+	var parentRoot *dataset
+	if parent := p.parent(ds); parent != nil {
+		parentRoot = p.encryptionRoot(parent)
+	}
+
+	switch {
+	case parentRoot == nil:
+		return keyErrorf("Key change error: Parent dataset '%s' is not "+
+			"encrypted.", parentName(ds.name),
+		)
+	case !parentRoot.keyLoaded:
+		return keyErrorf("Key change error: Parent key must be loaded.")
+	}
+
+	for _, k := range changeKeyProps {
+		delete(ds.props, k)
+	}
+	ds.encRoot = false
+	ds.keyLoaded = false
+	ds.key = nil
+
+	return nil
+}
+
+// changeKey sets a new key for ds, making it an encryption root.
+func changeKey(c *cmd, p *pool, ds *dataset, props map[string]string) error {
+	values := map[string]string{}
+	for _, k := range sortedKeys(props, nil) {
+		v, err := checkZFSProp(ds.typ, k, props[k], true)
+		if err != nil {
+			return keyErrorf("Key change error: %s", err)
+		}
+		values[k] = v
+	}
+
+	if _, ok := values["keyformat"]; !ok {
+		values["keyformat"] = p.zfsValue(ds, "keyformat")
+	}
+	if _, ok := values["keylocation"]; !ok {
+		values["keylocation"] = "prompt"
+		if ds.encRoot {
+			values["keylocation"] = p.zfsValue(ds, "keylocation")
+		}
+	}
+
+	var key []byte
+	if values["keylocation"] == "prompt" {
+		var err error
+		key, err = checkKey(values["keyformat"], c.readKey())
+		if err != nil {
+			return keyErrorf("Key change error: %s", err)
+		}
+	}
+
+	for k, v := range values {
+		ds.props[k] = v
+	}
+	ds.encRoot = true
+	ds.keyLoaded = true
+	ds.key = key
+
+	return nil
+}
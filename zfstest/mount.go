@@ -0,0 +1,227 @@
+package zfstest
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (r *Runner) zfsMount(c *cmd) error {
+	f, args, err := getopt(c.args, "aflvOo:")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case f.has("a") && len(args) > 0:
+		return usagef("too many arguments")
+	case f.has("a"):
+		for _, p := range r.sortedPools() {
+			p.mountAll()
+		}
+
+		return nil
+	case len(args) == 0:
+		r.writeMounts(c)
+
+		return nil
+	case len(args) > 1:
+		return usagef("too many arguments")
+	}
+
+	name := args[0]
+	p, ds := r.lookup(name)
+	if ds == nil {
+		return failf("cannot open '%s': dataset does not exist", name)
+	}
+	if ds.typ != typeFilesystem {
+		return failf("cannot open '%s': operation not applicable to "+
+			"datasets of this type", name,
+		)
+	}
+
+	switch mp := p.zfsValue(ds, "mountpoint"); {
+	case ds.mounted:
+		return failf("cannot mount '%s': filesystem already mounted", name)
+	case p.zfsValue(ds, "canmount") == "off":
+		return failf("cannot mount '%s': 'canmount' property is set to "+
+			"'off'", name,
+		)
+	case mp == "legacy":
+		return failf("cannot mount '%s': legacy mountpoint\n"+
+			"use mount(8) to mount this filesystem", name,
+		)
+	case mp == "none":
+		return failf("cannot mount '%s': no mountpoint set", name)
+	}
+
+	if root := p.encryptionRoot(ds); root != nil && !root.keyLoaded {
+		if !f.has("l") {
+			return failf("cannot mount '%s': encryption key not loaded",
+				name,
+			)
+		}
+		if err := r.loadKey(c, p, root, "", false); err != nil {
+			return err
+		}
+	}
+
+	ds.mounted = true
+
+	return nil
+}
+
+// writeMounts writes all mounted filesystems in the format used by zfs mount.
+func (r *Runner) writeMounts(c *cmd) {
+	for _, p := range r.sortedPools() {
+		for _, ds := range p.sortedDatasets() {
+			if ds.mounted {
+				fmt.Fprintf(c.stdout, "%-30s  %s\n",
+					ds.name, p.zfsValue(ds, "mountpoint"),
+				)
+			}
+		}
+	}
+}
+
+// sortedDatasets returns all datasets of the pool in zfs list order.
+func (p *pool) sortedDatasets() []*dataset {
+	datasets := make([]*dataset, 0, len(p.datasets))
+	for _, ds := range p.datasets {
+		datasets = append(datasets, ds)
+	}
+	sortDatasets(datasets)
+
+	return datasets
+}
+
+func (r *Runner) zfsUnmount(c *cmd) error {
+	f, args, err := getopt(c.args, "afu")
+	if err != nil {
+		return err
+	}
+
+	var targets []zfsEntry
+	switch {
+	case f.has("a") && len(args) > 0, len(args) > 1:
+		return usagef("too many arguments")
+	case f.has("a"):
+		for _, p := range r.sortedPools() {
+			for _, ds := range p.sortedDatasets() {
+				targets = append(targets, zfsEntry{pool: p, ds: ds})
+			}
+		}
+	case len(args) == 0:
+		return usagef("missing filesystem argument")
+	default:
+		p, ds, err := r.lookupMounted(args[0])
+		if err != nil {
+			return err
+		}
+		targets = append(targets, zfsEntry{pool: p, ds: ds})
+		for _, d := range p.descendants(ds) {
+			targets = append(targets, zfsEntry{pool: p, ds: d})
+		}
+	}
+
+	for _, t := range targets {
+		if t.ds.mounted && t.ds.busy && !f.has("f") {
+			return failf("cannot unmount '%s': pool or dataset is busy",
+				t.pool.zfsValue(t.ds, "mountpoint"),
+			)
+		}
+	}
+
+	for _, t := range targets {
+		t.ds.mounted = false
+		t.ds.shared = false
+	}
+
+	if f.has("u") {
+		for _, t := range targets {
+			root := t.pool.encryptionRoot(t.ds)
+			if root != nil && !t.pool.keyInUse(root) {
+				root.keyLoaded = false
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupMounted returns the mounted filesystem with the given name or
+// mountpoint.
+func (r *Runner) lookupMounted(name string) (*pool, *dataset, error) {
+	if !strings.HasPrefix(name, "/") {
+		p, ds := r.lookup(name)
+		switch {
+		case ds == nil:
+			return nil, nil, failf(
+				"cannot open '%s': dataset does not exist", name,
+			)
+		case !ds.mounted:
+			return nil, nil, failf(
+				"cannot unmount '%s': not currently mounted", name,
+			)
+		}
+
+		return p, ds, nil
+	}
+
+	for _, p := range r.pools {
+		for _, ds := range p.datasets {
+			if ds.mounted && p.zfsValue(ds, "mountpoint") == name {
+				return p, ds, nil
+			}
+		}
+	}
+
+	return nil, nil, failf("cannot unmount '%s': not a ZFS filesystem", name)
+}
+
+func (r *Runner) zfsShare(c *cmd) error {
+	if len(c.args) != 1 {
+		return usagef("missing filesystem argument")
+	}
+
+	name := c.args[0]
+	_, ds := r.lookup(name)
+	switch {
+	case ds == nil:
+		return failf("cannot open '%s': dataset does not exist", name)
+	case ds.typ != typeFilesystem:
+		return failf("cannot open '%s': operation not applicable to "+
+			"datasets of this type", name,
+		)
+	case !ds.mounted:
+		return failf("cannot share '%s': filesystem is not mounted", name)
+	}
+
+	ds.shared = true
+
+	return nil
+}
+
+func (r *Runner) zfsUnshare(c *cmd) error {
+	if len(c.args) != 1 {
+		return usagef("missing filesystem argument")
+	}
+
+	name := c.args[0]
+	var ds *dataset
+	if strings.HasPrefix(name, "/") {
+		_, ds, _ = r.lookupMounted(name)
+	} else {
+		_, ds = r.lookup(name)
+		if ds == nil {
+			return failf("cannot open '%s': dataset does not exist", name)
+		}
+	}
+
+	if ds == nil || !ds.shared {
+		return failf("cannot unshare '%s': not currently shared", name)
+	}
+
+	ds.shared = false
+
+	return nil
+}
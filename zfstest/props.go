@@ -0,0 +1,687 @@
+package zfstest
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krystal/go-zfs/zfsprops"
+	"github.com/krystal/go-zfs/zpoolprops"
+)
+
+// typeMask is a bit mask of dataset types.
+type typeMask int
+
+const (
+	typeFilesystem typeMask = 1 << iota
+	typeVolume
+	typeSnapshot
+	typeBookmark
+
+	typeDataset = typeFilesystem | typeVolume
+	typeAll     = typeDataset | typeSnapshot | typeBookmark
+)
+
+var typeNames = map[string]typeMask{
+	"filesystem": typeFilesystem,
+	"volume":     typeVolume,
+	"snapshot":   typeSnapshot,
+	"snap":       typeSnapshot,
+	"bookmark":   typeBookmark,
+	"all":        typeAll,
+}
+
+// parseTypes parses a comma separated list of dataset types, as given to the
+// -t flag.
+func parseTypes(s string) (typeMask, error) {
+	var m typeMask
+	for _, t := range strings.Split(s, ",") {
+		v, ok := typeNames[t]
+		if !ok {
+			return 0, usagef("invalid type '%s'", t)
+		}
+		m |= v
+	}
+
+	return m, nil
+}
+
+// kind determines how a property value is parsed and displayed.
+type kind int
+
+const (
+	kindString kind = iota
+	kindEnum
+	kindSize
+	kindNumber
+	kindTime
+	kindRatio
+	kindPercent
+)
+
+// propDef defines a native zfs or zpool property.
+type propDef struct {
+	name string
+	kind kind
+
+	// def is the default value, as displayed with -p.
+	def string
+
+	// values are the accepted values of kindEnum properties.
+	values []string
+
+	// types the property applies to. Unused for pool properties.
+	types typeMask
+
+	// readonly properties are statistics computed by the Runner.
+	readonly bool
+
+	// inherit indicates the property is inherited from parent datasets.
+	inherit bool
+
+	// create indicates the property can only be set at creation time, or for
+	// pool properties, at creation or import time.
+	create bool
+
+	// none indicates kindSize values of zero are displayed as "none", and
+	// that "none" is accepted as a value.
+	none bool
+}
+
+var (
+	onOff = []string{"on", "off"}
+
+	compressionValues = func() []string {
+		v := []string{"on", "off", "lzjb", "gzip", "zle", "lz4", "zstd"}
+		for i := 1; i <= 9; i++ {
+			v = append(v, fmt.Sprintf("gzip-%d", i))
+		}
+		for i := 1; i <= 19; i++ {
+			v = append(v, fmt.Sprintf("zstd-%d", i))
+		}
+
+		return append(v, "zstd-fast")
+	}()
+
+	encryptionValues = []string{
+		"off", "on", "aes-128-ccm", "aes-192-ccm", "aes-256-ccm",
+		"aes-128-gcm", "aes-192-gcm", "aes-256-gcm",
+	}
+)
+
+// zfsProps are the supported native dataset properties, in the order they are
+// listed by "zfs get all".
+var zfsProps = []*propDef{
+	{name: "type", types: typeAll, readonly: true},
+	{name: "creation", kind: kindTime, types: typeAll, readonly: true},
+	{name: "used", kind: kindSize, types: typeAll, readonly: true},
+	{name: "available", kind: kindSize, types: typeDataset, readonly: true},
+	{name: "referenced", kind: kindSize, types: typeAll, readonly: true},
+	{name: "compressratio", kind: kindRatio, types: typeAll, readonly: true},
+	{name: "mounted", types: typeFilesystem, readonly: true},
+	{name: "origin", types: typeDataset, readonly: true},
+	{
+		name: "quota", kind: kindSize, def: "0", none: true,
+		types: typeFilesystem,
+	},
+	{
+		name: "reservation", kind: kindSize, def: "0", none: true,
+		types: typeDataset,
+	},
+	{
+		name: "volsize", kind: kindSize, types: typeVolume,
+	},
+	{
+		name: "volblocksize", kind: kindSize, def: "8192", types: typeVolume,
+		create: true,
+	},
+	{
+		name: "recordsize", kind: kindSize, def: "131072",
+		types: typeFilesystem, inherit: true,
+	},
+	{name: "mountpoint", types: typeFilesystem, inherit: true},
+	{name: "sharenfs", def: "off", types: typeFilesystem, inherit: true},
+	{
+		name: "checksum", kind: kindEnum, def: "on", types: typeDataset,
+		inherit: true,
+		values: []string{
+			"on", "off", "fletcher2", "fletcher4", "sha256", "noparity",
+			"sha512", "skein", "edonr", "blake3",
+		},
+	},
+	{
+		name: "compression", kind: kindEnum, def: "off", types: typeDataset,
+		inherit: true, values: compressionValues,
+	},
+	{
+		name: "atime", kind: kindEnum, def: "on", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "devices", kind: kindEnum, def: "on", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "exec", kind: kindEnum, def: "on", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "setuid", kind: kindEnum, def: "on", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "readonly", kind: kindEnum, def: "off", types: typeDataset,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "zoned", kind: kindEnum, def: "off", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "snapdir", kind: kindEnum, def: "hidden", types: typeFilesystem,
+		inherit: true, values: []string{"hidden", "visible"},
+	},
+	{
+		name: "aclmode", kind: kindEnum, def: "discard", types: typeFilesystem,
+		inherit: true,
+		values:  []string{"discard", "groupmask", "passthrough", "restricted"},
+	},
+	{
+		name: "aclinherit", kind: kindEnum, def: "restricted",
+		types: typeFilesystem, inherit: true,
+		values: []string{
+			"discard", "noallow", "restricted", "passthrough", "passthrough-x",
+		},
+	},
+	{name: "createtxg", kind: kindNumber, types: typeAll, readonly: true},
+	{
+		name: "canmount", kind: kindEnum, def: "on", types: typeFilesystem,
+		values: []string{"on", "off", "noauto"},
+	},
+	{
+		name: "xattr", kind: kindEnum, def: "on", types: typeFilesystem,
+		inherit: true, values: []string{"on", "off", "sa", "dir"},
+	},
+	{
+		name: "copies", kind: kindEnum, def: "1", types: typeDataset,
+		inherit: true, values: []string{"1", "2", "3"},
+	},
+	{
+		name: "utf8only", kind: kindEnum, def: "off", types: typeFilesystem,
+		inherit: true, create: true, values: onOff,
+	},
+	{
+		name: "normalization", kind: kindEnum, def: "none",
+		types: typeFilesystem, inherit: true, create: true,
+		values: []string{"none", "formC", "formD", "formKC", "formKD"},
+	},
+	{
+		name: "casesensitivity", kind: kindEnum, def: "sensitive",
+		types: typeFilesystem, inherit: true, create: true,
+		values: []string{"sensitive", "insensitive", "mixed"},
+	},
+	{
+		name: "vscan", kind: kindEnum, def: "off", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "nbmand", kind: kindEnum, def: "off", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{name: "sharesmb", def: "off", types: typeFilesystem, inherit: true},
+	{
+		name: "refquota", kind: kindSize, def: "0", none: true,
+		types: typeFilesystem,
+	},
+	{
+		name: "refreservation", kind: kindSize, def: "0", none: true,
+		types: typeDataset,
+	},
+	{name: "guid", kind: kindNumber, types: typeAll, readonly: true},
+	{
+		name: "primarycache", kind: kindEnum, def: "all", types: typeDataset,
+		inherit: true, values: []string{"all", "none", "metadata"},
+	},
+	{
+		name: "secondarycache", kind: kindEnum, def: "all", types: typeDataset,
+		inherit: true, values: []string{"all", "none", "metadata"},
+	},
+	{
+		name: "usedbysnapshots", kind: kindSize, types: typeDataset,
+		readonly: true,
+	},
+	{
+		name: "usedbydataset", kind: kindSize, types: typeDataset,
+		readonly: true,
+	},
+	{
+		name: "usedbychildren", kind: kindSize, types: typeDataset,
+		readonly: true,
+	},
+	{
+		name: "usedbyrefreservation", kind: kindSize, types: typeDataset,
+		readonly: true,
+	},
+	{
+		name: "logbias", kind: kindEnum, def: "latency", types: typeDataset,
+		inherit: true, values: []string{"latency", "throughput"},
+	},
+	{name: "objsetid", kind: kindNumber, types: typeAll, readonly: true},
+	{
+		name: "dedup", kind: kindEnum, def: "off", types: typeDataset,
+		inherit: true,
+		values: []string{
+			"off", "on", "verify", "sha256", "sha256,verify", "sha512",
+			"sha512,verify", "skein", "skein,verify", "edonr,verify",
+		},
+	},
+	{
+		name: "sync", kind: kindEnum, def: "standard", types: typeDataset,
+		inherit: true, values: []string{"standard", "always", "disabled"},
+	},
+	{
+		name: "dnodesize", kind: kindEnum, def: "legacy",
+		types: typeFilesystem, inherit: true,
+		values: []string{"legacy", "auto", "1k", "2k", "4k", "8k", "16k"},
+	},
+	{
+		name: "refcompressratio", kind: kindRatio, types: typeAll,
+		readonly: true,
+	},
+	{name: "written", kind: kindSize, types: typeAll, readonly: true},
+	{name: "logicalused", kind: kindSize, types: typeAll, readonly: true},
+	{
+		name: "logicalreferenced", kind: kindSize, types: typeAll,
+		readonly: true,
+	},
+	{
+		name: "volmode", kind: kindEnum, def: "default", types: typeVolume,
+		inherit: true,
+		values:  []string{"default", "full", "geom", "dev", "none"},
+	},
+	{
+		name: "snapdev", kind: kindEnum, def: "hidden", types: typeDataset,
+		inherit: true, values: []string{"hidden", "visible"},
+	},
+	{
+		name: "acltype", kind: kindEnum, def: "off", types: typeFilesystem,
+		inherit: true,
+		values:  []string{"off", "noacl", "nfsv4", "posix", "posixacl"},
+	},
+	{
+		name: "relatime", kind: kindEnum, def: "off", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "redundant_metadata", kind: kindEnum, def: "all",
+		types: typeDataset, inherit: true,
+		values: []string{"all", "most", "some", "none"},
+	},
+	{
+		name: "overlay", kind: kindEnum, def: "on", types: typeFilesystem,
+		inherit: true, values: onOff,
+	},
+	{
+		name: "encryption", kind: kindEnum, def: "off", types: typeDataset,
+		inherit: true, create: true, values: encryptionValues,
+	},
+	{name: "keylocation", def: "none", types: typeDataset},
+	{
+		name: "keyformat", kind: kindEnum, def: "none", types: typeDataset,
+		inherit: true, create: true,
+		values: []string{"none", "raw", "hex", "passphrase"},
+	},
+	{name: "pbkdf2iters", kind: kindNumber, def: "0", types: typeDataset},
+	{name: "encryptionroot", types: typeDataset, readonly: true},
+	{name: "keystatus", types: typeDataset, readonly: true},
+	{
+		name: "special_small_blocks", kind: kindSize, def: "0",
+		types: typeFilesystem, inherit: true,
+	},
+	{name: "clones", types: typeSnapshot, readonly: true},
+	{name: "defer_destroy", types: typeSnapshot, readonly: true},
+	{name: "userrefs", kind: kindNumber, types: typeSnapshot, readonly: true},
+}
+
+// zpoolProps are the supported pool properties, in the order they are listed
+// by "zpool get all".
+var zpoolProps = []*propDef{
+	{name: "size", kind: kindSize, readonly: true},
+	{name: "capacity", kind: kindPercent, readonly: true},
+	{name: "altroot", def: "-", create: true},
+	{name: "health", readonly: true},
+	{name: "guid", kind: kindNumber, readonly: true},
+	{name: "load_guid", kind: kindNumber, readonly: true},
+	{name: "version", def: "-"},
+	{name: "bootfs", def: "-"},
+	{name: "delegation", kind: kindEnum, def: "on", values: onOff},
+	{name: "autoreplace", kind: kindEnum, def: "off", values: onOff},
+	{name: "cachefile", def: "-"},
+	{
+		name: "failmode", kind: kindEnum, def: "wait",
+		values: []string{"wait", "continue", "panic"},
+	},
+	{name: "listsnapshots", kind: kindEnum, def: "off", values: onOff},
+	{name: "autoexpand", kind: kindEnum, def: "off", values: onOff},
+	{name: "dedupratio", kind: kindRatio, readonly: true},
+	{name: "free", kind: kindSize, readonly: true},
+	{name: "allocated", kind: kindSize, readonly: true},
+	{name: "readonly", kind: kindEnum, def: "off", values: onOff, create: true},
+	{name: "ashift", kind: kindNumber, def: "0"},
+	{name: "comment", def: "-"},
+	{name: "expandsize", kind: kindSize, readonly: true},
+	{name: "freeing", kind: kindSize, readonly: true},
+	{name: "fragmentation", kind: kindPercent, readonly: true},
+	{name: "leaked", kind: kindSize, readonly: true},
+	{name: "multihost", kind: kindEnum, def: "off", values: onOff},
+	{name: "checkpoint", kind: kindSize, readonly: true},
+	{name: "autotrim", kind: kindEnum, def: "off", values: onOff},
+	{name: "compatibility", def: "off"},
+}
+
+var (
+	zfsPropsByName   = propsByName(zfsProps)
+	zpoolPropsByName = propsByName(zpoolProps)
+)
+
+func propsByName(defs []*propDef) map[string]*propDef {
+	m := make(map[string]*propDef, len(defs))
+	for _, d := range defs {
+		m[d.name] = d
+	}
+
+	return m
+}
+
+// quotaPrefixes are the prefixes of per user, group, and project quota
+// properties.
+var quotaPrefixes = []string{
+	"userquota@", "groupquota@", "projectquota@",
+	"userobjquota@", "groupobjquota@", "projectobjquota@",
+}
+
+func isUserProp(name string) bool {
+	return strings.Contains(name, ":")
+}
+
+func isQuotaProp(name string) bool {
+	for _, prefix := range quotaPrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isFeatureProp(name string) bool {
+	return strings.HasPrefix(name, "feature@") && len(name) > len("feature@")
+}
+
+// zfsPropName returns the full name of the zfs property name, resolving
+// aliases like "compress" to "compression" as zfs does.
+func zfsPropName(name string) string {
+	if p, ok := zfsprops.Lookup(name); ok {
+		return p.Name
+	}
+
+	return name
+}
+
+// zpoolPropName returns the full name of the zpool property name, resolving
+// aliases like "rdonly" to "readonly" as zpool does.
+func zpoolPropName(name string) string {
+	if p, ok := zpoolprops.Lookup(name); ok {
+		return p.Name
+	}
+
+	return name
+}
+
+// validZFSProp returns true if name is a known native property, a user
+// property, or a quota property.
+func validZFSProp(name string) bool {
+	_, ok := zfsPropsByName[name]
+
+	return ok || isUserProp(name) || isQuotaProp(name)
+}
+
+// checkZFSProp validates setting property name to value on a dataset of type
+// typ, returning the value as it is stored. The create argument indicates if
+// the property is being set at creation time.
+func checkZFSProp(typ typeMask, name, value string, create bool) (
+	string, error,
+) {
+	if isUserProp(name) {
+		if len(value) > 8192 {
+			return "", failf("property value '%s' is too long", name)
+		}
+
+		return value, nil
+	}
+	if isQuotaProp(name) {
+		if typ != typeFilesystem {
+			return "", failf("'%s' does not apply to datasets of this type",
+				name,
+			)
+		}
+
+		return (&propDef{name: name, kind: kindSize, none: true}).
+			normalize(value)
+	}
+
+	def, ok := zfsPropsByName[name]
+	switch {
+	case !ok:
+		return "", failf("invalid property '%s'", name)
+	case def.readonly || (def.create && !create):
+		return "", failf("'%s' is readonly", name)
+	case def.types&typ == 0:
+		return "", failf("'%s' does not apply to datasets of this type", name)
+	}
+
+	return def.normalize(value)
+}
+
+// checkZpoolProp validates setting property name to value on a pool,
+// returning the value as it is stored. The create argument indicates if the
+// property is being set at creation or import time.
+func checkZpoolProp(name, value string, create bool) (string, error) {
+	if isFeatureProp(name) {
+		if value != "enabled" {
+			return "", failf("property '%s' can only be set to 'enabled'",
+				name,
+			)
+		}
+
+		return value, nil
+	}
+
+	def, ok := zpoolPropsByName[name]
+	switch {
+	case !ok:
+		return "", failf("invalid property '%s'", name)
+	case def.readonly:
+		return "", failf("'%s' is readonly", name)
+	case def.create && !create:
+		return "", failf(
+			"property '%s' can only be set during pool creation or import",
+			name,
+		)
+	}
+
+	return def.normalize(value)
+}
+
+// normalize validates value, returning it in the form displayed with -p.
+func (d *propDef) normalize(value string) (string, error) {
+	switch d.kind {
+	case kindEnum:
+		if d.name == "encryption" && value == "on" {
+			return "aes-256-gcm", nil
+		}
+		if !contains(d.values, value) {
+			return "", failf("'%s' must be one of '%s'",
+				d.name, strings.Join(d.values, " | "),
+			)
+		}
+	case kindSize:
+		return d.normalizeSize(value)
+	case kindNumber:
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return "", failf("bad numeric value '%s'", value)
+		}
+	case kindString:
+		return d.normalizeString(value)
+	case kindTime, kindRatio, kindPercent:
+	}
+
+	return value, nil
+}
+
+func (d *propDef) normalizeSize(value string) (string, error) {
+	if d.none && value == "none" {
+		return "0", nil
+	}
+
+	n, err := parseSize(value)
+	if err != nil {
+		return "", failf("bad numeric value '%s'", value)
+	}
+
+	switch d.name {
+	case "recordsize":
+		if !powerOf2(n, 512, 1<<20) {
+			return "", failf("'%s' must be power of 2 from 512B to 1M", d.name)
+		}
+	case "volblocksize":
+		if !powerOf2(n, 512, 128<<10) {
+			return "", failf(
+				"'%s' must be power of 2 from 512B to 128KB", d.name,
+			)
+		}
+	}
+
+	return strconv.FormatUint(n, 10), nil
+}
+
+func (d *propDef) normalizeString(value string) (string, error) {
+	switch d.name {
+	case "mountpoint":
+		if value != "none" && value != "legacy" &&
+			!strings.HasPrefix(value, "/") {
+			return "", failf(
+				"'%s' must be an absolute path, 'none', or 'legacy'", d.name,
+			)
+		}
+	case "keylocation":
+		if value != "prompt" && value != "none" &&
+			!strings.HasPrefix(value, "file:///") &&
+			!strings.HasPrefix(value, "https://") &&
+			!strings.HasPrefix(value, "http://") {
+			return "", failf("invalid keylocation '%s'", value)
+		}
+	}
+
+	return value, nil
+}
+
+// format returns value as displayed with or without the -p flag.
+func (d *propDef) format(value string, parsable bool) string {
+	if parsable || value == "-" || value == "" {
+		return value
+	}
+
+	switch d.kind {
+	case kindSize:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return value
+		}
+		if n == 0 && d.none {
+			return "none"
+		}
+
+		return nicebytes(n)
+	case kindTime:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return value
+		}
+
+		return time.Unix(n, 0).Format("Mon Jan _2 15:04 2006")
+	case kindRatio:
+		return value + "x"
+	case kindPercent:
+		return value + "%"
+	case kindString, kindEnum, kindNumber:
+	}
+
+	return value
+}
+
+var sizeRegexp = regexp.MustCompile(
+	`^(?i)([0-9]+(?:\.[0-9]*)?)\s*([bkmgtpez]?)(?:ib|b)?$`,
+)
+
+// parseSize parses a human-readable size, like "1.5G", in the same way as the
+// zfs command, where all suffixes are powers of 1024.
+func parseSize(s string) (uint64, error) {
+	m := sizeRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	shift := strings.Index("bkmgtpez", strings.ToLower(m[2]))
+	if shift < 0 {
+		shift = 0
+	}
+
+	v := n * math.Pow(1024, float64(shift))
+	if v >= math.MaxUint64 {
+		return 0, fmt.Errorf("size '%s' is too large", s)
+	}
+
+	return uint64(v), nil
+}
+
+// nicebytes formats n bytes like zfs does without the -p flag, using at most
+// 5 characters, for example "512B", "96K" and "1.50G".
+func nicebytes(n uint64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	suffixes := "KMGTPE"
+	i := 0
+	v := float64(n) / 1024
+	for v >= 1024 && i < len(suffixes)-1 {
+		v /= 1024
+		i++
+	}
+
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f%c", v, suffixes[i])
+	}
+
+	for precision := 2; precision > 0; precision-- {
+		s := fmt.Sprintf("%.*f%c", precision, v, suffixes[i])
+		if len(s) <= 5 {
+			return s
+		}
+	}
+
+	return fmt.Sprintf("%.0f%c", v, suffixes[i])
+}
+
+func powerOf2(n, minimum, maximum uint64) bool {
+	return n >= minimum && n <= maximum && n&(n-1) == 0
+}
@@ -0,0 +1,277 @@
+package zfstest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// spaceFields are the fields supported by the -o flag of zfs userspace,
+// groupspace, and projectspace, and are also the default fields.
+var spaceFields = []string{
+	"type", "name", "used", "quota", "objused", "objquota",
+}
+
+// spaceType is a type of identity reported by zfs userspace, groupspace, and
+// projectspace.
+type spaceType struct {
+	name    string
+	display string
+	// quota and objQuota are the prefixes of the quota properties of the
+	// type.
+	quota    string
+	objQuota string
+}
+
+// spaceTypes are all space types, in the order they are output.
+var spaceTypes = []*spaceType{
+	{"posixuser", "POSIX User", "userquota@", "userobjquota@"},
+	{"smbuser", "SMB User", "userquota@", "userobjquota@"},
+	{"posixgroup", "POSIX Group", "groupquota@", "groupobjquota@"},
+	{"smbgroup", "SMB Group", "groupquota@", "groupobjquota@"},
+	{"project", "PROJECT", "projectquota@", "projectobjquota@"},
+}
+
+// spaceEntry is the space used by, and quotas of, a single identity.
+type spaceEntry struct {
+	typ      *spaceType
+	id       string
+	used     uint64
+	quota    string
+	objUsed  uint64
+	objQuota string
+}
+
+func (r *Runner) zfsUserSpace(c *cmd) error {
+	return r.space(c, "posixuser,smbuser")
+}
+
+func (r *Runner) zfsGroupSpace(c *cmd) error {
+	return r.space(c, "posixgroup,smbgroup")
+}
+
+func (r *Runner) zfsProjectSpace(c *cmd) error {
+	return r.space(c, "project")
+}
+
+// space simulates zfs userspace, groupspace, and projectspace, which report
+// identities of defaultTypes unless other types are given with -t. The fake
+// has no user or group database, nor SID mappings, so the -n and -i flags
+// have no effect.
+//
+// All space referenced by a filesystem or snapshot is accounted to the root
+// user, group, and project, along with a single object for the root
+// directory. Identities with a quota set on the filesystem are reported as
+// well, having used no space.
+func (r *Runner) space(c *cmd, defaultTypes string) error {
+	spec := "Hinpo:t:"
+	if defaultTypes == "project" {
+		spec = "Hpo:"
+	}
+	f, args, err := getopt(c.args, spec)
+	if err != nil {
+		return err
+	}
+
+	fields := spaceFields
+	if f.has("o") {
+		fields = strings.Split(f.value("o"), ",")
+		for _, field := range fields {
+			if !contains(spaceFields, field) {
+				return usagef("invalid field '%s'", field)
+			}
+		}
+	}
+
+	types, err := parseSpaceTypes(defaultTypes, f)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(args) == 0:
+		return usagef("missing dataset name")
+	case len(args) > 1:
+		return usagef("too many arguments")
+	}
+
+	name := args[0]
+	_, ds := r.lookup(name)
+	if ds == nil {
+		return failf("cannot open '%s': dataset does not exist", name)
+	}
+	if _, fs := r.lookup(strings.SplitN(name, "@", 2)[0]); fs == nil ||
+		fs.typ != typeFilesystem {
+		return failf("cannot open '%s': operation not applicable to "+
+			"datasets of this type", name,
+		)
+	}
+
+	rows := [][]string{}
+	for _, e := range spaceEntries(ds) {
+		if !types[e.typ] {
+			continue
+		}
+
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			row[i] = e.field(field, f.has("p"))
+		}
+		rows = append(rows, row)
+	}
+
+	writeTable(c.stdout, f.has("H"), fields, rows)
+
+	return nil
+}
+
+// parseSpaceTypes returns the space types given with the -t flag, or
+// defaultTypes if not given. Only projectspace reports projects.
+func parseSpaceTypes(
+	defaultTypes string,
+	f flags,
+) (map[*spaceType]bool, error) {
+	value := defaultTypes
+	if f.has("t") {
+		value = f.value("t")
+	}
+
+	types := map[*spaceType]bool{}
+	for _, name := range strings.Split(value, ",") {
+		found := false
+		for _, t := range spaceTypes {
+			project := t.name == "project"
+			if (name == "all" && !project) ||
+				(name == t.name && project == (defaultTypes == "project")) {
+				types[t] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, usagef("invalid type '%s'", name)
+		}
+	}
+
+	return types, nil
+}
+
+// spaceEntries returns the space entries of ds, sorted by type and then ID.
+func spaceEntries(ds *dataset) []*spaceEntry {
+	entries := map[string]*spaceEntry{}
+	entry := func(t *spaceType, id string) *spaceEntry {
+		key := t.name + "\x00" + id
+		if entries[key] == nil {
+			entries[key] = &spaceEntry{
+				typ: t, id: id, quota: "none", objQuota: "none",
+			}
+		}
+
+		return entries[key]
+	}
+
+	for _, t := range spaceTypes {
+		if strings.HasPrefix(t.name, "posix") || t.name == "project" {
+			e := entry(t, "0")
+			e.used = ds.referenced
+			e.objUsed = 1
+		}
+	}
+
+	if ds.typ == typeFilesystem {
+		for _, k := range sortedKeys(ds.props, isQuotaProp) {
+			v := ds.props[k]
+			if parseUint(v) == 0 {
+				continue
+			}
+			for _, t := range spaceTypes {
+				setSpaceQuota(t, entry, k, v)
+			}
+		}
+	}
+
+	sorted := make([]*spaceEntry, 0, len(entries))
+	for _, e := range entries {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].less(sorted[j])
+	})
+
+	return sorted
+}
+
+// setSpaceQuota sets the quota of the entry of type t named by the quota
+// property k to v, if k is a quota property of type t.
+func setSpaceQuota(
+	t *spaceType,
+	entry func(*spaceType, string) *spaceEntry,
+	k, v string,
+) {
+	for _, prefix := range []string{t.quota, t.objQuota} {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+
+		id := k[len(prefix):]
+		sid := strings.HasPrefix(id, "S-1-")
+		if t.name != "project" && sid != strings.HasPrefix(t.name, "smb") {
+			continue
+		}
+
+		if prefix == t.quota {
+			entry(t, id).quota = v
+		} else {
+			entry(t, id).objQuota = v
+		}
+	}
+}
+
+// less orders entries by type, then numerically by ID, with SIDs and other
+// non-numeric IDs last.
+func (e *spaceEntry) less(o *spaceEntry) bool {
+	if e.typ != o.typ {
+		return spaceTypeIndex(e.typ) < spaceTypeIndex(o.typ)
+	}
+
+	a, aErr := strconv.ParseUint(e.id, 10, 64)
+	b, bErr := strconv.ParseUint(o.id, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return a < b
+	case aErr == nil || bErr == nil:
+		return aErr == nil
+	}
+
+	return e.id < o.id
+}
+
+func spaceTypeIndex(t *spaceType) int {
+	for i, v := range spaceTypes {
+		if v == t {
+			return i
+		}
+	}
+
+	return len(spaceTypes)
+}
+
+// field returns the value of the named field of e, as displayed with or
+// without the -p flag.
+func (e *spaceEntry) field(name string, parsable bool) string {
+	switch name {
+	case "type":
+		return e.typ.display
+	case "name":
+		return e.id
+	case "used":
+		return (&propDef{kind: kindSize}).format(u64(e.used), parsable)
+	case "quota":
+		return (&propDef{kind: kindSize, none: true}).format(e.quota, parsable)
+	case "objused":
+		return u64(e.objUsed)
+	case "objquota":
+		return e.objQuota
+	}
+
+	return "-"
+}
@@ -0,0 +1,492 @@
+package zfstest
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Space referenced by newly created datasets.
+const (
+	filesystemReferenced = 96 << 10
+	volumeReferenced     = 56 << 10
+)
+
+type dataset struct {
+	name       string
+	typ        typeMask
+	props      map[string]string
+	created    time.Time
+	txg        uint64
+	guid       uint64
+	objsetid   uint64
+	referenced uint64
+	mounted    bool
+	shared     bool
+	busy       bool
+
+	// encRoot indicates the dataset is an encryption root, in which case key
+	// material and key status are tracked by the dataset.
+	encRoot   bool
+	keyLoaded bool
+	key       []byte
+}
+
+func (ds *dataset) typeName() string {
+	switch ds.typ {
+	case typeVolume:
+		return "volume"
+	case typeSnapshot:
+		return "snapshot"
+	case typeBookmark:
+		return "bookmark"
+	case typeFilesystem, typeDataset, typeAll:
+	}
+
+	return "filesystem"
+}
+
+type pool struct {
+	name     string
+	guid     uint64
+	loadGUID uint64
+	size     uint64
+	vdevs    []string
+	props    map[string]string
+	busy     bool
+	datasets map[string]*dataset
+
+	// features is the state of features not explicitly set, either "enabled"
+	// or "disabled".
+	features string
+
+	// exportName is the name the pool reverts to when exported, if it was
+	// imported with a temporary name.
+	exportName string
+}
+
+func (p *pool) root() *dataset {
+	return p.datasets[p.name]
+}
+
+// parent returns the parent of ds, which for snapshots is the dataset they
+// belong to. Returns nil for the root dataset.
+func (p *pool) parent(ds *dataset) *dataset {
+	name := parentName(ds.name)
+	if name == "" {
+		return nil
+	}
+
+	return p.datasets[name]
+}
+
+// descendants returns all datasets below ds, including snapshots, in zfs list
+// order.
+func (p *pool) descendants(ds *dataset) []*dataset {
+	r := []*dataset{}
+	for name, d := range p.datasets {
+		if strings.HasPrefix(name, ds.name+"/") ||
+			strings.HasPrefix(name, ds.name+"@") {
+			r = append(r, d)
+		}
+	}
+	sortDatasets(r)
+
+	return r
+}
+
+// children returns filesystems and volumes directly below ds.
+func (p *pool) children(ds *dataset) []*dataset {
+	r := []*dataset{}
+	for _, d := range p.datasets {
+		if d.typ&typeDataset != 0 && parentName(d.name) == ds.name {
+			r = append(r, d)
+		}
+	}
+
+	return r
+}
+
+// rename changes the name of the pool, and all of its datasets.
+func (p *pool) rename(name string) {
+	datasets := make(map[string]*dataset, len(p.datasets))
+	for _, ds := range p.datasets {
+		ds.name = name + strings.TrimPrefix(ds.name, p.name)
+		datasets[ds.name] = ds
+	}
+	p.name = name
+	p.datasets = datasets
+}
+
+func (p *pool) altroot() string {
+	return p.props["altroot"]
+}
+
+// parentName returns the name of the parent of the named dataset, or an empty
+// string if it has no parent.
+func parentName(name string) string {
+	if i := strings.IndexAny(name, "@#"); i != -1 {
+		return name[:i]
+	}
+	if i := strings.LastIndexByte(name, '/'); i != -1 {
+		return name[:i]
+	}
+
+	return ""
+}
+
+func poolName(name string) string {
+	if i := strings.IndexAny(name, "/@#"); i != -1 {
+		return name[:i]
+	}
+
+	return name
+}
+
+// lookup returns the pool and dataset of the given name. Either may be nil if
+// they do not exist.
+func (r *Runner) lookup(name string) (*pool, *dataset) {
+	p := r.pools[poolName(name)]
+	if p == nil {
+		return nil, nil
+	}
+
+	return p, p.datasets[name]
+}
+
+// sortedPools returns all imported pools sorted by name.
+func (r *Runner) sortedPools() []*pool {
+	pools := make([]*pool, 0, len(r.pools))
+	for _, p := range r.pools {
+		pools = append(pools, p)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].name < pools[j].name
+	})
+
+	return pools
+}
+
+// sortDatasets sorts datasets in the default order used by zfs list and zfs
+// get. Datasets are sorted by name, except for snapshots, which are listed
+// directly after the dataset they belong to, in order of creation.
+func sortDatasets(datasets []*dataset) {
+	sort.SliceStable(datasets, func(i, j int) bool {
+		return compareDatasets(datasets[i], datasets[j]) < 0
+	})
+}
+
+func compareDatasets(a, b *dataset) int {
+	an, bn := a.name, b.name
+	if a.typ == typeSnapshot {
+		an = parentName(an)
+	}
+	if b.typ == typeSnapshot {
+		bn = parentName(bn)
+	}
+
+	switch {
+	case an < bn:
+		return -1
+	case an > bn:
+		return 1
+	case a.typ != typeSnapshot && b.typ == typeSnapshot:
+		return -1
+	case a.typ == typeSnapshot && b.typ != typeSnapshot:
+		return 1
+	case a.txg < b.txg:
+		return -1
+	case a.txg > b.txg:
+		return 1
+	}
+
+	return strings.Compare(a.name, b.name)
+}
+
+// nextTXG returns the next transaction group number.
+func (r *Runner) nextTXG() uint64 {
+	r.txg++
+
+	return r.txg
+}
+
+// nextID returns a pseudo-random but deterministic identifier, as used for
+// GUIDs.
+func (r *Runner) nextID() uint64 {
+	// splitmix64
+	r.ids += 0x9e3779b97f4a7c15
+	z := r.ids
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+func (r *Runner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+
+	return time.Now()
+}
+
+func (r *Runner) poolSize() uint64 {
+	if r.PoolSize > 0 {
+		return r.PoolSize
+	}
+
+	return DefaultPoolSize
+}
+
+// newDataset returns a new dataset, which is not yet added to a pool.
+func (r *Runner) newDataset(
+	name string,
+	typ typeMask,
+	props map[string]string,
+) *dataset {
+	if props == nil {
+		props = map[string]string{}
+	}
+
+	ds := &dataset{
+		name:       name,
+		typ:        typ,
+		props:      props,
+		created:    r.now(),
+		txg:        r.nextTXG(),
+		guid:       r.nextID(),
+		objsetid:   r.nextID() % 0xffff,
+		referenced: filesystemReferenced,
+	}
+	if typ == typeVolume {
+		ds.referenced = volumeReferenced
+	}
+
+	return ds
+}
+
+// zfsProp returns the value as displayed with -p, and the source of the named
+// property of ds.
+func (p *pool) zfsProp(ds *dataset, name string) (string, string) {
+	def, native := zfsPropsByName[name]
+	switch {
+	case native && def.types&ds.typ == 0:
+		return "-", "-"
+	case native && def.readonly:
+		return p.zfsStat(ds, name), "-"
+	case isQuotaProp(name):
+		if v, ok := ds.props[name]; ok {
+			return v, "local"
+		}
+
+		return "0", "local"
+	}
+
+	if v, ok := ds.props[name]; ok {
+		return p.inheritedValue(name, v, ds, ds), "local"
+	}
+
+	if !native || def.inherit {
+		for a := p.parent(ds); a != nil; a = p.parent(a) {
+			if v, ok := a.props[name]; ok {
+				return p.inheritedValue(name, v, a, ds),
+					"inherited from " + a.name
+			}
+		}
+	}
+
+	switch {
+	case !native:
+		return "-", "-"
+	case name == "mountpoint":
+		return path.Join("/", p.altroot(), ds.name), "default"
+	}
+
+	return def.def, "default"
+}
+
+// inheritedValue returns the value v of property name set on dataset from, as
+// seen by dataset ds. Only mountpoints differ, as the relative path from the
+// ancestor is appended, and any altroot prepended.
+func (p *pool) inheritedValue(name, v string, from, ds *dataset) string {
+	if name != "mountpoint" || v == "none" || v == "legacy" {
+		return v
+	}
+
+	rel := strings.TrimPrefix(ds.name, from.name)
+
+	return path.Join("/", p.altroot(), v, rel)
+}
+
+// zfsValue returns the value of the named property of ds, as displayed with
+// -p.
+func (p *pool) zfsValue(ds *dataset, name string) string {
+	v, _ := p.zfsProp(ds, name)
+
+	return v
+}
+
+// zfsStat returns the value of the named read-only property of ds.
+//
+//nolint:gocyclo
+func (p *pool) zfsStat(ds *dataset, name string) string {
+	switch name {
+	case "type":
+		return ds.typeName()
+	case "creation":
+		return strconv.FormatInt(ds.created.Unix(), 10)
+	case "used", "logicalused":
+		return u64(p.used(ds))
+	case "available":
+		return u64(p.available(ds))
+	case "referenced", "logicalreferenced", "written", "usedbydataset":
+		return u64(ds.referenced)
+	case "usedbysnapshots":
+		return "0"
+	case "usedbychildren":
+		return u64(p.usedByChildren(ds))
+	case "usedbyrefreservation":
+		return u64(p.usedByRefReservation(ds))
+	case "compressratio", "refcompressratio":
+		return "1.00"
+	case "mounted":
+		if ds.mounted {
+			return "yes"
+		}
+
+		return "no"
+	case "createtxg":
+		return u64(ds.txg)
+	case "guid":
+		return u64(ds.guid)
+	case "objsetid":
+		return u64(ds.objsetid)
+	case "encryptionroot":
+		if root := p.encryptionRoot(ds); root != nil {
+			return root.name
+		}
+	case "keystatus":
+		if root := p.encryptionRoot(ds); root != nil {
+			if root.keyLoaded {
+				return "available"
+			}
+
+			return "unavailable"
+		}
+	case "clones":
+		return ""
+	case "defer_destroy":
+		return "off"
+	case "userrefs":
+		return "0"
+	}
+
+	return "-"
+}
+
+func (p *pool) used(ds *dataset) uint64 {
+	if ds.typ == typeSnapshot {
+		return 0
+	}
+
+	return ds.referenced + p.usedByChildren(ds) + p.usedByRefReservation(ds)
+}
+
+func (p *pool) usedByChildren(ds *dataset) uint64 {
+	var n uint64
+	for _, child := range p.children(ds) {
+		n += p.used(child)
+	}
+
+	return n
+}
+
+func (p *pool) usedByRefReservation(ds *dataset) uint64 {
+	refres := parseUint(ds.props["refreservation"])
+	if refres > ds.referenced {
+		return refres - ds.referenced
+	}
+
+	return 0
+}
+
+// available returns the space available to ds, taking into account the free
+// space in the pool, and quotas of ds and its ancestors.
+func (p *pool) available(ds *dataset) uint64 {
+	avail := sub(p.size, p.used(p.root()))
+	if refquota := parseUint(ds.props["refquota"]); refquota > 0 {
+		avail = minUint64(avail, sub(refquota, ds.referenced))
+	}
+	for a := ds; a != nil; a = p.parent(a) {
+		if quota := parseUint(a.props["quota"]); quota > 0 {
+			avail = minUint64(avail, sub(quota, p.used(a)))
+		}
+	}
+
+	return avail
+}
+
+// encryptionRoot returns the encryption root of ds, or nil if ds is not
+// encrypted.
+func (p *pool) encryptionRoot(ds *dataset) *dataset {
+	for a := ds; a != nil; a = p.parent(a) {
+		if a.encRoot {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// canMount returns true if ds is a filesystem which is mounted automatically.
+func (p *pool) canMount(ds *dataset) bool {
+	if ds.typ != typeFilesystem || p.zfsValue(ds, "canmount") != "on" {
+		return false
+	}
+	if mp := p.zfsValue(ds, "mountpoint"); mp == "none" || mp == "legacy" {
+		return false
+	}
+	if root := p.encryptionRoot(ds); root != nil && !root.keyLoaded {
+		return false
+	}
+
+	return true
+}
+
+// mountAll mounts all filesystems in the pool which are mounted
+// automatically.
+func (p *pool) mountAll() {
+	for _, ds := range p.datasets {
+		if p.canMount(ds) {
+			ds.mounted = true
+		}
+	}
+}
+
+func u64(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+func parseUint(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+
+	return n
+}
+
+func sub(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+
+	return a - b
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,1101 @@
+package zfstest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//nolint:lll
+var zfsCommands = map[string]subcommand{
+	"get": {
+		run:   (*Runner).zfsGet,
+		usage: `get [-rHp] [-d max] [-o "all" | field[,...]] [-t type[,...]] [-s source[,...]] <"all" | property[,...]> [filesystem|volume|snapshot|bookmark] ...`,
+	},
+	"set": {
+		run:   (*Runner).zfsSet,
+		usage: "set <property=value> ... <filesystem|volume|snapshot> ...",
+	},
+	"inherit": {
+		run:   (*Runner).zfsInherit,
+		usage: "inherit [-rS] <property> <filesystem|volume|snapshot> ...",
+	},
+	"list": {
+		run:   (*Runner).zfsList,
+		usage: "list [-Hp] [-r|-d max] [-o property[,...]] [-s property]... [-S property]... [-t type[,...]] [filesystem|volume|snapshot] ...",
+	},
+	"create": {
+		run:   (*Runner).zfsCreate,
+		usage: "create [-Pnpuv] [-o property=value] ... <filesystem>\n\tcreate [-Pnpsv] [-b blocksize] [-o property=value] ... -V <size> <volume>",
+	},
+	"destroy": {
+		run:   (*Runner).zfsDestroy,
+		usage: "destroy [-fnpRrv] <filesystem|volume>\n\tdestroy [-dnpRrv] <filesystem|volume>@<snap>[%<snap>][,...]",
+	},
+	"snapshot": {
+		run:   (*Runner).zfsSnapshot,
+		usage: "snapshot [-r] [-o property=value] ... <filesystem|volume>@<snap> ...",
+	},
+	"userspace": {
+		run:   (*Runner).zfsUserSpace,
+		usage: "userspace [-Hinp] [-o field[,...]] [-t type[,...]] <filesystem|snapshot>",
+	},
+	"groupspace": {
+		run:   (*Runner).zfsGroupSpace,
+		usage: "groupspace [-Hinp] [-o field[,...]] [-t type[,...]] <filesystem|snapshot>",
+	},
+	"projectspace": {
+		run:   (*Runner).zfsProjectSpace,
+		usage: "projectspace [-Hp] [-o field[,...]] <filesystem|snapshot>",
+	},
+	"mount": {
+		run:   (*Runner).zfsMount,
+		usage: "mount\n\tmount [-flvO] [-o opts] <-a | filesystem>",
+	},
+	"unmount": {
+		run:   (*Runner).zfsUnmount,
+		usage: "unmount [-fu] <-a | filesystem|mountpoint>",
+	},
+	"umount": {
+		run:   (*Runner).zfsUnmount,
+		usage: "unmount [-fu] <-a | filesystem|mountpoint>",
+	},
+	"share": {
+		run:   (*Runner).zfsShare,
+		usage: "share [-l] <-a [nfs|smb] | filesystem>",
+	},
+	"unshare": {
+		run:   (*Runner).zfsUnshare,
+		usage: "unshare <-a [nfs|smb] | filesystem|mountpoint>",
+	},
+	"load-key": {
+		run:   (*Runner).zfsLoadKey,
+		usage: "load-key [-rn] [-L <keylocation>] <-a | filesystem|volume>",
+	},
+	"unload-key": {
+		run:   (*Runner).zfsUnloadKey,
+		usage: "unload-key [-r] <-a | filesystem|volume>",
+	},
+	"change-key": {
+		run:   (*Runner).zfsChangeKey,
+		usage: "change-key [-l] [-o keyformat=<value>]\n\t    [-o keylocation=<value>] [-o pbkdf2iters=<value>]\n\t    <filesystem|volume>\n\tchange-key -i [-l] <filesystem|volume>",
+	},
+}
+
+// getFields are the default fields of zfs get and zpool get, and the fields
+// supported by the -o flag of zpool get.
+var getFields = []string{"name", "property", "value", "source"}
+
+// zfsGetFields are the fields supported by the -o flag of zfs get. The fake
+// does not support zfs receive, so received values are always "-".
+var zfsGetFields = []string{"name", "property", "value", "received", "source"}
+
+// zfsEntry is a dataset along with the pool it belongs to.
+type zfsEntry struct {
+	pool *pool
+	ds   *dataset
+}
+
+// selectDatasets returns the named datasets, and if depth is non-zero their
+// descendants up to depth levels below, matching types. A negative depth
+// means unlimited. If no names are given, all datasets of all pools are
+// selected. Named datasets are only filtered by type if filterNamed is true.
+func (r *Runner) selectDatasets(
+	names []string,
+	depth int,
+	types typeMask,
+	filterNamed bool,
+) ([]zfsEntry, error) {
+	if len(names) == 0 {
+		depth = -1
+		filterNamed = true
+		for _, p := range r.sortedPools() {
+			names = append(names, p.name)
+		}
+	}
+
+	seen := map[*dataset]bool{}
+	entries := []zfsEntry{}
+	add := func(p *pool, ds *dataset) {
+		if !seen[ds] && ds.typ&types != 0 {
+			seen[ds] = true
+			entries = append(entries, zfsEntry{pool: p, ds: ds})
+		}
+	}
+
+	for _, name := range names {
+		p, ds := r.lookup(name)
+		if ds == nil {
+			return nil, failf("cannot open '%s': dataset does not exist", name)
+		}
+		if filterNamed {
+			add(p, ds)
+		} else if !seen[ds] {
+			seen[ds] = true
+			entries = append(entries, zfsEntry{pool: p, ds: ds})
+		}
+		if depth == 0 {
+			continue
+		}
+
+		for _, d := range p.descendants(ds) {
+			if depth < 0 || relativeDepth(ds, d) <= depth {
+				add(p, d)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareDatasets(entries[i].ds, entries[j].ds) < 0
+	})
+
+	return entries, nil
+}
+
+// relativeDepth returns how many levels below ancestor ds is, where snapshots
+// are one level below their dataset.
+func relativeDepth(ancestor, ds *dataset) int {
+	rel := strings.TrimPrefix(ds.name, ancestor.name)
+	depth := strings.Count(rel, "/")
+	if strings.ContainsAny(rel, "@#") {
+		depth++
+	}
+
+	return depth
+}
+
+// depthFlag returns the depth requested by the -r and -d flags.
+func depthFlag(f flags) (int, error) {
+	if f.has("d") {
+		d, err := strconv.Atoi(f.value("d"))
+		if err != nil || d < 0 {
+			return 0, usagef("invalid depth '%s'", f.value("d"))
+		}
+
+		return d, nil
+	}
+	if f.has("r") {
+		return -1, nil
+	}
+
+	return 0, nil
+}
+
+func (r *Runner) zfsGet(c *cmd) error {
+	f, args, err := getopt(c.args, "rHpd:o:t:s:")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("missing property argument")
+	}
+
+	fields := getFields
+	switch {
+	case f.has("o") && f.value("o") == "all":
+		fields = zfsGetFields
+	case f.has("o"):
+		fields = strings.Split(f.value("o"), ",")
+		for _, field := range fields {
+			if !contains(zfsGetFields, field) {
+				return usagef("invalid field '%s'", field)
+			}
+		}
+	}
+
+	types := typeAll
+	if f.has("t") {
+		if types, err = parseTypes(f.value("t")); err != nil {
+			return err
+		}
+	}
+
+	var sources []string
+	if f.has("s") {
+		sources = strings.Split(f.value("s"), ",")
+	}
+
+	props := strings.Split(args[0], ",")
+	for i, prop := range props {
+		props[i] = zfsPropName(prop)
+		if prop != "all" && !validZFSProp(props[i]) {
+			return usagef("bad property list: invalid property '%s'", prop)
+		}
+	}
+
+	depth, err := depthFlag(f)
+	if err != nil {
+		return err
+	}
+
+	entries, err := r.selectDatasets(args[1:], depth, types, true)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, e := range entries {
+		for _, prop := range expandProps(e.pool, e.ds, props) {
+			value, source := e.pool.zfsProp(e.ds, prop)
+			if sources != nil && !contains(sources, sourceKind(source)) {
+				continue
+			}
+
+			rows = append(rows, getRow(fields, e.ds.name, prop,
+				formatZFSValue(prop, value, f.has("p")), source,
+			))
+		}
+	}
+
+	writeTable(c.stdout, f.has("H"), fields, rows)
+
+	return nil
+}
+
+// expandProps expands "all" in props to all native properties applicable to
+// ds, followed by all user and quota properties set on or inherited by ds.
+func expandProps(p *pool, ds *dataset, props []string) []string {
+	if !contains(props, "all") {
+		return props
+	}
+
+	r := []string{}
+	for _, def := range zfsProps {
+		if def.types&ds.typ != 0 {
+			r = append(r, def.name)
+		}
+	}
+
+	extra := map[string]string{}
+	for k := range ds.props {
+		if isQuotaProp(k) {
+			extra[k] = ""
+		}
+	}
+	for a := ds; a != nil; a = p.parent(a) {
+		for k := range a.props {
+			if isUserProp(k) {
+				extra[k] = ""
+			}
+		}
+	}
+
+	return append(r, sortedKeys(extra, nil)...)
+}
+
+// sourceKind returns the kind of source, as accepted by the -s flag of zfs
+// get.
+func sourceKind(source string) string {
+	switch {
+	case strings.HasPrefix(source, "inherited"):
+		return "inherited"
+	case source == "-":
+		return "none"
+	}
+
+	return source
+}
+
+func getRow(fields []string, name, prop, value, source string) []string {
+	row := make([]string, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "name":
+			row[i] = name
+		case "property":
+			row[i] = prop
+		case "value":
+			row[i] = value
+		case "source":
+			row[i] = source
+		case "received":
+			row[i] = "-"
+		}
+	}
+
+	return row
+}
+
+// formatZFSValue returns value of the named property as displayed with or
+// without the -p flag.
+func formatZFSValue(name, value string, parsable bool) string {
+	if def, ok := zfsPropsByName[name]; ok {
+		return def.format(value, parsable)
+	}
+	if isQuotaProp(name) {
+		return (&propDef{kind: kindSize, none: true}).format(value, parsable)
+	}
+
+	return value
+}
+
+func (r *Runner) zfsList(c *cmd) error {
+	f, args, err := getopt(c.args, "rHpd:o:t:s:S:")
+	if err != nil {
+		return err
+	}
+
+	columns := []string{"name", "used", "avail", "refer", "mountpoint"}
+	if f.has("o") {
+		columns = strings.Split(f.value("o"), ",")
+	}
+	props := make([]string, len(columns))
+	for i, col := range columns {
+		props[i] = zfsPropName(col)
+		if props[i] != "name" && !validZFSProp(props[i]) {
+			return usagef("bad property list: invalid property '%s'", col)
+		}
+	}
+
+	types := typeDataset
+	if f.has("t") {
+		if types, err = parseTypes(f.value("t")); err != nil {
+			return err
+		}
+	}
+
+	depth, err := depthFlag(f)
+	if err != nil {
+		return err
+	}
+
+	entries, err := r.selectDatasets(args, depth, types, f.has("t"))
+	if err != nil {
+		return err
+	}
+	if err = sortEntries(entries, f); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		row := make([]string, len(props))
+		for i, prop := range props {
+			if prop == "name" {
+				row[i] = e.ds.name
+
+				continue
+			}
+			row[i] = formatZFSValue(
+				prop, e.pool.zfsValue(e.ds, prop), f.has("p"),
+			)
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 && !f.has("H") {
+		c.stderr.WriteString("no datasets available\n")
+
+		return nil
+	}
+
+	writeTable(c.stdout, f.has("H"), columns, rows)
+
+	return nil
+}
+
+// sortKey is a property to sort zfs list output by, as given by the -s and -S
+// flags.
+type sortKey struct {
+	prop string
+	desc bool
+}
+
+// sortEntries sorts entries by the properties given with the -s and -S flags,
+// in the order given, falling back on the default order.
+func sortEntries(entries []zfsEntry, f flags) error {
+	keys := []sortKey{}
+	for _, arg := range sortArgs(f) {
+		prop := zfsPropName(
+			strings.TrimPrefix(strings.TrimPrefix(arg, "-s"), "-S"),
+		)
+		if prop != "name" && !validZFSProp(prop) {
+			return usagef("invalid property '%s'", prop)
+		}
+		keys = append(keys, sortKey{
+			prop: prop,
+			desc: strings.HasPrefix(arg, "-S"),
+		})
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		for _, key := range keys {
+			c := compareProp(entries[i], entries[j], key.prop)
+			if c != 0 {
+				return (c < 0) != key.desc
+			}
+		}
+
+		return false
+	})
+
+	return nil
+}
+
+// sortArgs returns the -s and -S flags in the order they were given, each
+// prefixed with the flag.
+func sortArgs(f flags) []string {
+	args := []string{}
+	for _, v := range f["s"] {
+		args = append(args, "-s"+v)
+	}
+	for _, v := range f["S"] {
+		args = append(args, "-S"+v)
+	}
+
+	return args
+}
+
+// compareProp compares the named property of two entries, numerically if
+// both values are numbers.
+func compareProp(a, b zfsEntry, prop string) int {
+	if prop == "name" {
+		return strings.Compare(a.ds.name, b.ds.name)
+	}
+
+	av := a.pool.zfsValue(a.ds, prop)
+	bv := b.pool.zfsValue(b.ds, prop)
+	an, aerr := strconv.ParseUint(av, 10, 64)
+	bn, berr := strconv.ParseUint(bv, 10, 64)
+	switch {
+	case aerr != nil || berr != nil:
+		return strings.Compare(av, bv)
+	case an < bn:
+		return -1
+	case an > bn:
+		return 1
+	}
+
+	return 0
+}
+
+func (r *Runner) zfsSet(c *cmd) error {
+	args := c.args
+	if len(args) == 0 {
+		return usagef("missing arguments")
+	}
+
+	props := map[string]string{}
+	for len(args) > 0 {
+		k, v, ok := splitProp(args[0])
+		if !ok {
+			break
+		}
+		props[zfsPropName(k)] = v
+		args = args[1:]
+	}
+	switch {
+	case len(props) == 0:
+		return usagef("missing '=' for property argument")
+	case len(args) == 0:
+		return usagef("missing dataset name(s)")
+	}
+
+	for _, name := range args {
+		p, ds := r.lookup(name)
+		if ds == nil {
+			return failf("cannot open '%s': dataset does not exist", name)
+		}
+		if err := r.setZFSProps(p, ds, props); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setZFSProps validates and sets all props on ds, or none of them if any are
+// invalid.
+func (r *Runner) setZFSProps(
+	p *pool,
+	ds *dataset,
+	props map[string]string,
+) error {
+	values := map[string]string{}
+	for _, k := range sortedKeys(props, nil) {
+		v, err := checkZFSProp(ds.typ, k, props[k], false)
+		if err == nil {
+			err = checkSpace(p, ds, k, v)
+		}
+		if err != nil {
+			return failf("cannot set property for '%s': %s", ds.name, err)
+		}
+		values[k] = v
+	}
+
+	for k, v := range values {
+		ds.props[k] = v
+	}
+
+	if !p.canMount(ds) {
+		ds.mounted = false
+	}
+
+	return nil
+}
+
+// checkSpace verifies that setting a quota or reservation property on ds is
+// possible given the space currently used and available.
+func checkSpace(p *pool, ds *dataset, prop, value string) error {
+	n := parseUint(value)
+	if n == 0 {
+		return nil
+	}
+
+	switch prop {
+	case "quota", "refquota":
+		used := p.used(ds)
+		if prop == "refquota" {
+			used = ds.referenced
+		}
+		if n < used {
+			return failf(
+				"size is less than current used or reserved space",
+			)
+		}
+	case "reservation", "refreservation":
+		if n > p.available(ds)+p.usedByRefReservation(ds) {
+			return failf("size is greater than available space")
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) zfsInherit(c *cmd) error {
+	f, args, err := getopt(c.args, "rS")
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) == 0:
+		return usagef("missing property argument")
+	case len(args) == 1:
+		return usagef("missing dataset argument")
+	}
+
+	prop := zfsPropName(args[0])
+	def, native := zfsPropsByName[prop]
+	switch {
+	case !native && !isUserProp(prop) && !isQuotaProp(prop):
+		return usagef("invalid property '%s'", prop)
+	case native && def.readonly:
+		return usagef("'%s' property is read-only", prop)
+	case native && !def.inherit, isQuotaProp(prop):
+		return usagef("'%s' property cannot be inherited", prop)
+	}
+
+	for _, name := range args[1:] {
+		p, ds := r.lookup(name)
+		if ds == nil {
+			return failf("cannot open '%s': dataset does not exist", name)
+		}
+
+		delete(ds.props, prop)
+		if f.has("r") {
+			for _, d := range p.descendants(ds) {
+				delete(d.props, prop)
+			}
+		}
+	}
+
+	return nil
+}
+
+// createOptions are the parsed options of zfs create.
+type createOptions struct {
+	name    string
+	typ     typeMask
+	props   map[string]string
+	parents bool
+	mount   bool
+	sparse  bool
+}
+
+func (r *Runner) zfsCreate(c *cmd) error {
+	f, args, err := getopt(c.args, "pusb:o:V:")
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) == 0:
+		return usagef("missing filesystem argument")
+	case len(args) > 1:
+		return usagef("too many arguments")
+	case (f.has("b") || f.has("s")) && !f.has("V"):
+		return usagef("'-b' and '-s' options only apply to volumes")
+	}
+
+	opts := &createOptions{
+		name:    args[0],
+		typ:     typeFilesystem,
+		props:   map[string]string{},
+		parents: f.has("p"),
+		mount:   !f.has("u"),
+		sparse:  f.has("s"),
+	}
+	for _, o := range f["o"] {
+		k, v, ok := splitProp(o)
+		if !ok {
+			return usagef("missing '=' for -o option")
+		}
+		opts.props[zfsPropName(k)] = v
+	}
+	if f.has("V") {
+		opts.typ = typeVolume
+		opts.props["volsize"] = f.value("V")
+		if f.has("b") {
+			opts.props["volblocksize"] = f.value("b")
+		}
+	}
+
+	return r.create(c, opts)
+}
+
+// create creates a new filesystem or volume as per opts.
+func (r *Runner) create(c *cmd, opts *createOptions) error {
+	name := opts.name
+	if msg := checkName(name); msg != "" {
+		return failf("cannot create '%s': %s", name, msg)
+	}
+
+	p, ds := r.lookup(name)
+	switch {
+	case p == nil:
+		return failf("cannot create '%s': no such pool '%s'",
+			name, poolName(name),
+		)
+	case ds != nil:
+		return failf("cannot create '%s': dataset already exists", name)
+	}
+
+	parent := p.datasets[parentName(name)]
+	switch {
+	case parent == nil && !opts.parents:
+		return failf("cannot create '%s': parent does not exist", name)
+	case parent != nil && parent.typ != typeFilesystem:
+		return failf("cannot create '%s': parent is not a filesystem", name)
+	}
+
+	props, err := checkCreateProps(p, opts)
+	if err != nil {
+		return err
+	}
+
+	if parent == nil {
+		err = r.create(c, &createOptions{
+			name:    parentName(name),
+			typ:     typeFilesystem,
+			props:   map[string]string{},
+			parents: true,
+			mount:   true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	ds = r.newDataset(name, opts.typ, props)
+	if err = r.setupEncryption(c, p, ds); err != nil {
+		return failf("cannot create '%s': %s", name, err)
+	}
+
+	p.datasets[name] = ds
+	ds.mounted = opts.mount && p.canMount(ds)
+
+	return nil
+}
+
+// checkCreateProps validates the properties of a new dataset, returning them
+// as they are stored.
+func checkCreateProps(
+	p *pool,
+	opts *createOptions,
+) (map[string]string, error) {
+	props := map[string]string{}
+	for _, k := range sortedKeys(opts.props, nil) {
+		v, err := checkZFSProp(opts.typ, k, opts.props[k], true)
+		if err != nil {
+			return nil, failf("cannot create '%s': %s", opts.name, err)
+		}
+		props[k] = v
+	}
+
+	if opts.typ != typeVolume {
+		return props, nil
+	}
+
+	size := parseUint(props["volsize"])
+	bs := parseUint(props["volblocksize"])
+	if bs == 0 {
+		bs = parseUint(zfsPropsByName["volblocksize"].def)
+	}
+	switch {
+	case size == 0:
+		return nil, failf("cannot create '%s': volume size cannot be zero",
+			opts.name,
+		)
+	case size%bs != 0:
+		return nil, failf("cannot create '%s': volume size must be a "+
+			"multiple of volume block size", opts.name,
+		)
+	}
+
+	if !opts.sparse {
+		if _, ok := props["refreservation"]; !ok {
+			props["refreservation"] = u64(size)
+		}
+		if parent := p.datasets[parentName(opts.name)]; parent != nil &&
+			parseUint(props["refreservation"]) > p.available(parent) {
+			return nil, failf("cannot create '%s': out of space", opts.name)
+		}
+	}
+
+	return props, nil
+}
+
+// checkName returns a message describing why name is not a valid filesystem
+// or volume name, or an empty string if it is valid.
+func checkName(name string) string {
+	switch {
+	case name == "":
+		return "empty component in name"
+	case strings.HasPrefix(name, "/"):
+		return "leading slash in name"
+	case strings.HasSuffix(name, "/"):
+		return "trailing slash in name"
+	case strings.Contains(name, "//"):
+		return "empty component in name"
+	case strings.Contains(name, "@"):
+		return "snapshot delimiter '@' is not expected here"
+	case strings.Contains(name, "#"):
+		return "bookmark delimiter '#' is not expected here"
+	case len(name) > 255:
+		return "name is too long"
+	}
+
+	for _, c := range name {
+		if !validNameChar(c) && c != '/' {
+			return fmt.Sprintf("invalid character '%c' in name", c)
+		}
+	}
+
+	return ""
+}
+
+func validNameChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || strings.ContainsRune("-_.: ", c)
+}
+
+func (r *Runner) zfsDestroy(c *cmd) error {
+	f, args, err := getopt(c.args, "rRdfnpv")
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) == 0:
+		return usagef("missing dataset argument")
+	case len(args) > 1:
+		return usagef("too many arguments")
+	}
+
+	name := args[0]
+	var targets []zfsEntry
+	if strings.Contains(name, "@") {
+		targets, err = r.destroySnapshotTargets(name, f.has("r") || f.has("R"))
+	} else {
+		targets, err = r.destroyTargets(name, f.has("r") || f.has("R"))
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		if t.ds.busy {
+			if t.ds.typ == typeSnapshot {
+				return failf("cannot destroy snapshot %s: dataset is busy",
+					t.ds.name,
+				)
+			}
+
+			return failf("cannot destroy '%s': dataset is busy", t.ds.name)
+		}
+	}
+
+	for _, t := range targets {
+		if f.has("v") || f.has("p") {
+			writeDestroyLine(c, f, t.ds.name)
+		}
+		if !f.has("n") {
+			delete(t.pool.datasets, t.ds.name)
+		}
+	}
+
+	return nil
+}
+
+func writeDestroyLine(c *cmd, f flags, name string) {
+	switch {
+	case f.has("p"):
+		fmt.Fprintf(c.stdout, "destroy\t%s\n", name)
+	case f.has("n"):
+		fmt.Fprintf(c.stdout, "would destroy %s\n", name)
+	default:
+		fmt.Fprintf(c.stdout, "will destroy %s\n", name)
+	}
+}
+
+// destroyTargets returns the datasets destroyed by destroying the named
+// filesystem or volume, children last.
+func (r *Runner) destroyTargets(name string, recursive bool) (
+	[]zfsEntry, error,
+) {
+	p, ds := r.lookup(name)
+	if ds == nil {
+		return nil, failf("cannot open '%s': dataset does not exist", name)
+	}
+
+	descendants := p.descendants(ds)
+	if len(descendants) > 0 && !recursive {
+		names := make([]string, len(descendants))
+		for i, d := range descendants {
+			names[i] = d.name
+		}
+
+		return nil, failf("cannot destroy '%s': %s has children\n"+
+			"use '-r' to destroy the following datasets:\n%s",
+			name, ds.typeName(), strings.Join(names, "\n"),
+		)
+	}
+
+	targets := make([]zfsEntry, 0, len(descendants)+1)
+	for i := len(descendants) - 1; i >= 0; i-- {
+		targets = append(targets, zfsEntry{pool: p, ds: descendants[i]})
+	}
+
+	if ds == p.root() {
+		if !recursive {
+			return nil, failf("cannot destroy '%s': operation does not "+
+				"apply to pools\n"+
+				"use 'zfs destroy -r %s' to destroy all datasets in the pool\n"+
+				"use 'zpool destroy %s' to destroy the pool itself",
+				name, name, name,
+			)
+		}
+
+		return targets, nil
+	}
+
+	return append(targets, zfsEntry{pool: p, ds: ds}), nil
+}
+
+// destroySnapshotTargets returns the snapshots destroyed by destroying the
+// named snapshots, which if recursive includes snapshots of the same names of
+// all descendants. Snapshots may be given as a comma separated list of names
+// and ranges, like "tank@a,c%e", where a range includes all snapshots created
+// between its ends, and either end may be omitted.
+func (r *Runner) destroySnapshotTargets(name string, recursive bool) (
+	[]zfsEntry, error,
+) {
+	i := strings.IndexByte(name, '@')
+	p, parent := r.lookup(name[:i])
+
+	targets := []zfsEntry{}
+	if parent != nil {
+		datasets := []*dataset{parent}
+		if recursive {
+			for _, d := range p.descendants(parent) {
+				if d.typ != typeSnapshot {
+					datasets = append(datasets, d)
+				}
+			}
+		}
+
+		seen := map[*dataset]bool{}
+		for _, d := range datasets {
+			for _, snap := range p.matchSnapshots(d, name[i+1:]) {
+				if !seen[snap] {
+					seen[snap] = true
+					targets = append(targets, zfsEntry{pool: p, ds: snap})
+				}
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, failf("could not find any snapshots to destroy; " +
+			"check snapshot names.",
+		)
+	}
+
+	return targets, nil
+}
+
+// matchSnapshots returns the snapshots of ds matched by spec, a comma
+// separated list of snapshot names and ranges. Names and range ends which do
+// not exist are ignored, matching nothing.
+func (p *pool) matchSnapshots(ds *dataset, spec string) []*dataset {
+	snapshots := []*dataset{}
+	for _, d := range p.descendants(ds) {
+		if strings.HasPrefix(d.name, ds.name+"@") {
+			snapshots = append(snapshots, d)
+		}
+	}
+	index := func(snap string) int {
+		for i, d := range snapshots {
+			if d.name == ds.name+"@"+snap {
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	matches := []*dataset{}
+	for _, item := range strings.Split(spec, ",") {
+		ends := strings.SplitN(item, "%", 2)
+		if len(ends) == 1 {
+			if i := index(item); i != -1 {
+				matches = append(matches, snapshots[i])
+			}
+
+			continue
+		}
+
+		start, end := 0, len(snapshots)-1
+		if ends[0] != "" {
+			start = index(ends[0])
+		}
+		if ends[1] != "" {
+			end = index(ends[1])
+		}
+		if start != -1 && end != -1 {
+			for i := start; i <= end; i++ {
+				matches = append(matches, snapshots[i])
+			}
+		}
+	}
+
+	return matches
+}
+
+func (r *Runner) zfsSnapshot(c *cmd) error {
+	f, args, err := getopt(c.args, "ro:")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("missing snapshot argument")
+	}
+
+	props := map[string]string{}
+	for _, o := range f["o"] {
+		k, v, ok := splitProp(o)
+		if !ok {
+			return usagef("missing '=' for -o option")
+		}
+		k = zfsPropName(k)
+		if v, err = checkZFSProp(typeSnapshot, k, v, true); err != nil {
+			return failf("cannot create snapshot '%s': %s", args[0], err)
+		}
+		props[k] = v
+	}
+
+	var entries []zfsEntry
+	for _, name := range args {
+		e, err := r.snapshotTargets(name, f.has("r"))
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e...)
+	}
+
+	txg := r.nextTXG()
+	for _, e := range entries {
+		snap := r.newDataset(e.ds.name, typeSnapshot, copyProps(props))
+		snap.txg = txg
+		snap.referenced = e.ds.referenced
+		e.pool.datasets[snap.name] = snap
+	}
+
+	return nil
+}
+
+// snapshotTargets returns entries for the snapshots to create for the named
+// snapshot, where each entry holds the name of the snapshot, and the dataset
+// it is a snapshot of.
+func (r *Runner) snapshotTargets(name string, recursive bool) (
+	[]zfsEntry, error,
+) {
+	i := strings.IndexByte(name, '@')
+	if i == -1 {
+		return nil, failf("cannot create snapshot '%s': "+
+			"missing '@' delimiter in snapshot name", name,
+		)
+	}
+
+	p, ds := r.lookup(name[:i])
+	if ds == nil {
+		return nil, failf("cannot open '%s': dataset does not exist",
+			name[:i],
+		)
+	}
+	if msg := checkName(name[i+1:]); msg != "" || i == len(name)-1 {
+		return nil, failf("cannot create snapshot '%s': "+
+			"invalid character in snapshot name", name,
+		)
+	}
+
+	datasets := []*dataset{ds}
+	if recursive {
+		for _, d := range p.descendants(ds) {
+			if d.typ != typeSnapshot {
+				datasets = append(datasets, d)
+			}
+		}
+	}
+
+	entries := make([]zfsEntry, 0, len(datasets))
+	for _, d := range datasets {
+		snap := d.name + name[i:]
+		if p.datasets[snap] != nil {
+			return nil, failf(
+				"cannot create snapshot '%s': dataset already exists", snap,
+			)
+		}
+		entries = append(entries, zfsEntry{
+			pool: p,
+			ds:   &dataset{name: snap, referenced: d.referenced},
+		})
+	}
+
+	return entries, nil
+}
+
+func copyProps(props map[string]string) map[string]string {
+	r := make(map[string]string, len(props))
+	for k, v := range props {
+		r[k] = v
+	}
+
+	return r
+}
+
+// sortedKeys returns the keys of m in sorted order, optionally filtered by
+// keep.
+func sortedKeys(m map[string]string, keep func(string) bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if keep == nil || keep(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,850 @@
+package zfstest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krystal/go-zfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func datasetNames(datasets []*zfs.Dataset) []string {
+	names := make([]string, 0, len(datasets))
+	for _, ds := range datasets {
+		names = append(names, ds.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestManager_CreateDataset(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        *zfs.CreateDatasetOptions
+		wantProps      map[string]string
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name: "filesystem",
+			options: &zfs.CreateDatasetOptions{
+				Name: "tank/data",
+				Properties: map[string]string{
+					"compression":       "lz4",
+					"quota":             "1G",
+					"com.example:owner": "alice",
+				},
+			},
+			wantProps: map[string]string{
+				"type":              "filesystem",
+				"compression":       "lz4",
+				"quota":             "1073741824",
+				"mountpoint":        "/tank/data",
+				"mounted":           "yes",
+				"com.example:owner": "alice",
+			},
+		},
+		{
+			name: "property aliases",
+			options: &zfs.CreateDatasetOptions{
+				Name: "tank/data",
+				Properties: map[string]string{
+					"compress": "lz4",
+					"recsize":  "64K",
+				},
+			},
+			wantProps: map[string]string{
+				"compression": "lz4",
+				"recordsize":  "65536",
+			},
+		},
+		{
+			name: "unmounted with parents",
+			options: &zfs.CreateDatasetOptions{
+				Name:          "tank/a/b",
+				CreateParents: true,
+				Unmounted:     true,
+			},
+			wantProps: map[string]string{
+				"type":    "filesystem",
+				"mounted": "no",
+			},
+		},
+		{
+			name: "volume",
+			options: &zfs.CreateDatasetOptions{
+				Name:       "tank/vol",
+				VolumeSize: "64M",
+			},
+			wantProps: map[string]string{
+				"type":           "volume",
+				"volsize":        "67108864",
+				"refreservation": "67108864",
+			},
+		},
+		{
+			name: "sparse volume",
+			options: &zfs.CreateDatasetOptions{
+				Name:       "tank/vol",
+				VolumeSize: "64M",
+				Sparse:     true,
+			},
+			wantProps: map[string]string{
+				"volsize":        "67108864",
+				"refreservation": "0",
+			},
+		},
+		{
+			name:    "already exists",
+			options: &zfs.CreateDatasetOptions{Name: "tank"},
+			wantErr: "zfs; already exists; exit status 1: " +
+				"cannot create 'tank': dataset already exists",
+			wantErrTargets: []error{zfs.ErrZFS, zfs.ErrExists},
+		},
+		{
+			name:           "missing parent",
+			options:        &zfs.CreateDatasetOptions{Name: "tank/a/b"},
+			wantErrTargets: []error{zfs.ErrZFS, zfs.ErrNotFound},
+		},
+		{
+			name: "invalid property value",
+			options: &zfs.CreateDatasetOptions{
+				Name:       "tank/data",
+				Properties: map[string]string{"atime": "maybe"},
+			},
+			wantErrTargets: []error{zfs.ErrZFS, zfs.ErrInvalidProperty},
+		},
+		{
+			name: "volume too large",
+			options: &zfs.CreateDatasetOptions{
+				Name:       "tank/vol",
+				VolumeSize: "2G",
+			},
+			wantErrTargets: []error{zfs.ErrZFS, zfs.ErrNoSpace},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, m := newManager(t)
+			ctx := context.Background()
+
+			err := m.CreateDataset(ctx, tt.options)
+
+			if tt.wantErr != "" || len(tt.wantErrTargets) > 0 {
+				require.Error(t, err)
+				if tt.wantErr != "" {
+					assert.EqualError(t, err, tt.wantErr)
+				}
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			ds, err := m.GetDataset(ctx, tt.options.Name)
+			require.NoError(t, err)
+			for k, v := range tt.wantProps {
+				assert.Equal(t, v, ds.Properties[k].Value, k)
+			}
+		})
+	}
+}
+
+func TestManager_GetDataset_inheritance(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/a",
+		Properties: map[string]string{
+			"mountpoint":        "/srv",
+			"compression":       "zstd",
+			"com.example:owner": "alice",
+		},
+	}))
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/a/b",
+	}))
+
+	ds, err := m.GetDataset(ctx, "tank/a/b")
+	require.NoError(t, err)
+
+	assert.Equal(t, zfs.Property{
+		Name:     "tank/a/b",
+		Property: "mountpoint",
+		Value:    "/srv/b",
+		Source:   "inherited from tank/a",
+	}, ds.Properties["mountpoint"])
+	assert.Equal(t, zfs.Property{
+		Name:     "tank/a/b",
+		Property: "compression",
+		Value:    "zstd",
+		Source:   "inherited from tank/a",
+	}, ds.Properties["compression"])
+	assert.Equal(t, zfs.Property{
+		Name:     "tank/a/b",
+		Property: "com.example:owner",
+		Value:    "alice",
+		Source:   "inherited from tank/a",
+	}, ds.Properties["com.example:owner"])
+	assert.Equal(t, zfs.Property{
+		Name:     "tank/a/b",
+		Property: "atime",
+		Value:    "on",
+		Source:   "default",
+	}, ds.Properties["atime"])
+
+	created, ok := ds.Creation()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1648814400), created.Unix())
+
+	require.NoError(t, m.InheritDatasetProperty(
+		ctx, "tank/a", "compression", false,
+	))
+	v, err := m.GetDatasetProperty(ctx, "tank/a/b", "compression")
+	require.NoError(t, err)
+	assert.Equal(t, "off", v)
+}
+
+func TestManager_GetDatasetReceived(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name:       "tank/a",
+		Properties: map[string]string{"compression": "zstd"},
+	}))
+
+	ds, err := m.GetDatasetReceived(ctx, "tank/a", "compression")
+	require.NoError(t, err)
+	assert.Equal(t, zfs.Property{
+		Name:     "tank/a",
+		Property: "compression",
+		Value:    "zstd",
+		Received: "-",
+		Source:   "local",
+	}, ds.Properties["compression"])
+	assert.False(t, ds.OverridesReceived("compression"))
+
+	require.NoError(t, m.RevertToReceived(ctx, "tank/a", "compression", false))
+	ds, err = m.GetDatasetBySource(ctx, "tank/a",
+		[]zfs.SourceKind{zfs.SourceLocal}, "compression",
+	)
+	require.NoError(t, err)
+	assert.Empty(t, ds.Properties)
+}
+
+func TestManager_SetDatasetProperties(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/data",
+	}))
+
+	err := m.SetDatasetProperties(ctx, "tank/data", map[string]string{
+		"atime":      "off",
+		"recordsize": "1M",
+	})
+	require.NoError(t, err)
+
+	ds, err := m.GetDataset(ctx, "tank/data", "atime", "recordsize")
+	require.NoError(t, err)
+	atime, ok := ds.Atime()
+	assert.True(t, ok)
+	assert.False(t, atime)
+	assert.Equal(t, "1048576", ds.Properties["recordsize"].Value)
+	assert.Equal(t, "local", ds.Properties["recordsize"].Source)
+
+	err = m.SetDatasetProperty(ctx, "tank/data", "compress", "zstd")
+	require.NoError(t, err)
+	ds, err = m.GetDataset(ctx, "tank/data", "compress")
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", ds.Properties["compression"].Value)
+
+	err = m.SetDatasetProperty(ctx, "tank/data", "used", "1G")
+	assert.EqualError(t, err,
+		"zfs; invalid property: used: property is read-only",
+	)
+
+	err = m.SetDatasetProperty(ctx, "tank/data", "recordsize", "3K")
+	assert.ErrorIs(t, err, zfs.ErrInvalidProperty)
+
+	err = m.SetDatasetProperty(ctx, "tank/missing", "atime", "off")
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+}
+
+func TestManager_ListDatasets(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"tank/a", "tank/a/b", "tank/c"} {
+		require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+			Name: name,
+		}))
+	}
+	_, _, err := run(t, r, "zfs", "snapshot", "-r", "tank/a@snap")
+	require.NoError(t, err)
+
+	datasets, err := m.ListDatasets(ctx, "", 0, zfs.FilesystemType, "used")
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]string{"tank", "tank/a", "tank/a/b", "tank/c"},
+		datasetNames(datasets),
+	)
+
+	datasets, err = m.ListDatasets(ctx, "tank/a", 0, zfs.SnapshotType)
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]string{"tank/a/b@snap", "tank/a@snap"},
+		datasetNames(datasets),
+	)
+
+	names, err := m.ListDatasetNames(ctx, "tank", 1, zfs.AllTypes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tank", "tank/a", "tank/c"}, names)
+
+	stdout, _, err := run(t, r, "zfs", "list", "-H", "-o", "name,used",
+		"-t", "all", "-r", "tank/a",
+	)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"tank/a\t192K\ntank/a@snap\t0B\ntank/a/b\t96K\ntank/a/b@snap\t0B\n",
+		stdout,
+	)
+
+	datasets, err = m.ListDatasetsWithOptions(ctx, &zfs.ListDatasetsOptions{
+		Names:      []string{"tank/a"},
+		Properties: []string{"used"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tank/a"}, datasetNames(datasets))
+
+	_, _, err = run(t, r, "zfs", "snapshot", "tank/a@later")
+	require.NoError(t, err)
+	datasets, err = m.ListDatasetsWithOptions(ctx, zfs.SnapshotsOf("tank/a"))
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	assert.Equal(t, "tank/a@snap", datasets[0].Name)
+	assert.Equal(t, "tank/a@later", datasets[1].Name)
+
+	assert.Contains(t, datasets[1].Properties, "createtxg")
+	assert.Empty(t, datasets[1].Properties["createtxg"].Source)
+
+	fast, err := m.ListDatasetsWithOptions(ctx,
+		zfs.SnapshotsOf("tank/a", "used"),
+	)
+	require.NoError(t, err)
+	require.Len(t, fast, 2)
+	assert.Equal(t, "tank/a@snap", fast[0].Name)
+	assert.Equal(t, "tank/a@later", fast[1].Name)
+	assert.Equal(t, "0", fast[1].Properties["used"].Value)
+	assert.Empty(t, fast[1].Properties["used"].Source)
+
+	fast, err = m.ListDatasetsWithOptions(ctx, &zfs.ListDatasetsOptions{
+		Names:      []string{"tank"},
+		Recursive:  true,
+		Properties: []string{"used"},
+		NoSources:  true,
+	})
+	require.NoError(t, err)
+	all, err := m.ListDatasets(ctx, "tank", 0, zfs.AllTypes, "used")
+	require.NoError(t, err)
+	assert.Equal(t, datasetNames(all), datasetNames(fast))
+
+	_, err = m.ListDatasets(ctx, "tank/missing", 0, zfs.AllTypes)
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+}
+
+func TestManager_WalkDatasets(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"tank/a", "tank/a/b", "tank/c"} {
+		require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+			Name: name,
+		}))
+	}
+	_, _, err := run(t, r, "zfs", "snapshot", "-r", "tank/a@snap")
+	require.NoError(t, err)
+
+	want, err := m.ListDatasets(ctx, "tank", 0, zfs.AllTypes, "used")
+	require.NoError(t, err)
+
+	for _, noSources := range []bool{false, true} {
+		got := []*zfs.Dataset{}
+		err = m.WalkDatasets(ctx, &zfs.ListDatasetsOptions{
+			Names:      []string{"tank"},
+			Recursive:  true,
+			Properties: []string{"used"},
+			NoSources:  noSources,
+		}, func(ds *zfs.Dataset) error {
+			got = append(got, ds)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, got, len(want))
+		for i, ds := range got {
+			assert.Equal(t, want[i].Name, ds.Name)
+			assert.Equal(t,
+				want[i].Properties["used"].Value,
+				ds.Properties["used"].Value,
+			)
+		}
+	}
+
+	names := []string{}
+	err = m.WalkDatasets(ctx, &zfs.ListDatasetsOptions{
+		Properties: []string{"used"},
+		Sort:       []zfs.SortKey{{Property: "name", Descending: true}},
+		NoSources:  true,
+	}, func(ds *zfs.Dataset) error {
+		names = append(names, ds.Name)
+		if len(names) == 2 {
+			return zfs.ErrStopWalk
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tank/c", "tank/a@snap"}, names)
+
+	err = m.WalkDatasets(ctx, &zfs.ListDatasetsOptions{
+		Names: []string{"tank/missing"},
+	}, func(ds *zfs.Dataset) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+}
+
+func TestManager_WalkDatasets_nested(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	for i := 0; i < 300; i++ {
+		require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+			Name: fmt.Sprintf("tank/dataset-%03d", i),
+		}))
+	}
+
+	done := make(chan error, 1)
+	count := 0
+	go func() {
+		done <- m.WalkDatasets(ctx, &zfs.ListDatasetsOptions{
+			Properties: []string{"used", "mountpoint"},
+		}, func(ds *zfs.Dataset) error {
+			count++
+			_, err := m.GetDatasetProperty(ctx, ds.Name, "mountpoint")
+
+			return err
+		})
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+		assert.Equal(t, 301, count)
+	case <-time.After(10 * time.Second):
+		t.Fatal("walk with nested manager calls did not finish")
+	}
+}
+
+func TestManager_sortedOrder(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	quotas := map[string]string{
+		"tank/a": "2G", "tank/b": "1G", "tank/c": "2G", "tank/d": "",
+	}
+	for _, name := range []string{"tank/d", "tank/c", "tank/b", "tank/a"} {
+		props := map[string]string{}
+		if quotas[name] != "" {
+			props["quota"] = quotas[name]
+			props["acme:rank"] = quotas[name]
+		}
+		require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+			Name:       name,
+			Properties: props,
+		}))
+	}
+
+	ordered := func(datasets []*zfs.Dataset) []string {
+		names := make([]string, 0, len(datasets))
+		for _, ds := range datasets {
+			names = append(names, ds.Name)
+		}
+
+		return names
+	}
+
+	for _, keys := range [][]zfs.SortKey{
+		{{Property: "quota", Descending: true}},
+		{{Property: "quota"}, {Property: "name", Descending: true}},
+		{{Property: "acme:rank"}},
+		{{Property: "acme:rank", Descending: true}},
+	} {
+		options := &zfs.ListDatasetsOptions{
+			Names:      []string{"tank"},
+			Recursive:  true,
+			Properties: []string{"used"},
+			Sort:       keys,
+			NoSources:  true,
+		}
+		list, err := m.ListDatasetsWithOptions(ctx, options)
+		require.NoError(t, err)
+
+		walked := []*zfs.Dataset{}
+		err = m.WalkDatasets(ctx, options, func(ds *zfs.Dataset) error {
+			walked = append(walked, ds)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Len(t, list, 5)
+		assert.Equal(t, ordered(list), ordered(walked), keys)
+	}
+}
+
+func TestManager_GetDatasetTree(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"tank/a", "tank/a/b", "tank/c"} {
+		require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+			Name: name,
+		}))
+	}
+	_, _, err := run(t, r, "zfs", "snapshot", "-r", "tank/a@snap")
+	require.NoError(t, err)
+
+	tree, err := m.GetDatasetTree(ctx, "tank", &zfs.DatasetTreeOptions{
+		Snapshots:  true,
+		Properties: []string{"usedbysnapshots"},
+		NoSources:  true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "tank", tree.Name)
+	assert.Empty(t, tree.Snapshots)
+	b := tree.Find("tank/a/b")
+	require.NotNil(t, b)
+	assert.Equal(t, "tank/a", b.Parent.Name)
+	assert.Equal(t, []string{"tank/a/b@snap"}, datasetNames(b.Snapshots))
+	assert.Equal(t, 2, tree.SnapshotCount())
+	_, ok := tree.SnapshotUsed()
+	assert.True(t, ok)
+
+	_, err = m.GetDatasetTree(ctx, "tank/missing", nil)
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+}
+
+func TestManager_DestroyDataset(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name:          "tank/a/b",
+		CreateParents: true,
+	}))
+
+	err := m.DestroyDataset(ctx, "tank/a")
+	assert.ErrorIs(t, err, zfs.ErrHasChildren)
+
+	err = m.DestroyDataset(ctx, "tank")
+	assert.Error(t, err)
+
+	require.NoError(t, m.DestroyDataset(ctx, "tank/a", zfs.DestroyRecursive))
+	names, err := m.ListDatasetNames(ctx, "", 0, zfs.AllTypes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tank"}, names)
+
+	_, _, err = run(t, r, "zfs", "snapshot", "tank@one", "tank@two")
+	require.NoError(t, err)
+	require.NoError(t, m.DestroyDataset(ctx, "tank@one"))
+
+	err = m.DestroyDataset(ctx, "tank@one")
+	assert.Error(t, err)
+}
+
+func TestManager_DestroyDataset_snapshots(t *testing.T) {
+	// Snapshots are created in this order, which ranges follow.
+	created := []string{"a", "b", "c", "e", "d"}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		want      []string
+		wantErr   bool
+	}{
+		{name: "tank@b", want: []string{"a", "c", "d", "e"}},
+		{name: "tank@a,c", want: []string{"b", "d", "e"}},
+		{name: "tank@b%e", want: []string{"a", "d"}},
+		{name: "tank@%b", want: []string{"c", "d", "e"}},
+		{name: "tank@e%", want: []string{"a", "b", "c"}},
+		{name: "tank@%", want: []string{}},
+		{name: "tank@a,c%e", want: []string{"b", "d"}},
+		{name: "tank@a,missing", want: []string{"b", "c", "d", "e"}},
+		{name: "tank@d%e", wantErr: true},
+		{name: "tank@missing%c", wantErr: true},
+		{name: "tank@x,y", wantErr: true},
+		{
+			name:      "tank@b%c",
+			recursive: true,
+			want:      []string{"a", "d", "e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, m := newManager(t)
+			ctx := context.Background()
+
+			require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+				Name: "tank/data",
+			}))
+			for _, snap := range created {
+				_, _, err := run(t, r, "zfs", "snapshot", "-r", "tank@"+snap)
+				require.NoError(t, err)
+			}
+			var flags []zfs.DestroyDatasetFlag
+			if tt.recursive {
+				flags = append(flags, zfs.DestroyRecursive)
+			}
+
+			err := m.DestroyDataset(ctx, tt.name, flags...)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			wantChild := created
+			if tt.recursive {
+				wantChild = tt.want
+			}
+			assert.Equal(t, tt.want, snapshotNames(t, m, "tank"))
+			assert.ElementsMatch(t,
+				wantChild, snapshotNames(t, m, "tank/data"),
+			)
+		})
+	}
+}
+
+// snapshotNames returns the sorted names of the snapshots of the named
+// dataset, without the dataset name.
+func snapshotNames(t *testing.T, m *zfs.Manager, name string) []string {
+	t.Helper()
+
+	datasets, err := m.ListDatasetNames(
+		context.Background(), name, 1, zfs.SnapshotType,
+	)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, ds := range datasets {
+		names = append(names, strings.TrimPrefix(ds, name+"@"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestManager_mounts(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name:       "tank/data",
+		Properties: map[string]string{"mountpoint": "/srv/my data"},
+	}))
+
+	mounts, err := m.ListMounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.Mount{
+		{Dataset: "tank", Mountpoint: "/tank"},
+		{Dataset: "tank/data", Mountpoint: "/srv/my data"},
+	}, mounts)
+
+	require.NoError(t, m.UnmountDataset(ctx, &zfs.UnmountDatasetOptions{
+		Name: "/srv/my data",
+	}))
+	err = m.UnmountDataset(ctx, &zfs.UnmountDatasetOptions{
+		Name: "tank/data",
+	})
+	assert.Error(t, err)
+
+	err = m.ShareDataset(ctx, "tank/data")
+	assert.Error(t, err)
+
+	require.NoError(t, m.MountDataset(ctx, &zfs.MountDatasetOptions{
+		Name: "tank/data",
+	}))
+	require.NoError(t, m.ShareDataset(ctx, "tank/data"))
+	require.NoError(t, m.UnshareDataset(ctx, "tank/data"))
+
+	require.NoError(t, m.UnmountDataset(ctx, &zfs.UnmountDatasetOptions{
+		All: true,
+	}))
+	mounts, err = m.ListMounts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, mounts)
+}
+
+func TestManager_keys(t *testing.T) {
+	_, m := newManager(t)
+	ctx := context.Background()
+
+	err := m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/secret",
+		Properties: map[string]string{
+			"encryption": "on",
+			"keyformat":  "passphrase",
+		},
+		Key: strings.NewReader("hunter2"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Passphrase too short (min 8).")
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/secret",
+		Properties: map[string]string{
+			"encryption": "on",
+			"keyformat":  "passphrase",
+		},
+		Key: strings.NewReader("correct horse"),
+	}))
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/secret/child",
+	}))
+
+	props, err := m.GetDataset(ctx, "tank/secret/child",
+		"encryption", "encryptionroot", "keystatus",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "aes-256-gcm", props.Properties["encryption"].Value)
+	assert.Equal(t, "tank/secret", props.Properties["encryptionroot"].Value)
+	assert.Equal(t, "available", props.Properties["keystatus"].Value)
+
+	err = m.UnloadKey(ctx, &zfs.UnloadKeyOptions{Name: "tank/secret"})
+	assert.EqualError(t, err, "zfs; exit status 255: "+
+		"Key unload error: 'tank/secret' is busy.",
+	)
+
+	require.NoError(t, m.UnmountDataset(ctx, &zfs.UnmountDatasetOptions{
+		Name:      "tank/secret",
+		UnloadKey: true,
+	}))
+	v, err := m.GetDatasetProperty(ctx, "tank/secret", "keystatus")
+	require.NoError(t, err)
+	assert.Equal(t, "unavailable", v)
+
+	err = m.MountDataset(ctx, &zfs.MountDatasetOptions{Name: "tank/secret"})
+	assert.ErrorIs(t, err, zfs.ErrKeyNotLoaded)
+
+	err = m.LoadKey(ctx, &zfs.LoadKeyOptions{
+		Name: "tank/secret",
+		Key:  strings.NewReader("wrong horse"),
+	})
+	assert.Error(t, err)
+
+	require.NoError(t, m.LoadKey(ctx, &zfs.LoadKeyOptions{
+		Name: "tank/secret",
+		Key:  strings.NewReader("correct horse"),
+	}))
+
+	require.NoError(t, m.ChangeKey(ctx, &zfs.ChangeKeyOptions{
+		Name: "tank/secret/child",
+		Properties: map[string]string{
+			"keyformat": "passphrase",
+		},
+		Key: strings.NewReader("another secret"),
+	}))
+	v, err = m.GetDatasetProperty(ctx, "tank/secret/child", "encryptionroot")
+	require.NoError(t, err)
+	assert.Equal(t, "tank/secret/child", v)
+
+	require.NoError(t, m.ChangeKey(ctx, &zfs.ChangeKeyOptions{
+		Name:    "tank/secret/child",
+		Inherit: true,
+	}))
+	v, err = m.GetDatasetProperty(ctx, "tank/secret/child", "encryptionroot")
+	require.NoError(t, err)
+	assert.Equal(t, "tank/secret", v)
+}
+
+func TestManager_space(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/home",
+		Properties: map[string]string{
+			"userquota@1000":    "1G",
+			"userobjquota@1000": "500",
+			"groupquota@100":    "2G",
+			"projectquota@42":   "1M",
+		},
+	}))
+	_, _, err := run(t, r, "zfs", "snapshot", "tank/home@daily")
+	require.NoError(t, err)
+
+	users, err := m.UserSpace(ctx, "tank/home", &zfs.SpaceOptions{
+		ResolveName: func(typ zfs.SpaceType, id uint64) (string, bool) {
+			return "alice", typ == zfs.SpacePOSIXUser && id == 1000
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.SpaceEntry{
+		{Type: zfs.SpacePOSIXUser, ID: 0, Used: 98304, ObjUsed: 1},
+		{
+			Type:     zfs.SpacePOSIXUser,
+			Name:     "alice",
+			ID:       1000,
+			Quota:    1073741824,
+			ObjQuota: 500,
+		},
+	}, users)
+
+	groups, err := m.GroupSpace(ctx, "tank/home", &zfs.SpaceOptions{
+		Types: []zfs.SpaceType{zfs.SpacePOSIXGroup},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.SpaceEntry{
+		{Type: zfs.SpacePOSIXGroup, ID: 0, Used: 98304, ObjUsed: 1},
+		{Type: zfs.SpacePOSIXGroup, ID: 100, Quota: 2147483648},
+	}, groups)
+
+	projects, err := m.ProjectSpace(ctx, "tank/home", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.SpaceEntry{
+		{Type: zfs.SpaceProject, ID: 0, Used: 98304, ObjUsed: 1},
+		{Type: zfs.SpaceProject, ID: 42, Quota: 1048576},
+	}, projects)
+
+	users, err = m.UserSpace(ctx, "tank/home@daily", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.SpaceEntry{
+		{Type: zfs.SpacePOSIXUser, ID: 0, Used: 98304, ObjUsed: 1},
+	}, users)
+
+	_, err = m.UserSpace(ctx, "tank/missing", nil)
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name:       "tank/vol",
+		VolumeSize: "1M",
+	}))
+	_, err = m.GroupSpace(ctx, "tank/vol", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,462 @@
+// Package zfstest provides an in-memory fake of the zfs and zpool commands,
+// allowing code built on top of zfs.Manager to be unit tested without root
+// access, or the ZFS kernel module.
+//
+// Runner implements runner.Runner, and simulates pools, filesystems, volumes,
+// snapshots, properties including inheritance, mounts, and encryption keys.
+// Commands produce tab-delimited -H and parsable -p output, and stderr error
+// messages modeled on those of OpenZFS 2.x. Failed commands return an
+// *ExitError with a non-zero exit code.
+//
+// To use it, assign a Runner to a zfs.Manager:
+//
+//	r := zfstest.New()
+//	m := &zfs.Manager{Runner: r}
+//
+//	err := m.CreatePool(ctx, &zfs.CreatePoolOptions{
+//	    Name:  "tank",
+//	    Vdevs: []string{"/dev/sdb"},
+//	})
+//
+// Only a subset of zfs and zpool subcommands are simulated, covering all
+// operations performed by zfs.Manager, as well as "zfs snapshot", which can be
+// used to seed test data:
+//
+//	err = r.Run(nil, nil, nil, "zfs", "snapshot", "tank@daily")
+//
+// Space accounting is simplified, with each filesystem and volume referencing
+// a fixed amount of space, and snapshots referencing the same amount as their
+// dataset, while not using any additional space. The space a filesystem or
+// snapshot references is accounted to the root user, group, and project, and
+// any identity with a quota set on a filesystem is reported as using none.
+package zfstest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krystal/go-runner"
+)
+
+// DefaultPoolSize is the size in bytes of pools created by a Runner with a
+// zero PoolSize.
+const DefaultPoolSize = 1 << 30
+
+// ExitError is returned by Runner when a simulated command fails. Just like
+// *exec.ExitError, it exposes the exit code of the command via ExitCode.
+type ExitError struct {
+	// Code is the exit code of the failed command.
+	Code int
+}
+
+// Error returns a message in the same format as *exec.ExitError.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code of the failed command.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Call is a record of a command executed by a Runner.
+type Call struct {
+	// Command is the executed command, for example "zfs".
+	Command string
+
+	// Args are the arguments the command was executed with.
+	Args []string
+
+	// ExitCode is the exit code of the simulated command.
+	ExitCode int
+}
+
+// Runner is a runner.Runner which simulates zfs and zpool commands against
+// in-memory state. The zero value is an empty system with no pools, ready for
+// use. It is safe for concurrent use.
+type Runner struct {
+	// Now returns the current time, used for the creation property of new
+	// datasets. Defaults to time.Now.
+	Now func() time.Time
+
+	// PoolSize is the size in bytes of newly created pools. Defaults to
+	// DefaultPoolSize.
+	PoolSize uint64
+
+	mu       sync.Mutex
+	env      []string
+	pools    map[string]*pool
+	exported []*pool
+	calls    []Call
+	txg      uint64
+	ids      uint64
+}
+
+var _ runner.Runner = &Runner{}
+
+// New returns a new Runner with no pools.
+func New() *Runner {
+	return &Runner{}
+}
+
+// Run simulates the given command.
+func (r *Runner) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext simulates the given command. If ctx is already done, the command
+// is not run, and the context's error is returned.
+func (r *Runner) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c := &cmd{
+		command: command,
+		args:    args,
+		stdin:   stdin,
+		stdout:  &bytes.Buffer{},
+		stderr:  &bytes.Buffer{},
+	}
+	code := r.record(c)
+
+	// Output is written without holding the lock, as the caller may be
+	// reading it from a pipe while running further commands, like a
+	// zfs.Manager.WalkDatasets callback does.
+	if stdout != nil {
+		_, _ = stdout.Write(c.stdout.Bytes())
+	}
+	if stderr != nil {
+		_, _ = stderr.Write(c.stderr.Bytes())
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+
+	return nil
+}
+
+// record runs c with the lock held, and records the call.
+func (r *Runner) record(c *cmd) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pools == nil {
+		r.pools = map[string]*pool{}
+	}
+
+	call := Call{Command: c.command, Args: append([]string{}, c.args...)}
+	call.ExitCode = r.exec(c)
+	r.calls = append(r.calls, call)
+
+	return call.ExitCode
+}
+
+// Env stores the given environment variables. They have no effect on
+// simulated commands.
+func (r *Runner) Env(env ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.env = env
+}
+
+// Calls returns a record of all commands executed by the Runner, in the order
+// they were executed.
+func (r *Runner) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call{}, r.calls...)
+}
+
+// SetBusy marks the named pool or dataset as busy, or no longer busy. Busy
+// pools cannot be destroyed or exported, and busy datasets cannot be
+// destroyed or unmounted, failing with "pool is busy" and "dataset is busy"
+// errors respectively. Useful for testing retry behavior.
+func (r *Runner) SetBusy(name string, busy bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ds := r.lookup(name)
+	switch {
+	case ds != nil:
+		ds.busy = busy
+	case p != nil && !strings.ContainsAny(name, "/@#"):
+		p.busy = busy
+	default:
+		return fmt.Errorf("zfstest: %s does not exist", name)
+	}
+
+	return nil
+}
+
+// subcommand is a simulated zfs or zpool subcommand.
+type subcommand struct {
+	run   func(r *Runner, c *cmd) error
+	usage string
+}
+
+// cmd holds the input and output of a command being simulated.
+type cmd struct {
+	command string
+	args    []string
+	stdin   io.Reader
+	stdout  *bytes.Buffer
+	stderr  *bytes.Buffer
+
+	key     []byte
+	keyRead bool
+}
+
+// readKey returns all of stdin. It is only read once, subsequent calls return
+// the same data.
+func (c *cmd) readKey() []byte {
+	if !c.keyRead && c.stdin != nil {
+		c.key, _ = io.ReadAll(c.stdin)
+	}
+	c.keyRead = true
+
+	return c.key
+}
+
+func (r *Runner) exec(c *cmd) int {
+	var commands map[string]subcommand
+	switch c.command {
+	case "zfs":
+		commands = zfsCommands
+	case "zpool":
+		commands = zpoolCommands
+	default:
+		fmt.Fprintf(c.stderr, "%s: command not found\n", c.command)
+
+		return 127
+	}
+
+	if len(c.args) == 0 {
+		fmt.Fprintf(c.stderr, "missing command\nusage: %s command args ...\n",
+			c.command,
+		)
+
+		return 2
+	}
+
+	name := c.args[0]
+	sub, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(c.stderr,
+			"unrecognized command '%s'\nusage: %s command args ...\n",
+			name, c.command,
+		)
+
+		return 2
+	}
+
+	c.args = c.args[1:]
+	err := sub.run(r, c)
+	if err == nil {
+		return 0
+	}
+
+	f, ok := err.(*failure) //nolint:errorlint
+	if !ok {
+		f = &failure{code: 1, msg: err.Error()}
+	}
+
+	c.stderr.WriteString(f.msg + "\n")
+	if f.usage {
+		fmt.Fprintf(c.stderr, "usage:\n\t%s\n", sub.usage)
+	}
+
+	return f.code
+}
+
+// failure is an error which causes a simulated command to fail with the given
+// exit code and stderr message.
+type failure struct {
+	code  int
+	msg   string
+	usage bool
+}
+
+func (f *failure) Error() string {
+	return f.msg
+}
+
+// failf returns a failure with exit code 1.
+func failf(format string, a ...interface{}) error {
+	return &failure{code: 1, msg: fmt.Sprintf(format, a...)}
+}
+
+// usagef returns a failure with exit code 2, which includes usage information
+// of the subcommand.
+func usagef(format string, a ...interface{}) error {
+	return &failure{code: 2, msg: fmt.Sprintf(format, a...), usage: true}
+}
+
+// keyErrorf returns a failure with exit code 255, as used by key related
+// subcommands.
+func keyErrorf(format string, a ...interface{}) error {
+	return &failure{code: 255, msg: fmt.Sprintf(format, a...)}
+}
+
+// flags holds parsed command line flags, where the values of each flag are
+// stored in the order given.
+type flags map[string][]string
+
+func (f flags) has(name string) bool {
+	_, ok := f[name]
+
+	return ok
+}
+
+// value returns the last value of the named flag, or an empty string.
+func (f flags) value(name string) string {
+	if v := f[name]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+
+	return ""
+}
+
+// getopt parses POSIX style single letter flags in args as per spec, where a
+// letter followed by ':' takes a value. Long flags without values are accepted
+// if listed in long. Parsing stops at the first non-flag argument, or at "--",
+// returning the remaining arguments.
+func getopt(
+	args []string,
+	spec string,
+	long ...string,
+) (flags, []string, error) {
+	f := flags{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return f, args[i+1:], nil
+		case strings.HasPrefix(arg, "--"):
+			if !contains(long, arg[2:]) {
+				return nil, nil, usagef("invalid option '%s'", arg[2:])
+			}
+			f[arg[2:]] = append(f[arg[2:]], "")
+
+			continue
+		case len(arg) < 2 || arg[0] != '-':
+			return f, args[i:], nil
+		}
+
+		for j := 1; j < len(arg); j++ {
+			c := arg[j]
+			k := strings.IndexByte(spec, c)
+			if c == ':' || k == -1 {
+				return nil, nil, usagef("invalid option '%c'", c)
+			}
+
+			name := string(c)
+			if k+1 >= len(spec) || spec[k+1] != ':' {
+				f[name] = append(f[name], "")
+
+				continue
+			}
+
+			v := arg[j+1:]
+			if v == "" {
+				if i+1 >= len(args) {
+					return nil, nil, usagef(
+						"missing argument for '%c' option", c,
+					)
+				}
+				i++
+				v = args[i]
+			}
+			f[name] = append(f[name], v)
+
+			break
+		}
+	}
+
+	return f, nil, nil
+}
+
+// splitProp splits a "property=value" argument.
+func splitProp(arg string) (string, string, bool) {
+	i := strings.IndexByte(arg, '=')
+	if i == -1 {
+		return "", "", false
+	}
+
+	return arg[:i], arg[i+1:], true
+}
+
+// writeTable writes rows of columns. Scripted tables are tab-delimited without
+// a header, as per the -H flag, otherwise columns are space padded, and
+// preceded by a header of upper-cased column names.
+func writeTable(w io.Writer, scripted bool, header []string, rows [][]string) {
+	if scripted {
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+
+		return
+	}
+
+	all := append([][]string{upper(header)}, rows...)
+	widths := make([]int, len(header))
+	for _, row := range all {
+		for i, col := range row {
+			if len(col) > widths[i] {
+				widths[i] = len(col)
+			}
+		}
+	}
+
+	for _, row := range all {
+		cols := make([]string, len(row))
+		for i, col := range row {
+			if i < len(row)-1 {
+				col += strings.Repeat(" ", widths[i]-len(col))
+			}
+			cols[i] = col
+		}
+		fmt.Fprintln(w, strings.Join(cols, "  "))
+	}
+}
+
+func upper(s []string) []string {
+	r := make([]string, len(s))
+	for i, v := range s {
+		r[i] = strings.ToUpper(v)
+	}
+
+	return r
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
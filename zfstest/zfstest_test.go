@@ -0,0 +1,318 @@
+package zfstest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krystal/go-zfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newManager returns a Runner with a pool named "tank", and a zfs.Manager
+// using it.
+func newManager(t *testing.T) (*Runner, *zfs.Manager) {
+	t.Helper()
+
+	r := New()
+	r.Now = func() time.Time {
+		return time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	}
+	m := &zfs.Manager{Runner: r}
+
+	err := m.CreatePool(context.Background(), &zfs.CreatePoolOptions{
+		Name:  "tank",
+		Vdevs: []string{"/dev/sdb"},
+	})
+	require.NoError(t, err)
+
+	return r, m
+}
+
+// run runs the given command with r, returning stdout and stderr.
+func run(
+	t *testing.T,
+	r *Runner,
+	command string,
+	args ...string,
+) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := r.Run(nil, &stdout, &stderr, command, args...)
+
+	return stdout.String(), stderr.String(), err
+}
+
+func TestRunner_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		args       []string
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{
+			name:       "unknown command",
+			command:    "zdb",
+			wantStderr: "zdb: command not found\n",
+			wantCode:   127,
+		},
+		{
+			name:       "missing subcommand",
+			command:    "zfs",
+			wantStderr: "missing command\nusage: zfs command args ...\n",
+			wantCode:   2,
+		},
+		{
+			name:    "unknown subcommand",
+			command: "zpool",
+			args:    []string{"scrub", "tank"},
+			wantStderr: "unrecognized command 'scrub'\n" +
+				"usage: zpool command args ...\n",
+			wantCode: 2,
+		},
+		{
+			name:    "invalid option",
+			command: "zpool",
+			args:    []string{"destroy", "-x", "tank"},
+			wantStderr: "invalid option 'x'\n" +
+				"usage:\n\tdestroy [-f] <pool>\n",
+			wantCode: 2,
+		},
+		{
+			name:       "failure",
+			command:    "zfs",
+			args:       []string{"get", "all", "tank/missing"},
+			wantStderr: "cannot open 'tank/missing': dataset does not exist\n",
+			wantCode:   1,
+		},
+		{
+			name:       "success",
+			command:    "zpool",
+			args:       []string{"list", "-H", "-o", "name,health"},
+			wantStdout: "tank\tONLINE\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newManager(t)
+
+			stdout, stderr, err := run(t, r, tt.command, tt.args...)
+
+			assert.Equal(t, tt.wantStdout, stdout)
+			assert.Equal(t, tt.wantStderr, stderr)
+			if tt.wantCode == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var exitErr *ExitError
+			require.True(t, errors.As(err, &exitErr))
+			assert.Equal(t, tt.wantCode, exitErr.ExitCode())
+			assert.Equal(t, tt.wantCode, exitErr.Code)
+
+			calls := r.Calls()
+			assert.Equal(t, tt.wantCode, calls[len(calls)-1].ExitCode)
+		})
+	}
+}
+
+func TestRunner_RunContext_canceled(t *testing.T) {
+	r := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := r.RunContext(ctx, nil, nil, nil, "zpool", "list")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, r.Calls())
+}
+
+func TestRunner_Calls(t *testing.T) {
+	r, _ := newManager(t)
+
+	_, _, err := run(t, r, "zfs", "snapshot", "tank@now")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Call{
+		{
+			Command: "zpool",
+			Args:    []string{"create", "tank", "/dev/sdb"},
+		},
+		{
+			Command: "zfs",
+			Args:    []string{"snapshot", "tank@now"},
+		},
+	}, r.Calls())
+}
+
+func TestRunner_SetBusy(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	err := m.CreateDataset(ctx, &zfs.CreateDatasetOptions{Name: "tank/data"})
+	require.NoError(t, err)
+
+	require.NoError(t, r.SetBusy("tank/data", true))
+	err = m.DestroyDataset(ctx, "tank/data")
+	assert.ErrorIs(t, err, zfs.ErrBusy)
+
+	require.NoError(t, r.SetBusy("tank", true))
+	err = m.ExportPool(ctx, "tank", false)
+	assert.ErrorIs(t, err, zfs.ErrBusy)
+
+	require.NoError(t, r.SetBusy("tank/data", false))
+	require.NoError(t, r.SetBusy("tank", false))
+	assert.NoError(t, m.DestroyDataset(ctx, "tank/data"))
+	assert.NoError(t, m.ExportPool(ctx, "tank", false))
+
+	err = r.SetBusy("tank/missing", true)
+	assert.EqualError(t, err, "zfstest: tank/missing does not exist")
+}
+
+func TestRunner_retry(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, r.SetBusy("tank", true))
+	m.RetryPolicy = &zfs.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     1,
+		RetryOn:        []error{zfs.ErrBusy},
+	}
+
+	err := m.ExportPool(ctx, "tank", false)
+
+	assert.ErrorIs(t, err, zfs.ErrBusy)
+	calls := r.Calls()
+	require.Len(t, calls, 4)
+	for _, c := range calls[1:] {
+		assert.Equal(t, []string{"export", "tank"}, c.Args)
+		assert.Equal(t, 1, c.ExitCode)
+	}
+}
+
+func Test_getopt(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		spec     string
+		long     []string
+		want     flags
+		wantArgs []string
+		wantErr  string
+	}{
+		{
+			name:     "no flags",
+			args:     []string{"tank"},
+			spec:     "rH",
+			want:     flags{},
+			wantArgs: []string{"tank"},
+		},
+		{
+			name:     "combined flags",
+			args:     []string{"-rHp", "tank"},
+			spec:     "rHp",
+			want:     flags{"r": {""}, "H": {""}, "p": {""}},
+			wantArgs: []string{"tank"},
+		},
+		{
+			name:     "value in next argument",
+			args:     []string{"-Ho", "name,used", "tank"},
+			spec:     "Ho:",
+			want:     flags{"H": {""}, "o": {"name,used"}},
+			wantArgs: []string{"tank"},
+		},
+		{
+			name:     "attached value",
+			args:     []string{"-d1", "tank"},
+			spec:     "d:",
+			want:     flags{"d": {"1"}},
+			wantArgs: []string{"tank"},
+		},
+		{
+			name: "repeated flag",
+			args: []string{"-o", "a=1", "-o", "b=2"},
+			spec: "o:",
+			want: flags{"o": {"a=1", "b=2"}},
+		},
+		{
+			name:     "double dash",
+			args:     []string{"-r", "--", "-tank"},
+			spec:     "r",
+			want:     flags{"r": {""}},
+			wantArgs: []string{"-tank"},
+		},
+		{
+			name:     "long flag",
+			args:     []string{"--rewind-to-checkpoint", "tank"},
+			long:     []string{"rewind-to-checkpoint"},
+			want:     flags{"rewind-to-checkpoint": {""}},
+			wantArgs: []string{"tank"},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-x"},
+			spec:    "r",
+			wantErr: "invalid option 'x'",
+		},
+		{
+			name:    "unknown long flag",
+			args:    []string{"--force"},
+			wantErr: "invalid option 'force'",
+		},
+		{
+			name:    "missing value",
+			args:    []string{"-o"},
+			spec:    "o:",
+			wantErr: "missing argument for 'o' option",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := getopt(tt.args, tt.spec, tt.long...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func Test_writeTable(t *testing.T) {
+	header := []string{"name", "used", "mountpoint"}
+	rows := [][]string{
+		{"tank", "192K", "/tank"},
+		{"tank/data", "96K", "/tank/data"},
+	}
+
+	var scripted, padded strings.Builder
+	writeTable(&scripted, true, header, rows)
+	writeTable(&padded, false, header, rows)
+
+	assert.Equal(t,
+		"tank\t192K\t/tank\ntank/data\t96K\t/tank/data\n",
+		scripted.String(),
+	)
+	assert.Equal(t,
+		"NAME       USED  MOUNTPOINT\n"+
+			"tank       192K  /tank\n"+
+			"tank/data  96K   /tank/data\n",
+		padded.String(),
+	)
+}
@@ -0,0 +1,787 @@
+package zfstest
+
+import (
+	"fmt"
+	"strings"
+)
+
+//nolint:lll
+var zpoolCommands = map[string]subcommand{
+	"get": {
+		run:   (*Runner).zpoolGet,
+		usage: `get [-Hp] [-o "all" | field[,...]] <"all" | property[,...]> <pool> ...`,
+	},
+	"set": {
+		run:   (*Runner).zpoolSet,
+		usage: "set <property=value> <pool>",
+	},
+	"create": {
+		run:   (*Runner).zpoolCreate,
+		usage: "create [-fnd] [-o property=value] ... \n\t    [-O file-system-property=value] ... \n\t    [-m mountpoint] [-R root] [-t tempname] <pool> <vdev> ...",
+	},
+	"list": {
+		run:   (*Runner).zpoolList,
+		usage: "list [-Hp] [-o property[,...]] [pool] ...",
+	},
+	"destroy": {
+		run:   (*Runner).zpoolDestroy,
+		usage: "destroy [-f] <pool>",
+	},
+	"export": {
+		run:   (*Runner).zpoolExport,
+		usage: "export [-af] <pool> ...",
+	},
+	"import": {
+		run:   (*Runner).zpoolImport,
+		usage: "import [-d dir] [-D]\n\timport [-o mntopts] [-o property=value] ... \n\t    [-d dir | -c cachefile] [-D] [-l] [-f] [-m] [-N] [-R root] [-F [-n]] -a\n\timport [-o mntopts] [-o property=value] ... \n\t    [-d dir | -c cachefile] [-D] [-l] [-f] [-m] [-N] [-R root] [-F [-n]]\n\t    [--rewind-to-checkpoint] <pool | id> [newpool]",
+	},
+}
+
+// zpoolFeatures are the names of supported pool features, as listed by
+// "zpool get all".
+var zpoolFeatures = []string{
+	"async_destroy", "empty_bpobj", "lz4_compress", "multi_vdev_crash_dump",
+	"spacemap_histogram", "enabled_txg", "hole_birth", "extensible_dataset",
+	"embedded_data", "bookmarks", "filesystem_limits", "large_blocks",
+	"large_dnode", "sha512", "skein", "edonr", "userobj_accounting",
+	"encryption", "project_quota", "device_removal", "obsolete_counts",
+	"zpool_checkpoint", "spacemap_v2", "allocation_classes",
+	"resilver_defer", "bookmark_v2", "redaction_bookmarks",
+	"redacted_datasets", "bookmark_written", "log_spacemap", "livelist",
+	"device_rebuild", "zstd_compress", "draid",
+}
+
+// zpoolListAliases maps abbreviated property names accepted by zpool list to
+// their full names.
+var zpoolListAliases = map[string]string{
+	"alloc":    "allocated",
+	"frag":     "fragmentation",
+	"cap":      "capacity",
+	"expandsz": "expandsize",
+	"dedup":    "dedupratio",
+	"ckpoint":  "checkpoint",
+}
+
+// vdevKeywords are the keywords of a vdev specification which are not
+// devices.
+var vdevKeywords = []string{
+	"mirror", "raidz", "raidz1", "raidz2", "raidz3", "draid", "spare", "log",
+	"cache", "special", "dedup",
+}
+
+// validZpoolProp returns true if name is a known pool property or feature.
+func validZpoolProp(name string) bool {
+	if isFeatureProp(name) {
+		return contains(zpoolFeatures, strings.TrimPrefix(name, "feature@"))
+	}
+	_, ok := zpoolPropsByName[name]
+
+	return ok
+}
+
+// zpoolProp returns the value as displayed with -p, and the source of the
+// named property of the pool.
+func (p *pool) zpoolProp(name string) (string, string) {
+	if isFeatureProp(name) {
+		if v, ok := p.props[name]; ok {
+			return v, "local"
+		}
+
+		return p.features, "local"
+	}
+
+	def := zpoolPropsByName[name]
+	switch {
+	case def == nil:
+		return "-", "-"
+	case def.readonly:
+		return p.zpoolStat(name), "-"
+	}
+
+	if v, ok := p.props[name]; ok {
+		return v, "local"
+	}
+
+	return def.def, "default"
+}
+
+// zpoolStat returns the value of the named read-only property of the pool.
+func (p *pool) zpoolStat(name string) string {
+	alloc := p.used(p.root())
+	switch name {
+	case "size":
+		return u64(p.size)
+	case "allocated":
+		return u64(alloc)
+	case "free":
+		return u64(sub(p.size, alloc))
+	case "capacity":
+		return u64(alloc * 100 / p.size)
+	case "health":
+		return "ONLINE"
+	case "guid":
+		return u64(p.guid)
+	case "load_guid":
+		return u64(p.loadGUID)
+	case "dedupratio":
+		return "1.00"
+	case "freeing", "leaked", "fragmentation":
+		return "0"
+	}
+
+	return "-"
+}
+
+// formatZpoolValue returns value of the named property as displayed with or
+// without the -p flag.
+func formatZpoolValue(name, value string, parsable bool) string {
+	if def, ok := zpoolPropsByName[name]; ok {
+		return def.format(value, parsable)
+	}
+
+	return value
+}
+
+// selectPools returns the named pools, or all pools if no names are given.
+func (r *Runner) selectPools(names []string) ([]*pool, error) {
+	if len(names) == 0 {
+		return r.sortedPools(), nil
+	}
+
+	pools := make([]*pool, 0, len(names))
+	for _, name := range names {
+		p := r.pools[name]
+		if p == nil {
+			return nil, failf("cannot open '%s': no such pool", name)
+		}
+		pools = append(pools, p)
+	}
+
+	return pools, nil
+}
+
+func (r *Runner) zpoolGet(c *cmd) error {
+	f, args, err := getopt(c.args, "Hpo:")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("missing property argument")
+	}
+
+	fields := getFields
+	if f.has("o") && f.value("o") != "all" {
+		fields = strings.Split(f.value("o"), ",")
+		for _, field := range fields {
+			if !contains(getFields, field) {
+				return usagef("invalid field '%s'", field)
+			}
+		}
+	}
+
+	props := strings.Split(args[0], ",")
+	for i, prop := range props {
+		props[i] = zpoolPropName(prop)
+		if prop != "all" && !validZpoolProp(props[i]) {
+			return usagef("bad property list: invalid property '%s'", prop)
+		}
+	}
+	if contains(props, "all") {
+		props = []string{}
+		for _, def := range zpoolProps {
+			props = append(props, def.name)
+		}
+		for _, feature := range zpoolFeatures {
+			props = append(props, "feature@"+feature)
+		}
+	}
+
+	pools, err := r.selectPools(args[1:])
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, p := range pools {
+		for _, prop := range props {
+			value, source := p.zpoolProp(prop)
+			rows = append(rows, getRow(fields, p.name, prop,
+				formatZpoolValue(prop, value, f.has("p")), source,
+			))
+		}
+	}
+
+	writeTable(c.stdout, f.has("H"), fields, rows)
+
+	return nil
+}
+
+func (r *Runner) zpoolSet(c *cmd) error {
+	switch {
+	case len(c.args) == 0:
+		return usagef("missing property=value argument")
+	case len(c.args) == 1:
+		return usagef("missing pool name")
+	}
+
+	name := c.args[len(c.args)-1]
+	p := r.pools[name]
+	if p == nil {
+		return failf("cannot open '%s': no such pool", name)
+	}
+
+	values := map[string]string{}
+	for _, arg := range c.args[:len(c.args)-1] {
+		k, v, ok := splitProp(arg)
+		if !ok {
+			return usagef("missing value in property=value argument")
+		}
+		k = zpoolPropName(k)
+		if !validZpoolProp(k) {
+			return failf("cannot set property for '%s': invalid property "+
+				"'%s'", name, k,
+			)
+		}
+
+		v, err := checkZpoolProp(k, v, false)
+		if err != nil {
+			return failf("cannot set property for '%s': %s", name, err)
+		}
+		values[k] = v
+	}
+
+	for k, v := range values {
+		p.props[k] = v
+	}
+
+	return nil
+}
+
+// poolOptions are the parsed options of zpool create.
+type poolOptions struct {
+	name     string
+	vdevs    []string
+	props    map[string]string
+	fsProps  map[string]string
+	features string
+}
+
+func (r *Runner) zpoolCreate(c *cmd) error {
+	f, args, err := getopt(c.args, "fndm:R:o:O:t:")
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) == 0:
+		return usagef("missing pool name argument")
+	case len(args) == 1:
+		return usagef("missing vdev specification")
+	}
+
+	opts, err := parsePoolOptions(f, args)
+	if err != nil {
+		return err
+	}
+	if err = r.checkVdevs(opts, f.has("f")); err != nil {
+		return err
+	}
+
+	if f.has("n") {
+		fmt.Fprintf(c.stdout,
+			"would create '%s' with the following layout:\n\n\t%s\n",
+			opts.name, opts.name,
+		)
+		for _, vdev := range opts.vdevs {
+			fmt.Fprintf(c.stdout, "\t  %s\n", vdev)
+		}
+
+		return nil
+	}
+
+	p := &pool{
+		name:     opts.name,
+		guid:     r.nextID(),
+		loadGUID: r.nextID(),
+		size:     r.poolSize(),
+		vdevs:    opts.vdevs,
+		props:    opts.props,
+		datasets: map[string]*dataset{},
+		features: opts.features,
+	}
+	if f.has("t") {
+		p.exportName = opts.name
+		p.name = f.value("t")
+	}
+
+	root := r.newDataset(p.name, typeFilesystem, opts.fsProps)
+	if err = r.setupEncryption(c, p, root); err != nil {
+		return failf("cannot create '%s': %s", opts.name, err)
+	}
+
+	p.datasets[root.name] = root
+	root.mounted = p.canMount(root)
+	r.pools[p.name] = p
+
+	return nil
+}
+
+// parsePoolOptions validates the flags and arguments of zpool create.
+func parsePoolOptions(f flags, args []string) (*poolOptions, error) {
+	name := args[0]
+	if msg := checkPoolName(name); msg != "" {
+		return nil, failf("cannot create '%s': %s", name, msg)
+	}
+
+	opts := &poolOptions{
+		name:     name,
+		vdevs:    args[1:],
+		props:    map[string]string{},
+		fsProps:  map[string]string{},
+		features: "enabled",
+	}
+	if f.has("d") {
+		opts.features = "disabled"
+	}
+
+	for _, o := range f["o"] {
+		k, v, ok := splitProp(o)
+		if !ok {
+			return nil, usagef("missing '=' for -o option")
+		}
+		k = zpoolPropName(k)
+		if !validZpoolProp(k) {
+			return nil, failf("property '%s' is not a valid pool property",
+				k,
+			)
+		}
+
+		v, err := checkZpoolProp(k, v, true)
+		if err != nil {
+			return nil, failf("cannot create '%s': %s", name, err)
+		}
+		opts.props[k] = v
+	}
+	if f.has("R") {
+		opts.props["altroot"] = f.value("R")
+		if _, ok := opts.props["cachefile"]; !ok {
+			opts.props["cachefile"] = "none"
+		}
+	}
+
+	fsArgs := f["O"]
+	if f.has("m") {
+		fsArgs = append(fsArgs, "mountpoint="+f.value("m"))
+	}
+	for _, o := range fsArgs {
+		k, v, ok := splitProp(o)
+		if !ok {
+			return nil, usagef("missing '=' for -O option")
+		}
+		k = zfsPropName(k)
+
+		v, err := checkZFSProp(typeFilesystem, k, v, true)
+		if err != nil {
+			return nil, failf("cannot create '%s': %s", name, err)
+		}
+		opts.fsProps[k] = v
+	}
+
+	return opts, nil
+}
+
+// checkPoolName returns a message describing why name is not a valid pool
+// name, or an empty string if it is valid.
+func checkPoolName(name string) string {
+	if msg := checkName(name); msg != "" {
+		return msg
+	}
+
+	switch {
+	case strings.Contains(name, "/"):
+		return "invalid character '/' in pool name"
+	case !(name[0] >= 'a' && name[0] <= 'z') &&
+		!(name[0] >= 'A' && name[0] <= 'Z'):
+		return "name must begin with a letter"
+	case contains(vdevKeywords, name) || strings.HasPrefix(name, "c") &&
+		len(name) > 1 && name[1] >= '0' && name[1] <= '9':
+		return "name is reserved"
+	}
+
+	return ""
+}
+
+// checkVdevs verifies that none of the devices of a new pool are in use by
+// another pool. Devices of exported pools can be reused with force, in which
+// case the exported pool is discarded.
+func (r *Runner) checkVdevs(opts *poolOptions, force bool) error {
+	if _, ok := r.pools[opts.name]; ok {
+		return failf("cannot create '%s': pool already exists", opts.name)
+	}
+
+	for _, vdev := range opts.vdevs {
+		if contains(vdevKeywords, vdev) {
+			continue
+		}
+
+		for _, p := range r.sortedPools() {
+			if contains(p.vdevs, vdev) {
+				return failf("invalid vdev specification\n"+
+					"the following errors must be manually repaired:\n"+
+					"%s is part of active pool '%s'", vdev, p.name,
+				)
+			}
+		}
+
+		for _, p := range r.exported {
+			if contains(p.vdevs, vdev) && !force {
+				return failf("invalid vdev specification\n"+
+					"use '-f' to override the following errors:\n"+
+					"%s is part of exported pool '%s'", vdev, p.name,
+				)
+			}
+		}
+	}
+
+	exported := r.exported[:0]
+	for _, p := range r.exported {
+		inUse := false
+		for _, vdev := range opts.vdevs {
+			inUse = inUse || contains(p.vdevs, vdev)
+		}
+		if !inUse {
+			exported = append(exported, p)
+		}
+	}
+	r.exported = exported
+
+	return nil
+}
+
+var zpoolListColumns = []string{
+	"name", "size", "alloc", "free", "ckpoint", "expandsz", "frag", "cap",
+	"dedup", "health", "altroot",
+}
+
+func (r *Runner) zpoolList(c *cmd) error {
+	f, args, err := getopt(c.args, "Hpo:")
+	if err != nil {
+		return err
+	}
+
+	columns := zpoolListColumns
+	if f.has("o") {
+		columns = strings.Split(f.value("o"), ",")
+	}
+	props := make([]string, len(columns))
+	for i, col := range columns {
+		props[i] = col
+		if alias, ok := zpoolListAliases[col]; ok {
+			props[i] = alias
+		}
+		if props[i] != "name" && !validZpoolProp(props[i]) {
+			return usagef("bad property list: invalid property '%s'", col)
+		}
+	}
+
+	pools, err := r.selectPools(args)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(pools))
+	for _, p := range pools {
+		row := make([]string, len(props))
+		for i, prop := range props {
+			if prop == "name" {
+				row[i] = p.name
+
+				continue
+			}
+			value, _ := p.zpoolProp(prop)
+			row[i] = formatZpoolValue(prop, value, f.has("p"))
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 && !f.has("H") {
+		c.stdout.WriteString("no pools available\n")
+
+		return nil
+	}
+
+	writeTable(c.stdout, f.has("H"), columns, rows)
+
+	return nil
+}
+
+// checkBusy returns an error if the pool, or any of its mounted filesystems,
+// is busy, and force is false. The op argument is used in the error message.
+func (p *pool) checkBusy(op string, force bool) error {
+	if force {
+		return nil
+	}
+	if p.busy {
+		return failf("cannot %s '%s': pool is busy", op, p.name)
+	}
+
+	for _, ds := range p.sortedDatasets() {
+		if ds.mounted && ds.busy {
+			return failf("cannot unmount '%s': pool or dataset is busy",
+				p.zfsValue(ds, "mountpoint"),
+			)
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) zpoolDestroy(c *cmd) error {
+	f, args, err := getopt(c.args, "f")
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) == 0:
+		return usagef("missing pool argument")
+	case len(args) > 1:
+		return usagef("too many arguments")
+	}
+
+	p := r.pools[args[0]]
+	if p == nil {
+		return failf("cannot open '%s': no such pool", args[0])
+	}
+	if err = p.checkBusy("destroy", f.has("f")); err != nil {
+		return err
+	}
+
+	delete(r.pools, p.name)
+
+	return nil
+}
+
+func (r *Runner) zpoolExport(c *cmd) error {
+	f, args, err := getopt(c.args, "af")
+	if err != nil {
+		return err
+	}
+	switch {
+	case f.has("a") && len(args) > 0:
+		return usagef("too many arguments")
+	case !f.has("a") && len(args) == 0:
+		return usagef("missing pool argument")
+	}
+
+	pools, err := r.selectPools(args)
+	if err != nil {
+		return err
+	}
+	for _, p := range pools {
+		if err = p.checkBusy("export", f.has("f")); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range pools {
+		delete(r.pools, p.name)
+		p.export()
+		r.exported = append(r.exported, p)
+	}
+
+	return nil
+}
+
+// export unmounts all filesystems and unloads all keys of the pool, and
+// resets properties which only apply while the pool is imported.
+func (p *pool) export() {
+	for _, ds := range p.datasets {
+		ds.mounted = false
+		ds.shared = false
+		if ds.encRoot {
+			ds.keyLoaded = false
+		}
+	}
+
+	if _, ok := p.props["altroot"]; ok {
+		delete(p.props, "altroot")
+		delete(p.props, "cachefile")
+	}
+	delete(p.props, "readonly")
+
+	if p.exportName != "" {
+		p.rename(p.exportName)
+		p.exportName = ""
+	}
+}
+
+func (r *Runner) zpoolImport(c *cmd) error {
+	f, args, err := getopt(
+		c.args, "aDfmNnlsFXtd:o:R:c:", "rewind-to-checkpoint",
+	)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case f.has("a") && len(args) > 0, len(args) > 2:
+		return usagef("too many arguments")
+	case f.has("t") && len(args) < 2:
+		return usagef("missing new pool name for temporary import")
+	case f.has("n") && !f.has("F"):
+		return usagef("-n or -X only meaningful with -F")
+	case len(args) == 0 && !f.has("a"):
+		return r.writeImportable(c)
+	}
+
+	var pools []*pool
+	if f.has("a") {
+		pools = append(pools, r.exported...)
+	} else {
+		p, err := r.findExported(args[0])
+		if err != nil {
+			return err
+		}
+		pools = append(pools, p)
+	}
+
+	if f.has("n") {
+		return nil
+	}
+
+	var firstErr error
+	for _, p := range pools {
+		name := p.name
+		if len(args) == 2 {
+			name = args[1]
+		}
+
+		err := r.importPool(c, f, p, name)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// writeImportable writes the pools available for import, as listed by zpool
+// import without arguments.
+func (r *Runner) writeImportable(c *cmd) error {
+	if len(r.exported) == 0 {
+		return failf("no pools available to import")
+	}
+
+	for i, p := range r.exported {
+		if i > 0 {
+			c.stdout.WriteString("\n")
+		}
+		fmt.Fprintf(c.stdout,
+			"   pool: %s\n     id: %d\n  state: ONLINE\n"+
+				" action: The pool can be imported using its name or "+
+				"numeric identifier.\n config:\n\n\t%s\tONLINE\n",
+			p.name, p.guid, p.name,
+		)
+		for _, vdev := range p.vdevs {
+			if !contains(vdevKeywords, vdev) {
+				fmt.Fprintf(c.stdout, "\t  %s\tONLINE\n", vdev)
+			}
+		}
+	}
+
+	return nil
+}
+
+// findExported returns the exported pool with the given name or numeric
+// identifier.
+func (r *Runner) findExported(nameOrGUID string) (*pool, error) {
+	var matches []*pool
+	for _, p := range r.exported {
+		if p.name == nameOrGUID || u64(p.guid) == nameOrGUID {
+			matches = append(matches, p)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, failf("cannot import '%s': no such pool available",
+			nameOrGUID,
+		)
+	case 1:
+		return matches[0], nil
+	}
+
+	return nil, failf("cannot import '%s': more than one matching pool\n"+
+		"import by numeric ID instead", nameOrGUID,
+	)
+}
+
+// importPool imports the exported pool p under the given name.
+func (r *Runner) importPool(c *cmd, f flags, p *pool, name string) error {
+	if _, ok := r.pools[name]; ok {
+		return failf("cannot import '%s': a pool with that name is already "+
+			"created/imported,\nand no additional pools with that name "+
+			"were found", p.name,
+		)
+	}
+
+	props := map[string]string{}
+	for _, o := range f["o"] {
+		k, v, ok := splitProp(o)
+		if !ok {
+			// Mount options, which have no effect.
+			continue
+		}
+		k = zpoolPropName(k)
+		if !validZpoolProp(k) {
+			return failf("property '%s' is not a valid pool property", k)
+		}
+
+		v, err := checkZpoolProp(k, v, true)
+		if err != nil {
+			return failf("cannot import '%s': %s", p.name, err)
+		}
+		props[k] = v
+	}
+	if f.has("R") {
+		props["altroot"] = f.value("R")
+		if _, ok := props["cachefile"]; !ok {
+			props["cachefile"] = "none"
+		}
+	}
+
+	exported := r.exported[:0]
+	for _, e := range r.exported {
+		if e != p {
+			exported = append(exported, e)
+		}
+	}
+	r.exported = exported
+
+	if name != p.name {
+		if f.has("t") {
+			p.exportName = p.name
+		}
+		p.rename(name)
+	}
+	for k, v := range props {
+		p.props[k] = v
+	}
+	p.loadGUID = r.nextID()
+	r.pools[p.name] = p
+
+	var err error
+	if f.has("l") {
+		for _, ds := range p.sortedDatasets() {
+			if ds.encRoot && !ds.keyLoaded {
+				if e := r.loadKey(c, p, ds, "", false); e != nil && err == nil {
+					err = e
+				}
+			}
+		}
+	}
+	if !f.has("N") {
+		p.mountAll()
+	}
+
+	return err
+}
@@ -0,0 +1,296 @@
+package zfstest
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/krystal/go-zfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_CreatePool(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        *zfs.CreatePoolOptions
+		wantPoolProps  map[string]string
+		wantRootProps  map[string]string
+		wantErr        string
+		wantErrTargets []error
+	}{
+		{
+			name: "defaults",
+			options: &zfs.CreatePoolOptions{
+				Name:  "data",
+				Vdevs: []string{"mirror", "/dev/sdc", "/dev/sdd"},
+			},
+			wantPoolProps: map[string]string{
+				"size":                  "1073741824",
+				"health":                "ONLINE",
+				"altroot":               "-",
+				"feature@encryption":    "enabled",
+				"feature@zstd_compress": "enabled",
+			},
+			wantRootProps: map[string]string{
+				"mountpoint": "/data",
+				"mounted":    "yes",
+			},
+		},
+		{
+			name: "with options",
+			options: &zfs.CreatePoolOptions{
+				Name:       "data",
+				Vdevs:      []string{"/dev/sdc"},
+				Properties: map[string]string{"ashift": "12"},
+				FilesystemProperties: map[string]string{
+					"compression": "lz4",
+				},
+				Mountpoint:      "/srv",
+				Root:            "/mnt",
+				DisableFeatures: true,
+			},
+			wantPoolProps: map[string]string{
+				"ashift":             "12",
+				"altroot":            "/mnt",
+				"cachefile":          "none",
+				"feature@encryption": "disabled",
+			},
+			wantRootProps: map[string]string{
+				"mountpoint":  "/mnt/srv",
+				"compression": "lz4",
+			},
+		},
+		{
+			name: "already exists",
+			options: &zfs.CreatePoolOptions{
+				Name:  "tank",
+				Vdevs: []string{"/dev/sdc"},
+			},
+			wantErr: "zpool; already exists; exit status 1: " +
+				"cannot create 'tank': pool already exists",
+			wantErrTargets: []error{zfs.ErrZpool, zfs.ErrExists},
+		},
+		{
+			name: "vdev in use",
+			options: &zfs.CreatePoolOptions{
+				Name:  "data",
+				Vdevs: []string{"/dev/sdb"},
+				Force: true,
+			},
+			wantErr: "zpool; exit status 1: invalid vdev specification: " +
+				"the following errors must be manually repaired:: " +
+				"/dev/sdb is part of active pool 'tank'",
+		},
+		{
+			name: "invalid property",
+			options: &zfs.CreatePoolOptions{
+				Name:       "data",
+				Vdevs:      []string{"/dev/sdc"},
+				Properties: map[string]string{"failmode": "explode"},
+			},
+			wantErrTargets: []error{zfs.ErrZpool, zfs.ErrInvalidProperty},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, m := newManager(t)
+			ctx := context.Background()
+
+			err := m.CreatePool(ctx, tt.options)
+
+			if tt.wantErr != "" || len(tt.wantErrTargets) > 0 {
+				require.Error(t, err)
+				if tt.wantErr != "" {
+					assert.EqualError(t, err, tt.wantErr)
+				}
+				for _, target := range tt.wantErrTargets {
+					assert.ErrorIs(t, err, target)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			pool, err := m.GetPool(ctx, tt.options.Name)
+			require.NoError(t, err)
+			for k, v := range tt.wantPoolProps {
+				assert.Equal(t, v, pool.Properties[k].Value, k)
+			}
+
+			ds, err := m.GetDataset(ctx, tt.options.Name)
+			require.NoError(t, err)
+			for k, v := range tt.wantRootProps {
+				assert.Equal(t, v, ds.Properties[k].Value, k)
+			}
+		})
+	}
+}
+
+func TestManager_CreatePool_dryRun(t *testing.T) {
+	r, m := newManager(t)
+
+	err := m.CreatePool(context.Background(), &zfs.CreatePoolOptions{
+		Name:  "data",
+		Vdevs: []string{"/dev/sdc"},
+		Args:  []string{"-n"},
+	})
+	require.NoError(t, err)
+
+	names, err := m.ListPoolNames(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tank"}, names)
+
+	stdout, _, err := run(t, r, "zpool", "create", "-n", "data", "/dev/sdc")
+	require.NoError(t, err)
+	assert.Equal(t,
+		"would create 'data' with the following layout:\n\n"+
+			"\tdata\n\t  /dev/sdc\n",
+		stdout,
+	)
+}
+
+func TestManager_PoolProperties(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.SetPoolProperties(ctx, "tank", map[string]string{
+		"autotrim": "on",
+		"comment":  "test pool",
+	}))
+
+	v, err := m.GetPoolProperty(ctx, "tank", "autotrim")
+	require.NoError(t, err)
+	assert.Equal(t, "on", v)
+
+	pool, err := m.GetPool(ctx, "tank", "comment", "capacity", "free")
+	require.NoError(t, err)
+	assert.Equal(t, zfs.Property{
+		Name:     "tank",
+		Property: "comment",
+		Value:    "test pool",
+		Source:   "local",
+	}, pool.Properties["comment"])
+	capacity, ok := pool.Capacity()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), capacity)
+	free, ok := pool.Free()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<30-96<<10), free)
+
+	err = m.SetPoolProperty(ctx, "tank", "size", "1G")
+	assert.Error(t, err)
+
+	err = m.SetPoolProperty(ctx, "tank", "altroot", "/mnt")
+	assert.Error(t, err)
+
+	_, err = m.GetPoolProperty(ctx, "missing", "size")
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+
+	stdout, _, err := run(t, r, "zpool", "list")
+	require.NoError(t, err)
+	assert.Equal(t,
+		"NAME  SIZE  ALLOC  FREE   CKPOINT  EXPANDSZ  FRAG  CAP  DEDUP  "+
+			"HEALTH  ALTROOT\n"+
+			"tank  1G    96K    1024M  -        -         0%    0%   1.00x  "+
+			"ONLINE  -\n",
+		stdout,
+	)
+}
+
+func TestManager_ListPools(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreatePool(ctx, &zfs.CreatePoolOptions{
+		Name:  "data",
+		Vdevs: []string{"/dev/sdc"},
+	}))
+
+	pools, err := m.ListPools(ctx, "health")
+	require.NoError(t, err)
+	names := []string{}
+	for _, p := range pools {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"data", "tank"}, names)
+
+	require.NoError(t, m.DestroyPool(ctx, "data", false))
+	require.NoError(t, m.DestroyPool(ctx, "tank", false))
+
+	stdout, _, err := run(t, r, "zpool", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "no pools available\n", stdout)
+
+	names, err = m.ListPoolNames(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestManager_ExportPool_ImportPool(t *testing.T) {
+	r, m := newManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.CreateDataset(ctx, &zfs.CreateDatasetOptions{
+		Name: "tank/secret",
+		Properties: map[string]string{
+			"encryption":  "on",
+			"keyformat":   "hex",
+			"keylocation": "file:///etc/zfs/tank.key",
+		},
+	}))
+	guid, err := m.GetPoolProperty(ctx, "tank", "guid")
+	require.NoError(t, err)
+
+	require.NoError(t, m.ExportPool(ctx, "tank", false))
+
+	_, err = m.GetPool(ctx, "tank")
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+
+	stdout, _, err := run(t, r, "zpool", "import")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "   pool: tank\n     id: "+guid+"\n")
+
+	err = m.ImportPool(ctx, &zfs.ImportPoolOptions{Name: "missing"})
+	assert.EqualError(t, err, "zpool; not found; exit status 1: "+
+		"cannot import 'missing': no such pool available",
+	)
+
+	require.NoError(t, m.ImportPool(ctx, &zfs.ImportPoolOptions{
+		GUID:          guid,
+		NewName:       "temp",
+		TemporaryName: true,
+		AltRoot:       "/mnt",
+		LoadKeys:      true,
+	}))
+
+	mounts, err := m.ListMounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.Mount{
+		{Dataset: "temp", Mountpoint: "/mnt/temp"},
+		{Dataset: "temp/secret", Mountpoint: "/mnt/temp/secret"},
+	}, mounts)
+
+	require.NoError(t, m.ExportPool(ctx, "temp", false))
+	require.NoError(t, m.ImportPool(ctx, &zfs.ImportPoolOptions{All: true}))
+
+	pool, err := m.GetPool(ctx, "tank", "altroot", "guid")
+	require.NoError(t, err)
+	assert.Equal(t, "-", pool.Properties["altroot"].Value)
+	assert.Equal(t, guid, pool.Properties["guid"].Value)
+
+	v, err := m.GetDatasetProperty(ctx, "tank/secret", "keystatus")
+	require.NoError(t, err)
+	assert.Equal(t, "unavailable", v)
+
+	mounts, err = m.ListMounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]*zfs.Mount{{Dataset: "tank", Mountpoint: "/tank"}},
+		mounts,
+	)
+
+	err = m.ImportPool(ctx, &zfs.ImportPoolOptions{Name: "tank"})
+	assert.ErrorIs(t, err, zfs.ErrNotFound)
+}
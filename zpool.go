@@ -1,47 +1,30 @@
 package zfs
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
 
+	"github.com/krystal/go-zfs/zfsprops"
+	"github.com/krystal/go-zfs/zpoolprops"
 	"go.uber.org/multierr"
 )
 
 var (
-	errInvalidPoolName          = multierr.Append(ErrZpool, ErrInvalidName)
 	errInvalidPoolProperty      = multierr.Append(ErrZpool, ErrInvalidProperty)
 	errInvalidCreatePoolOptions = multierr.Append(
 		ErrZpool, ErrInvalidCreateOptions,
 	)
+	errInvalidImportPoolOptions = multierr.Append(
+		ErrZpool, ErrInvalidImportOptions,
+	)
 )
 
-func validPoolName(name string) bool {
-	return len(name) > 0 && !strings.Contains(name, "/")
-}
-
 func (m *Manager) zpool(
 	ctx context.Context,
 	args ...string,
 ) ([][]string, error) {
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	err := m.Runner.RunContext(ctx, nil, &stdout, &stderr, "zpool", args...)
-	if err != nil {
-		cleanStderr := cleanUpStderr(stderr.Bytes())
-
-		errs := ErrZpool
-		if notFoundErr(cleanStderr) {
-			errs = multierr.Append(errs, ErrNotFound)
-		}
-
-		return nil, multierr.Append(
-			errs, fmt.Errorf("%w: %s", err, cleanStderr),
-		)
-	}
-
-	return parseTabular(stdout.Bytes()), nil
+	return m.run(ctx, nil, ErrZpool, "zpool", args...)
 }
 
 // GetProperty returns the value of property on zpool with name.
@@ -50,8 +33,8 @@ func (m *Manager) GetPoolProperty(
 	name string,
 	property string,
 ) (string, error) {
-	if !validPoolName(name) {
-		return "", errInvalidPoolName
+	if err := validatePoolName(name); err != nil {
+		return "", multierr.Append(ErrZpool, err)
 	}
 
 	if property == "" || property == allProperty {
@@ -82,8 +65,8 @@ func (m *Manager) SetPoolProperties(
 	name string,
 	properties map[string]string,
 ) error {
-	if !validPoolName(name) {
-		return errInvalidPoolName
+	if err := validatePoolName(name); err != nil {
+		return multierr.Append(ErrZpool, err)
 	}
 
 	args := []string{"set"}
@@ -91,6 +74,10 @@ func (m *Manager) SetPoolProperties(
 	if err != nil {
 		return multierr.Append(ErrZpool, err)
 	}
+	err = validatePoolProperties(properties, zpoolprops.AccessSettable)
+	if err != nil {
+		return multierr.Append(ErrZpool, err)
+	}
 	args = append(args, propArgs...)
 	args = append(args, name)
 
@@ -139,12 +126,8 @@ func (m *Manager) CreatePool(
 	if options == nil {
 		return errInvalidCreatePoolOptions
 	}
-	if !validPoolName(options.Name) {
-		return multierr.Combine(
-			ErrZpool,
-			ErrInvalidCreateOptions,
-			ErrInvalidName,
-		)
+	if err := validateNewPoolName(options.Name); err != nil {
+		return multierr.Combine(ErrZpool, ErrInvalidCreateOptions, err)
 	}
 	if len(options.Vdevs) == 0 {
 		return fmt.Errorf("%w: no vdevs specified", errInvalidCreatePoolOptions)
@@ -168,12 +151,24 @@ func (m *Manager) CreatePool(
 	if err != nil {
 		return multierr.Append(ErrZpool, err)
 	}
+	err = validatePoolProperties(options.Properties,
+		zpoolprops.AccessSettable, zpoolprops.AccessCreateOnly,
+	)
+	if err != nil {
+		return multierr.Append(ErrZpool, err)
+	}
 	args = append(args, poolProps...)
 
 	fsProps, err := propertyMapFlags("-O", options.FilesystemProperties)
 	if err != nil {
 		return multierr.Append(ErrZpool, err)
 	}
+	err = validateDatasetProperties(
+		options.FilesystemProperties, zfsprops.TypeFilesystem, true,
+	)
+	if err != nil {
+		return multierr.Append(ErrZpool, err)
+	}
 	args = append(args, fsProps...)
 
 	args = append(args, options.Args...)
@@ -194,8 +189,8 @@ func (m *Manager) GetPool(
 	name string,
 	properties ...string,
 ) (*Pool, error) {
-	if !validPoolName(name) {
-		return nil, errInvalidPoolName
+	if err := validatePoolName(name); err != nil {
+		return nil, multierr.Append(ErrZpool, err)
 	}
 	if len(properties) == 0 {
 		properties = []string{allProperty}
@@ -214,7 +209,7 @@ func (m *Manager) GetPool(
 	return newPool(name, props[name]), nil
 }
 
-// ListPools returns a slice of *Pool instances for all pools.
+// ListPools returns a slice of *Pool instances for all pools, sorted by name.
 //
 // If properties are specified, only those properties are returned for each
 // pool, otherwise all properties are returned.
@@ -235,9 +230,10 @@ func (m *Manager) ListPools(
 	}
 
 	props := newProperties(records)
-	pools := make([]*Pool, 0, len(props))
-	for name, poolProps := range props {
-		pools = append(pools, newPool(name, poolProps))
+	names := recordNames(records)
+	pools := make([]*Pool, 0, len(names))
+	for _, name := range names {
+		pools = append(pools, newPool(name, props[name]))
 	}
 
 	return pools, nil
@@ -267,8 +263,8 @@ func (m *Manager) DestroyPool(
 	name string,
 	force bool,
 ) error {
-	if !validPoolName(name) {
-		return errInvalidPoolName
+	if err := validatePoolName(name); err != nil {
+		return multierr.Append(ErrZpool, err)
 	}
 
 	args := []string{"destroy"}
@@ -287,12 +283,83 @@ type ImportPoolOptions struct {
 	// Name of the pool to import.
 	Name string
 
+	// GUID is the numeric identifier of the pool to import. Useful when
+	// multiple exported pools share the same name. Cannot be combined with
+	// Name.
+	GUID string
+
+	// NewName imports the pool under a different name.
+	//
+	// Requires Name or GUID to be set.
+	NewName string
+
+	// TemporaryName indicates whether NewName is only a temporary name by
+	// passing the -t flag. The pool is imported under NewName, but retains
+	// its original name on disk.
+	//
+	// Ignored when NewName is empty.
+	TemporaryName bool
+
+	// All indicates whether to import all pools found by passing the -a flag.
+	// Cannot be combined with Name or GUID.
+	All bool
+
 	// Properties is a map of properties (-o) to set on the pool.
 	Properties map[string]string
 
 	// Force indicates whether to force flag (-f) should be set.
 	Force bool
 
+	// ReadOnly indicates whether to import the pool in read-only mode by
+	// passing "-o readonly=on". Cannot be combined with a readonly value in
+	// Properties.
+	ReadOnly bool
+
+	// NoMount indicates whether to import the pool without mounting any file
+	// systems by passing the -N flag.
+	NoMount bool
+
+	// MissingLog indicates whether to allow importing a pool with a missing
+	// log device by passing the -m flag. Recent transactions can be lost
+	// because the log device will be discarded.
+	MissingLog bool
+
+	// LoadKeys indicates whether to load keys for encrypted datasets during
+	// import by passing the -l flag.
+	LoadKeys bool
+
+	// Scan indicates whether to scan the default search path for devices
+	// without using the libblkid cache, by passing the -s flag.
+	Scan bool
+
+	// Rewind indicates whether to attempt recovery of a non-importable pool
+	// by discarding the last few transactions, by passing the -F flag.
+	Rewind bool
+
+	// RewindDryRun indicates whether to only check if a Rewind recovery is
+	// possible, without actually performing it, by passing the -n flag.
+	//
+	// Requires Rewind to be set.
+	RewindDryRun bool
+
+	// ExtremeRewind indicates whether to use extreme measures to find a valid
+	// transaction group to rewind to, by passing the -X flag. This can be
+	// extremely slow and is potentially dangerous.
+	//
+	// Requires Rewind to be set.
+	ExtremeRewind bool
+
+	// RewindToCheckpoint indicates whether to rewind the pool to its
+	// checkpoint by passing the --rewind-to-checkpoint flag.
+	RewindToCheckpoint bool
+
+	// AltRoot is the alternate root (-R) to import the pool with.
+	AltRoot string
+
+	// Cachefile is a path to a cache file (-c) to read the pool configuration
+	// from, instead of searching devices.
+	Cachefile string
+
 	// Args is a list of additional arguments to pass to zpool import.
 	Args []string
 
@@ -301,6 +368,91 @@ type ImportPoolOptions struct {
 	DirOrDevice []string
 }
 
+func (o *ImportPoolOptions) validate() error {
+	if o.Name != "" {
+		if err := validatePoolName(o.Name); err != nil {
+			return multierr.Append(ErrZpool, err)
+		}
+	}
+
+	switch {
+	case o.Name != "" && o.GUID != "":
+		return fmt.Errorf(
+			"%w: name and guid are mutually exclusive",
+			errInvalidImportPoolOptions,
+		)
+	case o.All && (o.Name != "" || o.GUID != ""):
+		return fmt.Errorf(
+			"%w: all cannot be combined with name or guid",
+			errInvalidImportPoolOptions,
+		)
+	case o.NewName != "" && o.Name == "" && o.GUID == "":
+		return fmt.Errorf(
+			"%w: new name requires name or guid",
+			errInvalidImportPoolOptions,
+		)
+	case (o.RewindDryRun || o.ExtremeRewind) && !o.Rewind:
+		return fmt.Errorf(
+			"%w: rewind dry-run and extreme rewind require rewind",
+			errInvalidImportPoolOptions,
+		)
+	case o.ReadOnly && o.Properties[zpoolprops.ReadOnly] != "":
+		return fmt.Errorf(
+			"%w: read-only cannot be combined with readonly property",
+			errInvalidImportPoolOptions,
+		)
+	}
+
+	return nil
+}
+
+// flags returns the flags for zpool import, excluding pool properties,
+// directories/devices, custom args, and pool names.
+func (o *ImportPoolOptions) flags() []string {
+	args := []string{}
+	if o.All {
+		args = append(args, "-a")
+	}
+	if o.Force {
+		args = append(args, "-f")
+	}
+	if o.NoMount {
+		args = append(args, "-N")
+	}
+	if o.MissingLog {
+		args = append(args, "-m")
+	}
+	if o.LoadKeys {
+		args = append(args, "-l")
+	}
+	if o.Scan {
+		args = append(args, "-s")
+	}
+	if o.NewName != "" && o.TemporaryName {
+		args = append(args, "-t")
+	}
+	if o.Rewind {
+		args = append(args, "-F")
+		if o.RewindDryRun {
+			args = append(args, "-n")
+		}
+		if o.ExtremeRewind {
+			args = append(args, "-X")
+		}
+	}
+	if o.RewindToCheckpoint {
+		args = append(args, "--rewind-to-checkpoint")
+	}
+	if o.AltRoot != "" {
+		args = append(args, "-R", o.AltRoot)
+	}
+	if o.Cachefile != "" {
+		args = append(args, "-c", o.Cachefile)
+	}
+
+	return args
+}
+
 // ImportPool imports the named pool based on the given options.
 func (m *Manager) ImportPool(
 	ctx context.Context,
@@ -309,29 +461,53 @@ func (m *Manager) ImportPool(
 	if options == nil {
 		options = &ImportPoolOptions{}
 	}
-	if options.Name != "" && !validPoolName(options.Name) {
-		return errInvalidPoolName
+	if err := options.validate(); err != nil {
+		return err
+	}
+	if options.NewName != "" {
+		if err := validateNewPoolName(options.NewName); err != nil {
+			return multierr.Append(ErrZpool, err)
+		}
 	}
 
-	args := []string{"import"}
-	if options.Force {
-		args = append(args, "-f")
+	args := append([]string{"import"}, options.flags()...)
+
+	props := options.Properties
+	if options.ReadOnly {
+		props = make(map[string]string, len(options.Properties)+1)
+		for k, v := range options.Properties {
+			props[k] = v
+		}
+		props[zpoolprops.ReadOnly] = "on"
 	}
 
-	poolProps, err := propertyMapFlags("-o", options.Properties)
+	poolProps, err := propertyMapFlags("-o", props)
 	if err != nil {
 		return multierr.Append(ErrZpool, err)
 	}
-	args = append(args, poolProps...,
+	err = validatePoolProperties(props,
+		zpoolprops.AccessSettable,
+		zpoolprops.AccessCreateOnly,
+		zpoolprops.AccessImportOnly,
 	)
+	if err != nil {
+		return multierr.Append(ErrZpool, err)
+	}
+	args = append(args, poolProps...)
 	if len(options.DirOrDevice) > 0 {
 		for _, v := range options.DirOrDevice {
 			args = append(args, "-d", v)
 		}
 	}
 	args = append(args, options.Args...)
-	if options.Name != "" {
+	switch {
+	case options.Name != "":
 		args = append(args, options.Name)
+	case options.GUID != "":
+		args = append(args, options.GUID)
+	}
+	if options.NewName != "" {
+		args = append(args, options.NewName)
 	}
 
 	_, err = m.zpool(ctx, args...)
@@ -346,8 +522,8 @@ func (m *Manager) ExportPool(
 	name string,
 	force bool,
 ) error {
-	if !validPoolName(name) {
-		return errInvalidPoolName
+	if err := validatePoolName(name); err != nil {
+		return multierr.Append(ErrZpool, err)
 	}
 
 	args := []string{"export"}
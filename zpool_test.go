@@ -40,7 +40,7 @@ func TestManager_GetPoolProperty(t *testing.T) {
 				name:     "",
 				property: "size",
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrInvalidName,
@@ -53,7 +53,50 @@ func TestManager_GetPoolProperty(t *testing.T) {
 				name:     "my-pool/things",
 				property: "size",
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				name:     "1tank",
+				property: "size",
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				name:     "tank@x",
+				property: "size",
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				name:     "ta!nk",
+				property: "size",
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -215,7 +258,7 @@ func TestManager_SetPoolProperty(t *testing.T) {
 				property: "quota",
 				value:    "1G",
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -229,7 +272,53 @@ func TestManager_SetPoolProperty(t *testing.T) {
 				property: "quota",
 				value:    "1G",
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				name:     "1tank",
+				property: "quota",
+				value:    "1G",
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				name:     "tank@x",
+				property: "quota",
+				value:    "1G",
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				name:     "ta!nk",
+				property: "quota",
+				value:    "1G",
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -280,9 +369,9 @@ func TestManager_SetPoolProperty(t *testing.T) {
 				property: "feature@async_destroy",
 				value:    "disabled",
 			},
-			wantArgs: []string{
-				"set", "feature@async_destroy=disabled", "my-test-pool",
-			},
+			wantErr: "zpool; invalid property: feature@async_destroy: " +
+				"invalid value 'disabled', must be one of: enabled",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
 		},
 		{
 			name: "no such pool",
@@ -305,18 +394,58 @@ func TestManager_SetPoolProperty(t *testing.T) {
 			args: args{
 				name:     "my-test-pool",
 				property: "listsnapshots",
-				value:    "whatnow",
+				value:    "on",
 			},
-			wantArgs: []string{"set", "listsnapshots=whatnow", "my-test-pool"},
-			//nolint:lll
-			stderr: `cannot set property for 'zfs-local-test': 'listsnapshots' must be one of 'on | off'
-usage:
-	set <property=value> <pool>
-`,
+			wantArgs: []string{"set", "listsnapshots=on", "my-test-pool"},
+			stderr: "cannot set property for 'my-test-pool': " +
+				"permission denied\n",
 			commandErr: errors.New("exit status 1"),
-			wantErr: "zpool; exit status 1: cannot set property for " +
-				"'zfs-local-test': 'listsnapshots' must be one of 'on | off'",
-			wantErrTargets: []error{Err, ErrZpool},
+			wantErr: "zpool; permission denied; exit status 1: " +
+				"cannot set property for 'my-test-pool': permission denied",
+			wantErrTargets: []error{Err, ErrZpool, ErrPermissionDenied},
+		},
+		{
+			name: "invalid value",
+			args: args{
+				name:     "my-test-pool",
+				property: "listsnapshots",
+				value:    "whatnow",
+			},
+			wantErr: "zpool; invalid property: listsnapshots: " +
+				"invalid value 'whatnow', must be one of: on, off",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "read-only property",
+			args: args{
+				name:     "my-test-pool",
+				property: "size",
+				value:    "10G",
+			},
+			wantErr: "zpool; invalid property: size: property is read-only",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "import-only property",
+			args: args{
+				name:     "my-test-pool",
+				property: "readonly",
+				value:    "on",
+			},
+			wantErr: "zpool; invalid property: readonly: " +
+				"property can only be set at import time",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "feature property",
+			args: args{
+				name:     "my-test-pool",
+				property: "feature@async_destroy",
+				value:    "enabled",
+			},
+			wantArgs: []string{
+				"set", "feature@async_destroy=enabled", "my-test-pool",
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -393,7 +522,7 @@ func TestManager_SetPoolProperties(t *testing.T) {
 					"quota": "10G",
 				},
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -408,7 +537,56 @@ func TestManager_SetPoolProperties(t *testing.T) {
 					"quota": "10G",
 				},
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				name: "1tank",
+				properties: map[string]string{
+					"quota": "10G",
+				},
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				name: "tank@x",
+				properties: map[string]string{
+					"quota": "10G",
+				},
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				name: "ta!nk",
+				properties: map[string]string{
+					"quota": "10G",
+				},
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -463,11 +641,11 @@ func TestManager_SetPoolProperties(t *testing.T) {
 				name: "my-test-pool",
 				properties: map[string]string{
 					"quota":                 "10G",
-					"feature@async_destroy": "disabled",
+					"feature@async_destroy": "enabled",
 				},
 			},
 			wantArgs: []string{
-				"set", "feature@async_destroy=disabled", "quota=10G",
+				"set", "feature@async_destroy=enabled", "quota=10G",
 				"my-test-pool",
 			},
 		},
@@ -493,19 +671,41 @@ func TestManager_SetPoolProperties(t *testing.T) {
 			args: args{
 				name: "my-test-pool",
 				properties: map[string]string{
-					"listsnapshots": "whatnow",
+					"listsnapshots": "on",
 				},
 			},
-			wantArgs: []string{"set", "listsnapshots=whatnow", "my-test-pool"},
-			//nolint:lll
-			stderr: `cannot set property for 'zfs-local-test': 'listsnapshots' must be one of 'on | off'
-usage:
-	set <property=value> <pool>
-`,
+			wantArgs: []string{"set", "listsnapshots=on", "my-test-pool"},
+			stderr: "cannot set property for 'my-test-pool': " +
+				"permission denied\n",
 			commandErr: errors.New("exit status 1"),
-			wantErr: "zpool; exit status 1: cannot set property for " +
-				"'zfs-local-test': 'listsnapshots' must be one of 'on | off'",
-			wantErrTargets: []error{Err, ErrZpool},
+			wantErr: "zpool; permission denied; exit status 1: " +
+				"cannot set property for 'my-test-pool': permission denied",
+			wantErrTargets: []error{Err, ErrZpool, ErrPermissionDenied},
+		},
+		{
+			name: "invalid values",
+			args: args{
+				name: "my-test-pool",
+				properties: map[string]string{
+					"listsnapshots": "whatnow",
+					"failmode":      "explode",
+				},
+			},
+			wantErr: "zpool; invalid property: failmode: invalid value " +
+				"'explode', must be one of: wait, continue, panic",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "create-only property",
+			args: args{
+				name: "my-test-pool",
+				properties: map[string]string{
+					"altroot": "/mnt",
+				},
+			},
+			wantErr: "zpool; invalid property: altroot: " +
+				"property can only be set at creation or import time",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
 		},
 	}
 	for _, tt := range tests {
@@ -583,7 +783,7 @@ func TestManager_CreatePool(t *testing.T) {
 			args: args{
 				options: &CreatePoolOptions{},
 			},
-			wantErr: "zpool; invalid create options; invalid name",
+			wantErr: "zpool; invalid create options; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -599,7 +799,7 @@ func TestManager_CreatePool(t *testing.T) {
 					Vdevs: []string{"/dev/test-a", "/dev/test-b"},
 				},
 			},
-			wantErr: "zpool; invalid create options; invalid name",
+			wantErr: "zpool; invalid create options; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -614,13 +814,32 @@ func TestManager_CreatePool(t *testing.T) {
 					Vdevs: []string{"/dev/test-a", "/dev/test-b"},
 				},
 			},
-			wantErr: "zpool; invalid create options; invalid name",
+			wantErr: "zpool; invalid create options; " +
+				"invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
 				ErrInvalidName,
 			},
 		},
+		{
+			name: "reserved pool name",
+			args: args{
+				options: &CreatePoolOptions{
+					Name:  "mirror1",
+					Vdevs: []string{"/dev/test-a", "/dev/test-b"},
+				},
+			},
+			wantErr: "zpool; invalid create options; " +
+				"invalid name 'mirror1': name is reserved",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidCreateOptions,
+				ErrInvalidName,
+			},
+		},
 		{
 			name: "no vdevs",
 			args: args{
@@ -711,6 +930,60 @@ func TestManager_CreatePool(t *testing.T) {
 				ErrInvalidProperty,
 			},
 		},
+		{
+			name: "read-only pool property",
+			args: args{
+				options: &CreatePoolOptions{
+					Name:       "my-test-pool",
+					Properties: map[string]string{"health": "ONLINE"},
+					Vdevs:      []string{"/dev/test-a"},
+				},
+			},
+			wantErr: "zpool; invalid property: health: " +
+				"property is read-only",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "import-only pool property",
+			args: args{
+				options: &CreatePoolOptions{
+					Name:       "my-test-pool",
+					Properties: map[string]string{"readonly": "on"},
+					Vdevs:      []string{"/dev/test-a"},
+				},
+			},
+			wantErr: "zpool; invalid property: readonly: " +
+				"property can only be set at import time",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "invalid pool property value",
+			args: args{
+				options: &CreatePoolOptions{
+					Name:       "my-test-pool",
+					Properties: map[string]string{"ashift": "7"},
+					Vdevs:      []string{"/dev/test-a"},
+				},
+			},
+			wantErr: "zpool; invalid property: ashift: invalid value " +
+				"'7', must be one of: 0, 9, 10, 11, 12, 13, 14, 15, 16",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "invalid filesystem property value",
+			args: args{
+				options: &CreatePoolOptions{
+					Name: "my-test-pool",
+					FilesystemProperties: map[string]string{
+						"atime": "maybe",
+					},
+					Vdevs: []string{"/dev/test-a"},
+				},
+			},
+			wantErr: "zpool; invalid property: atime: invalid value " +
+				"'maybe', must be one of: on, off",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
 		{
 			name: "simple",
 			args: args{
@@ -962,7 +1235,7 @@ func TestManager_GetPool(t *testing.T) {
 			args: args{
 				name: "",
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name: empty name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -974,7 +1247,47 @@ func TestManager_GetPool(t *testing.T) {
 			args: args{
 				name: "my-pool/things",
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				name: "1tank",
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				name: "tank@x",
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				name: "ta!nk",
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -1535,7 +1848,7 @@ usage:
 			}
 
 			require.NoError(t, err)
-			assert.ElementsMatch(t, tt.want, got)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
@@ -1649,7 +1962,7 @@ func TestManager_DestroyPool(t *testing.T) {
 			args: args{
 				name: "",
 			},
-			wantErr:        "zpool; invalid name",
+			wantErr:        "zpool; invalid name: empty name",
 			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
 		},
 		{
@@ -1657,7 +1970,35 @@ func TestManager_DestroyPool(t *testing.T) {
 			args: args{
 				name: "my-pool/things",
 			},
-			wantErr:        "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				name: "1tank",
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				name: "tank@x",
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				name: "ta!nk",
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
 		},
 		{
@@ -1692,16 +2033,14 @@ func TestManager_DestroyPool(t *testing.T) {
 		{
 			name: "command error",
 			args: args{
-				name: "foo#bar",
+				name: "my-pool",
 			},
-			wantArgs: []string{"destroy", "foo#bar"},
-			stderr: `cannot open 'foo#bar': invalid character '#' in pool name
-usage:
-	destroy [-f] <pool>
-`,
+			wantArgs: []string{"destroy", "my-pool"},
+			stderr: "could not destroy 'my-pool': " +
+				"could not unmount datasets\n",
 			commandErr: errors.New("exit status 1"),
-			wantErr: "zpool; exit status 1: cannot open 'foo#bar': " +
-				"invalid character '#' in pool name",
+			wantErr: "zpool; exit status 1: could not destroy 'my-pool': " +
+				"could not unmount datasets",
 			wantErrTargets: []error{Err, ErrZpool},
 		},
 	}
@@ -1792,7 +2131,53 @@ func TestManager_ImportPool(t *testing.T) {
 					Name: "my-pool/things",
 				},
 			},
-			wantErr: "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				options: &ImportPoolOptions{
+					Name: "1tank",
+				},
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				options: &ImportPoolOptions{
+					Name: "tank@x",
+				},
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				options: &ImportPoolOptions{
+					Name: "ta!nk",
+				},
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{
 				Err,
 				ErrZpool,
@@ -1861,6 +2246,58 @@ func TestManager_ImportPool(t *testing.T) {
 				ErrInvalidProperty,
 			},
 		},
+		{
+			name: "read-only pool property",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:       "my-test-pool",
+					Properties: map[string]string{"guid": "1234"},
+				},
+			},
+			wantErr: "zpool; invalid property: guid: property is read-only",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidProperty},
+		},
+		{
+			name: "import-only pool property",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:       "my-test-pool",
+					Properties: map[string]string{"readonly": "on"},
+				},
+			},
+			wantArgs: []string{
+				"import", "-o", "readonly=on", "my-test-pool",
+			},
+		},
+		{
+			name: "read-only",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:     "my-test-pool",
+					ReadOnly: true,
+					Properties: map[string]string{
+						(zpoolprops.AltRoot): "/mnt",
+					},
+				},
+			},
+			wantArgs: []string{
+				"import", "-o", "altroot=/mnt", "-o", "readonly=on",
+				"my-test-pool",
+			},
+		},
+		{
+			name: "read-only with readonly property",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:       "my-test-pool",
+					ReadOnly:   true,
+					Properties: map[string]string{"readonly": "off"},
+				},
+			},
+			wantErr: "zpool; invalid import options: " +
+				"read-only cannot be combined with readonly property",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidImportOptions},
+		},
 		{
 			name: "force",
 			args: args{
@@ -1933,6 +2370,226 @@ func TestManager_ImportPool(t *testing.T) {
 				"my-test-pool",
 			},
 		},
+		{
+			name: "guid",
+			args: args{
+				options: &ImportPoolOptions{
+					GUID: "10387467209574432810",
+				},
+			},
+			wantArgs: []string{"import", "10387467209574432810"},
+		},
+		{
+			name: "name and guid",
+			args: args{
+				options: &ImportPoolOptions{
+					Name: "my-test-pool",
+					GUID: "10387467209574432810",
+				},
+			},
+			wantErr: "zpool; invalid import options: " +
+				"name and guid are mutually exclusive",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidImportOptions,
+			},
+		},
+		{
+			name: "new name",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:    "my-test-pool",
+					NewName: "my-new-pool",
+				},
+			},
+			wantArgs: []string{"import", "my-test-pool", "my-new-pool"},
+		},
+		{
+			name: "temporary new name",
+			args: args{
+				options: &ImportPoolOptions{
+					GUID:          "10387467209574432810",
+					NewName:       "my-tmp-pool",
+					TemporaryName: true,
+				},
+			},
+			wantArgs: []string{
+				"import", "-t", "10387467209574432810", "my-tmp-pool",
+			},
+		},
+		{
+			name: "temporary name without new name",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:          "my-test-pool",
+					TemporaryName: true,
+				},
+			},
+			wantArgs: []string{"import", "my-test-pool"},
+		},
+		{
+			name: "new name without name or guid",
+			args: args{
+				options: &ImportPoolOptions{
+					NewName: "my-new-pool",
+				},
+			},
+			wantErr: "zpool; invalid import options: " +
+				"new name requires name or guid",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidImportOptions,
+			},
+		},
+		{
+			name: "invalid new name",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:    "my-test-pool",
+					NewName: "my-pool/things",
+				},
+			},
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "reserved new name",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:    "my-test-pool",
+					NewName: "log",
+				},
+			},
+			wantErr: "zpool; invalid name 'log': name is reserved",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidName,
+			},
+		},
+		{
+			name: "reserved name imported under new name",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:    "log",
+					NewName: "my-new-pool",
+				},
+			},
+			wantArgs: []string{"import", "log", "my-new-pool"},
+		},
+		{
+			name: "all",
+			args: args{
+				options: &ImportPoolOptions{
+					All:     true,
+					NoMount: true,
+				},
+			},
+			wantArgs: []string{"import", "-a", "-N"},
+		},
+		{
+			name: "all with name",
+			args: args{
+				options: &ImportPoolOptions{
+					Name: "my-test-pool",
+					All:  true,
+				},
+			},
+			wantErr: "zpool; invalid import options: " +
+				"all cannot be combined with name or guid",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidImportOptions,
+			},
+		},
+		{
+			name: "rewind",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:          "my-test-pool",
+					Rewind:        true,
+					RewindDryRun:  true,
+					ExtremeRewind: true,
+				},
+			},
+			wantArgs: []string{
+				"import", "-F", "-n", "-X", "my-test-pool",
+			},
+		},
+		{
+			name: "rewind dry-run without rewind",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:         "my-test-pool",
+					RewindDryRun: true,
+				},
+			},
+			wantErr: "zpool; invalid import options: " +
+				"rewind dry-run and extreme rewind require rewind",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidImportOptions,
+			},
+		},
+		{
+			name: "extreme rewind without rewind",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:          "my-test-pool",
+					ExtremeRewind: true,
+				},
+			},
+			wantErr: "zpool; invalid import options: " +
+				"rewind dry-run and extreme rewind require rewind",
+			wantErrTargets: []error{
+				Err,
+				ErrZpool,
+				ErrInvalidImportOptions,
+			},
+		},
+		{
+			name: "rewind to checkpoint",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:               "my-test-pool",
+					RewindToCheckpoint: true,
+				},
+			},
+			wantArgs: []string{
+				"import", "--rewind-to-checkpoint", "my-test-pool",
+			},
+		},
+		{
+			name: "recovery options",
+			args: args{
+				options: &ImportPoolOptions{
+					Name:       "my-test-pool",
+					Force:      true,
+					NoMount:    true,
+					MissingLog: true,
+					LoadKeys:   true,
+					Scan:       true,
+					AltRoot:    "/mnt/recovery",
+					Cachefile:  "/etc/zfs/zpool.cache",
+				},
+			},
+			wantArgs: []string{
+				"import",
+				"-f", "-N", "-m", "-l", "-s",
+				"-R", "/mnt/recovery",
+				"-c", "/etc/zfs/zpool.cache",
+				"my-test-pool",
+			},
+		},
 		{
 			name: "no such pool",
 			args: args{
@@ -2038,7 +2695,7 @@ func TestManager_ExportPool(t *testing.T) {
 			args: args{
 				name: "",
 			},
-			wantErr:        "zpool; invalid name",
+			wantErr:        "zpool; invalid name: empty name",
 			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
 		},
 		{
@@ -2046,7 +2703,35 @@ func TestManager_ExportPool(t *testing.T) {
 			args: args{
 				name: "my-pool/things",
 			},
-			wantErr:        "zpool; invalid name",
+			wantErr: "zpool; invalid name 'my-pool/things': " +
+				"invalid character '/' in pool name",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
+		},
+		{
+			name: "pool name starting with a digit",
+			args: args{
+				name: "1tank",
+			},
+			wantErr: "zpool; invalid name '1tank': " +
+				"pool doesn't begin with a letter",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
+		},
+		{
+			name: "pool name with snapshot delimiter",
+			args: args{
+				name: "tank@x",
+			},
+			wantErr: "zpool; invalid name 'tank@x': " +
+				"invalid character '@' in pool name",
+			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
+		},
+		{
+			name: "pool name with invalid character",
+			args: args{
+				name: "ta!nk",
+			},
+			wantErr: "zpool; invalid name 'ta!nk': " +
+				"invalid character '!' in name",
 			wantErrTargets: []error{Err, ErrZpool, ErrInvalidName},
 		},
 		{
@@ -0,0 +1,293 @@
+package zpoolprops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/krystal/go-zfs/internal/propval"
+)
+
+// Kind is the kind of value a property holds.
+type Kind int
+
+const (
+	// KindString properties hold arbitrary strings, optionally restricted by
+	// a pattern.
+	KindString Kind = iota + 1
+
+	// KindBool properties hold "on" or "off".
+	KindBool
+
+	// KindEnum properties hold one of a fixed set of values.
+	KindEnum
+
+	// KindSize properties hold a size, like "10G".
+	KindSize
+
+	// KindNumber properties hold an unsigned integer.
+	KindNumber
+)
+
+// String returns the name of the kind, for example "bool".
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	case KindEnum:
+		return "enum"
+	case KindSize:
+		return "size"
+	case KindNumber:
+		return "number"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Access describes if and when a property can be set.
+type Access int
+
+const (
+	// AccessReadOnly properties cannot be set.
+	AccessReadOnly Access = iota + 1
+
+	// AccessSettable properties can be set at creation time, import time, and
+	// later with zpool set.
+	AccessSettable
+
+	// AccessCreateOnly properties can only be set at creation time and import
+	// time.
+	AccessCreateOnly
+
+	// AccessImportOnly properties can only be set at import time.
+	AccessImportOnly
+)
+
+// Property is machine-readable metadata of a property.
+type Property struct {
+	// Name of the property.
+	Name string
+
+	// Aliases are alternative short names accepted by zpool, like "cap" for
+	// "capacity".
+	Aliases []string
+
+	// Kind of value the property holds.
+	Kind Kind
+
+	// Values are the accepted values of KindBool and KindEnum properties, and
+	// special values accepted in addition to sizes, numbers, or patterns for
+	// other kinds, like "none".
+	Values []string
+
+	// Pattern optionally matches additional accepted values. For KindString
+	// properties with a Pattern, only matching values and Values are
+	// accepted.
+	Pattern *regexp.Regexp
+
+	// Access describes if and when the property can be set.
+	Access Access
+}
+
+// Validate returns an error describing the accepted values if value is not
+// valid for the property.
+func (p *Property) Validate(value string) error {
+	if propval.OneOf(value, p.Values) ||
+		(p.Pattern != nil && p.Pattern.MatchString(value)) {
+		return nil
+	}
+
+	switch p.Kind {
+	case KindSize:
+		if propval.Size(value) {
+			return nil
+		}
+	case KindNumber:
+		if propval.Number(value) {
+			return nil
+		}
+	case KindString:
+		if p.Pattern == nil && len(p.Values) == 0 {
+			return nil
+		}
+	case KindBool, KindEnum:
+	}
+
+	return fmt.Errorf(
+		"%s: invalid value '%s', must be %s",
+		p.Name, value, propval.Describe(p.Kind.String(), p.Values, p.Pattern),
+	)
+}
+
+var onOff = []string{"on", "off"}
+
+// properties is the metadata of all properties, sorted by name.
+var properties = []*Property{
+	{
+		Name: Allocated, Aliases: []string{"alloc"}, Kind: KindSize,
+		Access: AccessReadOnly,
+	},
+	{
+		Name: AltRoot, Kind: KindString, Access: AccessCreateOnly,
+		Pattern: regexp.MustCompile(`^/`),
+	},
+	{
+		Name: Ashift, Kind: KindEnum, Access: AccessSettable,
+		Values: []string{
+			"0", "9", "10", "11", "12", "13", "14", "15", "16",
+		},
+	},
+	{
+		Name: AutoExpand, Aliases: []string{"expand"}, Kind: KindBool,
+		Values: onOff, Access: AccessSettable,
+	},
+	{
+		Name: AutoReplace, Aliases: []string{"replace"}, Kind: KindBool,
+		Values: onOff, Access: AccessSettable,
+	},
+	{
+		Name: AutoTrim, Aliases: []string{"trim"}, Kind: KindBool,
+		Values: onOff, Access: AccessSettable,
+	},
+	{Name: Bootfs, Kind: KindString, Access: AccessSettable},
+	{
+		Name: Cachefile, Kind: KindString, Access: AccessSettable,
+		Values: []string{"none", ""}, Pattern: regexp.MustCompile(`^/`),
+	},
+	{
+		Name: Capacity, Aliases: []string{"cap"}, Kind: KindNumber,
+		Access: AccessReadOnly,
+	},
+	{
+		Name: Comment, Kind: KindString, Access: AccessSettable,
+		Pattern: regexp.MustCompile(`^[[:print:]]{0,32}$`),
+	},
+	{Name: Compatibility, Kind: KindString, Access: AccessSettable},
+	{Name: DedupDitto, Kind: KindNumber, Access: AccessSettable},
+	{
+		Name: Delegation, Aliases: []string{"deleg"}, Kind: KindBool,
+		Values: onOff, Access: AccessSettable,
+	},
+	{
+		Name: ExpandSize, Aliases: []string{"expandsz"}, Kind: KindSize,
+		Access: AccessReadOnly,
+	},
+	{
+		Name: FailMode, Kind: KindEnum, Access: AccessSettable,
+		Values: []string{"wait", "continue", "panic"},
+	},
+	{
+		Name: Fragmentation, Aliases: []string{"frag"}, Kind: KindNumber,
+		Access: AccessReadOnly,
+	},
+	{Name: Free, Kind: KindSize, Access: AccessReadOnly},
+	{Name: Freeing, Kind: KindSize, Access: AccessReadOnly},
+	{Name: GUID, Kind: KindNumber, Access: AccessReadOnly},
+	{
+		Name: Health, Kind: KindEnum, Access: AccessReadOnly,
+		Values: []string{
+			"ONLINE", "DEGRADED", "FAULTED", "OFFLINE", "REMOVED", "UNAVAIL",
+		},
+	},
+	{Name: Leaked, Kind: KindSize, Access: AccessReadOnly},
+	{
+		Name: ListSnapshots, Aliases: []string{"listsnaps"}, Kind: KindBool,
+		Values: onOff, Access: AccessSettable,
+	},
+	{Name: LoadGUID, Kind: KindNumber, Access: AccessReadOnly},
+	{
+		Name: MultiHost, Kind: KindBool, Values: onOff,
+		Access: AccessSettable,
+	},
+	{
+		Name: ReadOnly, Aliases: []string{"rdonly"}, Kind: KindBool,
+		Values: onOff, Access: AccessImportOnly,
+	},
+	{Name: Size, Kind: KindSize, Access: AccessReadOnly},
+	{Name: Version, Kind: KindNumber, Access: AccessSettable},
+}
+
+// byName indexes properties by name and aliases.
+var byName = func() map[string]*Property {
+	m := map[string]*Property{}
+	for _, p := range properties {
+		m[p.Name] = p
+		for _, alias := range p.Aliases {
+			m[alias] = p
+		}
+	}
+
+	return m
+}()
+
+// Properties returns a copy of the metadata of all properties, sorted by name.
+// Feature and user properties are not included, but are supported by Lookup.
+func Properties() []*Property {
+	r := make([]*Property, len(properties))
+	for i, p := range properties {
+		r[i] = p.clone()
+	}
+
+	return r
+}
+
+// Lookup returns the metadata of the named property. Aliases, like "cap",
+// resolve to their property.
+//
+// Feature properties, like "feature@async_destroy", and user properties
+// containing a colon (":"), like "com.example:owner", are also supported,
+// with metadata describing them generically.
+//
+// The returned metadata is a copy, which can safely be modified. The second
+// return value indicates if the property is known.
+func Lookup(name string) (*Property, bool) {
+	if p, ok := byName[name]; ok {
+		return p.clone(), true
+	}
+
+	if feature := strings.TrimPrefix(name, "feature@"); feature != name {
+		if feature == "" {
+			return nil, false
+		}
+
+		// Features report "disabled", "enabled", or "active", but can
+		// only be set to "enabled".
+		return &Property{
+			Name:   name,
+			Kind:   KindEnum,
+			Values: []string{"enabled"},
+			Access: AccessSettable,
+		}, true
+	}
+
+	if strings.Contains(name, ":") && !strings.HasPrefix(name, "-") {
+		return &Property{
+			Name:   name,
+			Kind:   KindString,
+			Access: AccessSettable,
+		}, true
+	}
+
+	return nil, false
+}
+
+// clone returns a deep copy of p.
+func (p *Property) clone() *Property {
+	c := *p
+	c.Aliases = append([]string(nil), p.Aliases...)
+	c.Values = append([]string(nil), p.Values...)
+
+	return &c
+}
+
+// Valid reports whether property is a property defined by this package or
+// one of its aliases, a feature@ property, or a user property containing a
+// colon (":").
+func Valid(property string) bool {
+	_, ok := Lookup(property)
+
+	return ok
+}
@@ -0,0 +1,200 @@
+package zpoolprops
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProperties_Metadata(t *testing.T) {
+	props := Properties()
+	require.NotEmpty(t, props)
+
+	names := make([]string, 0, len(props))
+	seen := map[string]bool{}
+	for _, p := range props {
+		names = append(names, p.Name)
+
+		assert.False(t, seen[p.Name], "duplicate property %s", p.Name)
+		seen[p.Name] = true
+		for _, alias := range p.Aliases {
+			assert.False(t, seen[alias], "duplicate alias %s", alias)
+			seen[alias] = true
+		}
+
+		assert.NotZero(t, p.Access, "%s has no access", p.Name)
+		if p.Kind == KindBool || p.Kind == KindEnum {
+			assert.NotEmpty(t, p.Values, "%s has no values", p.Name)
+		}
+	}
+	assert.True(t, sort.StringsAreSorted(names), "properties are not sorted")
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantName   string
+		wantKind   Kind
+		wantAccess Access
+		wantOK     bool
+	}{
+		{
+			name:       "autotrim",
+			wantName:   "autotrim",
+			wantKind:   KindBool,
+			wantAccess: AccessSettable,
+			wantOK:     true,
+		},
+		{
+			name:       "cap",
+			wantName:   "capacity",
+			wantKind:   KindNumber,
+			wantAccess: AccessReadOnly,
+			wantOK:     true,
+		},
+		{
+			name:       "altroot",
+			wantName:   "altroot",
+			wantKind:   KindString,
+			wantAccess: AccessCreateOnly,
+			wantOK:     true,
+		},
+		{
+			name:       "readonly",
+			wantName:   "readonly",
+			wantKind:   KindBool,
+			wantAccess: AccessImportOnly,
+			wantOK:     true,
+		},
+		{
+			name:       "feature@async_destroy",
+			wantName:   "feature@async_destroy",
+			wantKind:   KindEnum,
+			wantAccess: AccessSettable,
+			wantOK:     true,
+		},
+		{
+			name:       "com.example:owner",
+			wantName:   "com.example:owner",
+			wantKind:   KindString,
+			wantAccess: AccessSettable,
+			wantOK:     true,
+		},
+		{name: "feature@"},
+		{name: "-bad:prop"},
+		{name: "nope"},
+		{name: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Lookup(tt.name)
+
+			assert.Equal(t, tt.wantOK, ok)
+			if !tt.wantOK {
+				assert.Nil(t, got)
+
+				return
+			}
+
+			require.NotNil(t, got)
+			assert.Equal(t, tt.wantName, got.Name)
+			assert.Equal(t, tt.wantKind, got.Kind)
+			assert.Equal(t, tt.wantAccess, got.Access)
+		})
+	}
+}
+
+func TestLookup_copy(t *testing.T) {
+	p, ok := Lookup(AutoTrim)
+	require.True(t, ok)
+	p.Access = AccessReadOnly
+	p.Values[0] = "changed"
+
+	for _, props := range Properties() {
+		if props.Name == AutoTrim {
+			props.Aliases = nil
+		}
+	}
+
+	got, ok := Lookup(AutoTrim)
+	require.True(t, ok)
+	assert.Equal(t, AccessSettable, got.Access)
+	assert.NotEqual(t, "changed", got.Values[0])
+	assert.NotEmpty(t, got.Aliases)
+}
+
+func TestProperty_Validate(t *testing.T) {
+	tests := []struct {
+		property string
+		value    string
+		wantErr  string
+	}{
+		{property: AutoTrim, value: "on"},
+		{
+			property: AutoTrim,
+			value:    "true",
+			wantErr:  "autotrim: invalid value 'true', must be one of: on, off",
+		},
+		{property: Ashift, value: "12"},
+		{
+			property: Ashift,
+			value:    "17",
+			wantErr: "ashift: invalid value '17', must be " +
+				"one of: 0, 9, 10, 11, 12, 13, 14, 15, 16",
+		},
+		{property: FailMode, value: "continue"},
+		{
+			property: FailMode,
+			value:    "retry",
+			wantErr: "failmode: invalid value 'retry', must be " +
+				"one of: wait, continue, panic",
+		},
+		{property: Cachefile, value: "none"},
+		{property: Cachefile, value: "/etc/zfs/zpool.cache"},
+		{
+			property: Cachefile,
+			value:    "zpool.cache",
+			wantErr: "cachefile: invalid value 'zpool.cache', must be " +
+				"one of: none, '', or matching ^/",
+		},
+		{property: Comment, value: "main storage"},
+		{
+			property: Comment,
+			value:    "this comment is far too long to be accepted",
+			wantErr: "comment: invalid value " +
+				"'this comment is far too long to be accepted', must be " +
+				"matching ^[[:print:]]{0,32}$",
+		},
+		{property: Version, value: "28"},
+		{
+			property: Version,
+			value:    "latest",
+			wantErr:  "version: invalid value 'latest', must be a number",
+		},
+		{property: "feature@encryption", value: "enabled"},
+		{
+			property: "feature@encryption",
+			value:    "disabled",
+			wantErr: "feature@encryption: invalid value 'disabled', must be " +
+				"one of: enabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property+"="+tt.value, func(t *testing.T) {
+			p, ok := Lookup(tt.property)
+			require.True(t, ok)
+
+			err := p.Validate(tt.value)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -90,3 +90,4 @@ const (
 func Feature(feature_name string) string {
 	return fmt.Sprintf("feature@%s", feature_name)
 }
+
@@ -87,3 +87,27 @@ func TestFeature(t *testing.T) {
 		})
 	}
 }
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		property string
+		want     bool
+	}{
+		{property: Size, want: true},
+		{property: AltRoot, want: true},
+		{property: Ashift, want: true},
+		{property: Feature("async_destroy"), want: true},
+		{property: "com.example:owner", want: true},
+		{property: "", want: false},
+		{property: "ashfit", want: false},
+		{property: "feature@", want: false},
+		{property: "-bad:prop", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property, func(t *testing.T) {
+			got := Valid(tt.property)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}